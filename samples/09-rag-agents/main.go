@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/snipwise/snip-sdk/snip/agents"
 	"github.com/snipwise/snip-sdk/snip/rag"
@@ -78,78 +79,57 @@ func main() {
 	fmt.Println(ragAgent02.GetInfo())
 	fmt.Println(ragAgent03.GetInfo())
 
-	if ragAgent01.GetNumberOfDocuments() == 0 {
-		txtChunks := []string{
-			"Squirrels run in the forest",
-			"Birds fly in the sky",
-			"Frogs swim in the pond",
-			"Fishes swim in the sea",
-			"Lions roar in the savannah",
-			"Eagles soar above the mountains",
-			"Dolphins leap out of the ocean",
-			"Bears fish in the river",
-		}
-		var chunks []text.TextChunk
-		for _, txt := range txtChunks {
-			chunk := text.TextChunk{
-				Content:  txt,
-				Metadata: map[string]any{"source": "example"},
-			}
-			chunks = append(chunks, chunk)
-		}
-
-		_, err = ragAgent01.AddTextChunksToStore(chunks)
-		if err != nil {
-			fmt.Printf("Error adding text chunks to RAG_Agent_1: %v\n", err)
-			return
-		}
-
-		_, err = ragAgent02.AddTextChunksToStore(chunks)
-		if err != nil {
-			fmt.Printf("Error adding text chunks to RAG_Agent_2: %v\n", err)
-			return
+	txtChunks := []string{
+		"Squirrels run in the forest",
+		"Birds fly in the sky",
+		"Frogs swim in the pond",
+		"Fishes swim in the sea",
+		"Lions roar in the savannah",
+		"Eagles soar above the mountains",
+		"Dolphins leap out of the ocean",
+		"Bears fish in the river",
+	}
+	var chunks []text.TextChunk
+	for idx, txt := range txtChunks {
+		chunk := text.TextChunk{
+			ID:       fmt.Sprintf("example-%d", idx),
+			Content:  txt,
+			Metadata: map[string]any{"source": "example"},
 		}
+		chunks = append(chunks, chunk)
+	}
 
-		_, err = ragAgent03.AddTextChunksToStore(chunks)
+	// Upserting is idempotent: on the next runs the unchanged chunks are skipped without being embedded
+	for _, ragAgent := range []*rag.RagAgent{ragAgent01, ragAgent02, ragAgent03} {
+		written, err := ragAgent.UpsertTextChunks(chunks)
 		if err != nil {
-			fmt.Printf("Error adding text chunks to RAG_Agent_3: %v\n", err)
+			fmt.Printf("Error upserting text chunks to %s: %v\n", ragAgent.GetName(), err)
 			return
 		}
-
-		fmt.Println("Text chunks added to all RAG agents successfully.")
-	} else {
-		fmt.Println("RAG agents already have documents in their stores.")
-	}
-
-	query := "Which animals swim?"
-	similarities1, err := ragAgent01.SearchSimilarities(query)
-	if err != nil {
-		fmt.Printf("Error searching similarities with RAG_Agent_1: %v\n", err)
-		return
-	}
-	fmt.Printf("RAG_Agent_1 similarities for query '%s':\n", query)
-	for _, sim := range similarities1 {
-		fmt.Println("  -", sim)
+		fmt.Printf("%s: %d text chunks written, %d unchanged.\n", ragAgent.GetName(), written, len(chunks)-written)
 	}
 
-	similarities2, err := ragAgent02.SearchSimilarities(query)
+	// Query the three agents in parallel and merge their results:
+	// the scores of each embedding model are normalized before being merged
+	retriever, err := rag.NewMultiRetriever([]rag.RetrieverSource{
+		{Searcher: ragAgent01},
+		{Searcher: ragAgent02},
+		{Searcher: ragAgent03, Timeout: 10 * time.Second},
+	})
 	if err != nil {
-		fmt.Printf("Error searching similarities with RAG_Agent_2: %v\n", err)
+		fmt.Printf("Error creating the retriever: %v\n", err)
 		return
 	}
-	fmt.Printf("RAG_Agent_2 similarities for query '%s':\n", query)
-	for _, sim := range similarities2 {
-		fmt.Println("  -", sim)
-	}
 
-	similarities3, err := ragAgent03.SearchSimilarities(query)
+	query := "Which animals swim?"
+	results, err := retriever.Search(query, rag.WithTopK(4))
 	if err != nil {
-		fmt.Printf("Error searching similarities with RAG_Agent_3: %v\n", err)
+		fmt.Printf("Error searching similarities: %v\n", err)
 		return
 	}
-	fmt.Printf("RAG_Agent_3 similarities for query '%s':\n", query)
-	for _, sim := range similarities3 {
-		fmt.Println("  -", sim)
+	fmt.Printf("Similarities for query '%s':\n", query)
+	for _, result := range results {
+		fmt.Printf("  - %s (score %.3f, best from %s, found by %d agents)\n", result.Content, result.Score, result.Source, len(result.SourceScores))
 	}
 
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chat"
+	"github.com/snipwise/snip-sdk/snip/models"
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/ragchat"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/env"
+)
+
+func main() {
+
+	ctx := context.Background()
+	engineURL := env.GetEnvOrDefault("MODEL_RUNNER_BASE_URL", "http://localhost:12434/engines/llama.cpp/v1")
+	embeddingModelId := env.GetEnvOrDefault("EMBEDDING_MODEL", "ai/embeddinggemma")
+	chatModelId := env.GetEnvOrDefault("CHAT_MODEL", "hf.co/menlo/jan-nano-gguf:q4_k_m")
+
+	ragAgent, err := rag.NewRagAgent(ctx,
+		agents.AgentConfig{
+			Name:      "RAG_Agent",
+			ModelID:   embeddingModelId,
+			EngineURL: engineURL,
+		},
+		rag.StoreConfig{
+			StoreName: "RAG_Store",
+			StorePath: "./data",
+		},
+	)
+	if err != nil {
+		fmt.Printf("Error creating RAG agent: %v\n", err)
+		return
+	}
+
+	chatAgent, err := chat.NewChatAgent(ctx,
+		agents.AgentConfig{
+			Name:               "Chat_Agent",
+			SystemInstructions: "You are a helpful assistant who answers questions about animals.",
+			ModelID:            chatModelId,
+			EngineURL:          engineURL,
+		},
+		models.ModelConfig{
+			Temperature: 0.0,
+		},
+		chat.EnableChatStreamFlowWithMemory(),
+	)
+	if err != nil {
+		fmt.Printf("Error creating chat agent: %v\n", err)
+		return
+	}
+
+	animals := map[string]string{
+		"dolphins": "Dolphins are marine mammals that swim in the ocean and breathe air.",
+		"eagles":   "Eagles are birds of prey that soar above the mountains.",
+		"frogs":    "Frogs are amphibians: they swim in the ponds and jump on the land.",
+		"bears":    "Bears fish salmon in the rivers before hibernating.",
+	}
+	var chunks []text.TextChunk
+	for name, content := range animals {
+		chunks = append(chunks, text.TextChunk{
+			ID:       name,
+			Content:  content,
+			Metadata: map[string]any{"source": "animals.md", "title": name},
+		})
+	}
+	if _, err := ragAgent.UpsertTextChunks(chunks); err != nil {
+		fmt.Printf("Error upserting text chunks: %v\n", err)
+		return
+	}
+
+	// Retrieve the chunks of each question, and answer with numbered citations
+	ragChatAgent, err := ragchat.NewRagChatAgent("Animals_Expert", ragAgent, chatAgent,
+		ragchat.WithSearchOptions(rag.WithTopK(3)),
+		ragchat.WithContextTokens(1024),
+	)
+	if err != nil {
+		fmt.Printf("Error creating RAG chat agent: %v\n", err)
+		return
+	}
+
+	for _, question := range []string{"Which animals swim?", "Which of them also breathe air?"} {
+		fmt.Printf("🤔 %s\n", question)
+		response, err := ragChatAgent.AskStreamWithMemory(question,
+			func(chunk agents.ChatResponse) error {
+				fmt.Print(chunk.Text)
+				return nil
+			},
+		)
+		if err != nil {
+			fmt.Printf("\nError asking question: %v\n", err)
+			return
+		}
+		fmt.Println()
+		for _, source := range response.Sources {
+			fmt.Printf("  [%d] %s (%s, score %.3f)\n", source.Number, source.Title(), source.Metadata["source"], source.Score)
+		}
+		fmt.Println()
+	}
+}
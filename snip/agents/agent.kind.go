@@ -14,5 +14,6 @@ const (
 	Compressor AgentKind = "Compressor"
 	Structured AgentKind = "Structured"
 	Macro AgentKind = "Macro"
+	RagChat AgentKind = "RagChat"
 )
 
@@ -16,6 +16,7 @@ import (
 	"github.com/snipwise/snip-sdk/snip/toolbox/conversion"
 	"github.com/snipwise/snip-sdk/snip/toolbox/env"
 	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+	"github.com/snipwise/snip-sdk/snip/toolbox/metrics"
 
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/core"
@@ -45,7 +46,8 @@ type ChatAgent struct {
 	streamCancel context.CancelFunc
 	streamCtx    context.Context
 
-	logger logger.Logger
+	logger  logger.Logger
+	metrics *metrics.AgentMetrics
 }
 
 func NewChatAgent(
@@ -78,6 +80,7 @@ func NewChatAgent(
 		ctx:            ctx,
 		genKitInstance: genKitInstance,
 		logger:         logger.GetLoggerFromEnvWithPrefix(agentConfig.Name), // Default logger from env
+		metrics:        metrics.DefaultAgentMetrics(),
 	}
 
 	// Apply all options (can override logger)
@@ -121,6 +124,7 @@ func (agent *ChatAgent) GetCurrentContextSize() int {
 func (agent *ChatAgent) AddSystemMessage(context string) error {
 	// Add a system message to the conversation history
 	agent.Messages = append(agent.Messages, ai.NewSystemTextMessage(strings.TrimSpace(context)))
+	agent.recordHistorySize()
 	return nil
 }
 
@@ -130,6 +134,7 @@ func (agent *ChatAgent) ReplaceMessagesWith(messages []*ai.Message) error {
 		return fmt.Errorf("messages cannot be nil")
 	}
 	agent.Messages = messages
+	agent.recordHistorySize()
 	return nil
 }
 
@@ -146,9 +151,59 @@ func (agent *ChatAgent) ReplaceMessagesWithSystemMessages(systemMessages []strin
 	}
 
 	agent.Messages = newMessages
+	agent.recordHistorySize()
 	return nil
 }
 
+// ClearMessages removes the entire conversation history
+func (agent *ChatAgent) ClearMessages() error {
+	agent.Messages = []*ai.Message{}
+	agent.recordHistorySize()
+	return nil
+}
+
+// TruncateMessages keeps only the last keepLast messages of the conversation history
+func (agent *ChatAgent) TruncateMessages(keepLast int) error {
+	if keepLast < 0 {
+		return fmt.Errorf("keepLast cannot be negative")
+	}
+	if keepLast >= len(agent.Messages) {
+		return nil
+	}
+	truncatedMessages := make([]*ai.Message, keepLast)
+	copy(truncatedMessages, agent.Messages[len(agent.Messages)-keepLast:])
+	agent.Messages = truncatedMessages
+	agent.recordHistorySize()
+	return nil
+}
+
+func (agent *ChatAgent) GetSystemInstructions() string {
+	return agent.SystemInstructions
+}
+
+func (agent *ChatAgent) SetSystemInstructions(systemInstructions string) error {
+	agent.SystemInstructions = strings.TrimSpace(systemInstructions)
+	return nil
+}
+
+// GenerateText answers the prompt with the model of the agent and the system instructions system,
+// without the conversation history nor the instructions of the agent
+// (e.g. for the query transformers and the rerankers of the RAG agents, see rag.TextGenerator)
+func (agent *ChatAgent) GenerateText(ctx context.Context, system, prompt string) (string, error) {
+	resp, err := genkit.Generate(ctx, agent.genKitInstance,
+		ai.WithModelName("openai/"+agent.ModelID),
+		ai.WithSystem(system),
+		ai.WithPrompt(prompt),
+		ai.WithConfig(agent.Config.ToOpenAIParams()),
+	)
+	if err != nil {
+		agent.recordGenerationError(generateTextMetricName, err)
+		return "", err
+	}
+	agent.recordGeneratedTokens(generateTextMetricName, resp, 0)
+	return resp.Text(), nil
+}
+
 func (agent *ChatAgent) GetInfo() (agents.AgentInfo, error) {
 	return agents.AgentInfo{
 		Name:    agent.Name,
@@ -272,6 +327,14 @@ func (agent *ChatAgent) GetChatStreamFlowWithMemory() *core.Flow[*agents.ChatReq
 	return agent.chatStreamFlowWithMemory
 }
 
+func (agent *ChatAgent) GetChatFlow() *core.Flow[*agents.ChatRequest, *agents.ChatResponse, struct{}] {
+	return agent.chatFlow
+}
+
+func (agent *ChatAgent) GetChatStreamFlow() *core.Flow[*agents.ChatRequest, *agents.ChatResponse, agents.ChatResponse] {
+	return agent.chatStreamFlow
+}
+
 func displayConversationHistory(agent *ChatAgent) {
 	// For debugging: print conversation history
 	shouldIDisplay := env.GetEnvOrDefault("LOG_MESSAGES", "false")
@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"context"
+	"errors"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/toolbox/metrics"
+)
+
+// Flow names used as "flow" label of the metrics
+const (
+	chatFlowMetricName                 = "chat"
+	chatStreamFlowMetricName           = "chat-stream"
+	chatFlowWithMemoryMetricName       = "chat-with-memory"
+	chatStreamFlowWithMemoryMetricName = "chat-stream-with-memory"
+	generateTextMetricName             = "generate-text"
+)
+
+// GetMetrics returns the metrics recorded by the agent
+func (agent *ChatAgent) GetMetrics() *metrics.AgentMetrics {
+	return agent.metrics
+}
+
+// recordGenerationError records a failed completion
+// A cancelled completion is recorded as a stream cancellation, not as an engine error
+func (agent *ChatAgent) recordGenerationError(flow string, err error) {
+	if errors.Is(err, context.Canceled) {
+		agent.metrics.StreamCancelled(agent.Name)
+		return
+	}
+	agent.metrics.EngineError(agent.Name, flow)
+}
+
+// recordGeneratedTokens records the number of generated tokens reported by the engine
+// When the engine does not report the usage, the number of streamed chunks is used as an estimate
+func (agent *ChatAgent) recordGeneratedTokens(flow string, resp *ai.ModelResponse, streamedChunks int) {
+	tokens := streamedChunks
+	if resp != nil && resp.Usage != nil && resp.Usage.OutputTokens > 0 {
+		tokens = resp.Usage.OutputTokens
+	}
+	agent.metrics.AddGeneratedTokens(agent.Name, flow, tokens)
+}
+
+// recordHistorySize records the number of messages in the conversation history
+func (agent *ChatAgent) recordHistorySize() {
+	agent.metrics.SetHistorySize(agent.Name, metrics.DefaultSession, len(agent.Messages))
+}
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+	"github.com/snipwise/snip-sdk/snip/toolbox/metrics"
 )
 
 // AgentServerOption defines a functional option for configuring the agent
@@ -31,3 +32,11 @@ func WithLogLevel(level logger.LogLevel) ChatAgentOption {
 		a.logger = logger.NewConsoleLoggerWithPrefix(level, a.Name)
 	}
 }
+
+// WithMetrics sets the metrics recorded by the agent
+// By default, the agent records its metrics in metrics.DefaultAgentMetrics()
+func WithMetrics(agentMetrics *metrics.AgentMetrics) ChatAgentOption {
+	return func(a *ChatAgent) {
+		a.metrics = agentMetrics
+	}
+}
@@ -605,6 +605,127 @@ func TestAgentReplaceMessagesWithSystemMessages(t *testing.T) {
 	})
 }
 
+// ============================================================================
+// Tests for Agent.ClearMessages
+// ============================================================================
+
+func TestAgentClearMessages(t *testing.T) {
+	agent := &ChatAgent{
+		Messages: []*ai.Message{
+			ai.NewUserTextMessage("Hello"),
+			ai.NewModelTextMessage("Hi there"),
+		},
+	}
+
+	err := agent.ClearMessages()
+	if err != nil {
+		t.Errorf("ClearMessages() unexpected error: %v", err)
+	}
+
+	if agent.Messages == nil {
+		t.Fatal("Messages should be an empty slice, not nil")
+	}
+
+	if len(agent.Messages) != 0 {
+		t.Errorf("Expected 0 messages, got %d", len(agent.Messages))
+	}
+}
+
+// ============================================================================
+// Tests for Agent.TruncateMessages
+// ============================================================================
+
+func TestAgentTruncateMessages(t *testing.T) {
+	newAgent := func() *ChatAgent {
+		return &ChatAgent{
+			Messages: []*ai.Message{
+				ai.NewUserTextMessage("Message 1"),
+				ai.NewModelTextMessage("Message 2"),
+				ai.NewUserTextMessage("Message 3"),
+				ai.NewModelTextMessage("Message 4"),
+			},
+		}
+	}
+
+	t.Run("keep last messages", func(t *testing.T) {
+		agent := newAgent()
+
+		err := agent.TruncateMessages(2)
+		if err != nil {
+			t.Errorf("TruncateMessages() unexpected error: %v", err)
+		}
+
+		if len(agent.Messages) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(agent.Messages))
+		}
+
+		if agent.Messages[0].Content[0].Text != "Message 3" {
+			t.Errorf("First message = %q, want %q", agent.Messages[0].Content[0].Text, "Message 3")
+		}
+		if agent.Messages[1].Content[0].Text != "Message 4" {
+			t.Errorf("Second message = %q, want %q", agent.Messages[1].Content[0].Text, "Message 4")
+		}
+	})
+
+	t.Run("keep more than available", func(t *testing.T) {
+		agent := newAgent()
+
+		err := agent.TruncateMessages(10)
+		if err != nil {
+			t.Errorf("TruncateMessages() unexpected error: %v", err)
+		}
+
+		if len(agent.Messages) != 4 {
+			t.Errorf("Expected 4 messages, got %d", len(agent.Messages))
+		}
+	})
+
+	t.Run("keep zero", func(t *testing.T) {
+		agent := newAgent()
+
+		err := agent.TruncateMessages(0)
+		if err != nil {
+			t.Errorf("TruncateMessages() unexpected error: %v", err)
+		}
+
+		if len(agent.Messages) != 0 {
+			t.Errorf("Expected 0 messages, got %d", len(agent.Messages))
+		}
+	})
+
+	t.Run("negative value", func(t *testing.T) {
+		agent := newAgent()
+
+		err := agent.TruncateMessages(-1)
+		if err == nil {
+			t.Error("TruncateMessages(-1) expected error, got nil")
+		}
+
+		if len(agent.Messages) != 4 {
+			t.Errorf("Messages should be unchanged, got %d", len(agent.Messages))
+		}
+	})
+}
+
+// ============================================================================
+// Tests for Agent.SetSystemInstructions
+// ============================================================================
+
+func TestAgentSetSystemInstructions(t *testing.T) {
+	agent := &ChatAgent{
+		SystemInstructions: "You are a helpful assistant",
+	}
+
+	err := agent.SetSystemInstructions("  You are a Go expert  ")
+	if err != nil {
+		t.Errorf("SetSystemInstructions() unexpected error: %v", err)
+	}
+
+	if agent.GetSystemInstructions() != "You are a Go expert" {
+		t.Errorf("GetSystemInstructions() = %q, want %q", agent.GetSystemInstructions(), "You are a Go expert")
+	}
+}
+
 // ============================================================================
 // Tests for Agent.GetInfo
 // ============================================================================
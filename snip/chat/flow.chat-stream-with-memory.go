@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/core"
@@ -30,6 +31,12 @@ func initializeChatStreamFlowWithMemory(agent *ChatAgent) {
 			agent.streamCtx = streamCtx
 			agent.streamCancel = streamCancel
 
+			// === METRICS ===
+			agent.metrics.StreamStarted(agent.Name)
+			defer agent.metrics.StreamEnded(agent.Name)
+			startTime := time.Now()
+			streamedChunks := 0
+
 			// === DEBUG: CONTEXT SIZE ===
 			// Log total context size for debugging
 			totalContextSize := len(agent.SystemInstructions) + len(input.UserMessage)
@@ -61,6 +68,11 @@ func initializeChatStreamFlowWithMemory(agent *ChatAgent) {
 					case <-streamCtx.Done():
 						return streamCtx.Err()
 					default:
+						streamedChunks++
+						if streamedChunks == 1 {
+							agent.metrics.ObserveTimeToFirstToken(agent.Name, chatStreamFlowWithMemoryMetricName, time.Since(startTime))
+						}
+
 						// Send ChatResponse with the chunk text
 						return callback(ctx, agents.ChatResponse{
 							Text:    chunk.Text(),
@@ -75,6 +87,8 @@ func initializeChatStreamFlowWithMemory(agent *ChatAgent) {
 				agent.streamCancel = nil
 				agent.streamCtx = nil
 
+				agent.recordGenerationError(chatStreamFlowWithMemoryMetricName, err)
+
 				// Log detailed error information
 				agent.logger.Error("❌ Generation error: %v", err)
 				agent.logger.Error("Context details - Total size: %d chars, Messages: %d",
@@ -83,6 +97,8 @@ func initializeChatStreamFlowWithMemory(agent *ChatAgent) {
 				return nil, fmt.Errorf("generation failed (context size: %d chars): %w", totalContextSize, err)
 			}
 
+			agent.recordGeneratedTokens(chatStreamFlowWithMemoryMetricName, resp, streamedChunks)
+
 			// Send a final callback with complete metadata (FinishReason and FinishMessage)
 			finalChunk := agents.ChatResponse{
 				Text:          "", // Empty text since all text was already streamed
@@ -100,6 +116,8 @@ func initializeChatStreamFlowWithMemory(agent *ChatAgent) {
 			// ASSISTANT MESSAGE: append assistant response to history
 			agent.Messages = append(agent.Messages, ai.NewModelTextMessage(strings.TrimSpace(resp.Text())))
 
+			agent.recordHistorySize()
+
 			// DEBUG: print conversation history
 			displayConversationHistory(agent)
 
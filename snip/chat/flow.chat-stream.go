@@ -3,6 +3,7 @@ package chat
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/snipwise/snip-sdk/snip/agents"
 
@@ -28,6 +29,12 @@ func initializeChatStreamFlow(agent *ChatAgent) {
 			agent.streamCtx = streamCtx
 			agent.streamCancel = streamCancel
 
+			// === METRICS ===
+			agent.metrics.StreamStarted(agent.Name)
+			defer agent.metrics.StreamEnded(agent.Name)
+			startTime := time.Now()
+			streamedChunks := 0
+
 			// === DEBUG: CONTEXT SIZE ===
 			// Log total context size for debugging
 			totalContextSize := len(agent.SystemInstructions) + len(input.UserMessage)
@@ -65,6 +72,11 @@ func initializeChatStreamFlow(agent *ChatAgent) {
 						// 	}
 						// }
 
+						streamedChunks++
+						if streamedChunks == 1 {
+							agent.metrics.ObserveTimeToFirstToken(agent.Name, chatStreamFlowMetricName, time.Since(startTime))
+						}
+
 						// Send ChatResponse with the chunk text
 						return callback(ctx, agents.ChatResponse{
 							Text:    chunk.Text(),
@@ -79,6 +91,8 @@ func initializeChatStreamFlow(agent *ChatAgent) {
 				agent.streamCancel = nil
 				agent.streamCtx = nil
 
+				agent.recordGenerationError(chatStreamFlowMetricName, err)
+
 				// Log detailed error information
 				agent.logger.Error("❌ Generation error: %v", err)
 				agent.logger.Error("Context details - Total size: %d chars, Messages: %d",
@@ -87,6 +101,8 @@ func initializeChatStreamFlow(agent *ChatAgent) {
 				return nil, fmt.Errorf("generation failed (context size: %d chars): %w", totalContextSize, err)
 			}
 
+			agent.recordGeneratedTokens(chatStreamFlowMetricName, resp, streamedChunks)
+
 			// Send a final callback with complete metadata (FinishReason and FinishMessage)
 			finalChunk := agents.ChatResponse{
 				Text:          "", // Empty text since all text was already streamed
@@ -32,8 +32,10 @@ func initializeChatFlowWithMemory(agent *ChatAgent) {
 				),
 			)
 			if err != nil {
+				agent.recordGenerationError(chatFlowWithMemoryMetricName, err)
 				return nil, err
 			}
+			agent.recordGeneratedTokens(chatFlowWithMemoryMetricName, resp, 0)
 			// === CONVERSATIONAL MEMORY ===
 
 			// USER MESSAGE: append user message to history
@@ -41,6 +43,8 @@ func initializeChatFlowWithMemory(agent *ChatAgent) {
 			// ASSISTANT MESSAGE: append assistant response to history
 			agent.Messages = append(agent.Messages, ai.NewModelTextMessage(strings.TrimSpace(resp.Text())))
 
+			agent.recordHistorySize()
+
 			// DEBUG: print conversation history
 			displayConversationHistory(agent)
 
@@ -31,8 +31,10 @@ func initializeChatFlow(agent *ChatAgent) {
 				),
 			)
 			if err != nil {
+				agent.recordGenerationError(chatFlowMetricName, err)
 				return nil, err
 			}
+			agent.recordGeneratedTokens(chatFlowMetricName, resp, 0)
 
 			return &agents.ChatResponse{
 				Text:             resp.Text(),
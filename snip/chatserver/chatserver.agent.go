@@ -3,27 +3,65 @@ package chatserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip"
 	"github.com/snipwise/snip-sdk/snip/agents"
 	"github.com/snipwise/snip-sdk/snip/chat"
 	"github.com/snipwise/snip-sdk/snip/models"
+	"github.com/snipwise/snip-sdk/snip/registry"
 	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
 )
 
 type ChatAgentServer struct {
 	agent *chat.ChatAgent
 
+	compressorAgent snip.AICompressorAgent
+
 	serverConfig *ConfigHTTP
 	httpServer   *http.Server
 	serverCancel context.CancelFunc
 
+	// endpoints registered by Handler (used by the OpenAPI and discovery documents)
+	endpoints []Endpoint
+
+	// HTTP server lifecycle settings (see server.options.go)
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+	tlsCertFile       string
+	tlsKeyFile        string
+	listener          net.Listener
+	handleSignals     bool
+
+	// buffered streams, resumable with Last-Event-ID (see server.stream.go)
+	streams          *streamRegistry
+	streamsOnce      sync.Once
+	streamBufferSize int
+	streamRetention  time.Duration
+	// generations are the streaming generations running, drained by Stop
+	generations sync.WaitGroup
+
+	// registry where the server announces itself while it serves (see WithRegistry)
+	registry          registry.Registry
+	advertisedAddress string
+	heartbeatInterval time.Duration
+	// announcement is the registration of the serving server, stopped first by Stop
+	announcement *registry.Announcement
+
 	logger logger.Logger
 
 	ctx context.Context
@@ -57,6 +95,9 @@ func NewChatAgentServer(
 		agent:  agent,
 		ctx:    ctx,
 		logger: &logger.NoOpLogger{}, // Initialize with a no-op logger by default
+
+		shutdownTimeout: DefaultShutdownTimeout,
+		handleSignals:   true,
 	}
 	for _, opt := range opts {
 		opt(chatAgentServer)
@@ -93,6 +134,22 @@ func (cas *ChatAgentServer) ReplaceMessagesWithSystemMessages(systemMessages []s
 	return cas.agent.ReplaceMessagesWithSystemMessages(systemMessages)
 }
 
+func (cas *ChatAgentServer) ClearMessages() error {
+	return cas.agent.ClearMessages()
+}
+
+func (cas *ChatAgentServer) TruncateMessages(keepLast int) error {
+	return cas.agent.TruncateMessages(keepLast)
+}
+
+func (cas *ChatAgentServer) GetSystemInstructions() string {
+	return cas.agent.GetSystemInstructions()
+}
+
+func (cas *ChatAgentServer) SetSystemInstructions(systemInstructions string) error {
+	return cas.agent.SetSystemInstructions(systemInstructions)
+}
+
 func (cas *ChatAgentServer) GetInfo() (agents.AgentInfo, error) {
 	return cas.agent.GetInfo()
 }
@@ -113,15 +170,16 @@ func (cas *ChatAgentServer) AskStream(question string, callback func(agents.Chat
 	return cas.agent.AskStream(question, callback)
 }
 
-// Serve starts the HTTP server with the configured endpoints for the agent's flows
-// The server automatically handles SIGINT (Ctrl+C) and SIGTERM signals for graceful shutdown
-// Use the Stop() method to manually shutdown the server
-func (cas *ChatAgentServer) Serve() error {
+// Handler returns an http.Handler exposing the configured endpoints for the agent's flows
+// It can be mounted on an existing mux, e.g. mux.Handle("/agent/", http.StripPrefix("/agent", cas.Handler()))
+// Returns nil if the server configuration is not set (use EnableServer option)
+func (cas *ChatAgentServer) Handler() http.Handler {
 	if cas.serverConfig == nil {
-		return fmt.Errorf("server configuration is not set, use EnableServer option")
+		return nil
 	}
 
 	mux := http.NewServeMux()
+	cas.endpoints = []Endpoint{}
 
 	// Set default values for paths if not provided
 	if cas.serverConfig.ChatFlowPath == "" {
@@ -130,6 +188,12 @@ func (cas *ChatAgentServer) Serve() error {
 	if cas.serverConfig.ChatStreamFlowPath == "" {
 		cas.serverConfig.ChatStreamFlowPath = DefaultChatStreamFlowPath
 	}
+	if cas.serverConfig.AskFlowPath == "" {
+		cas.serverConfig.AskFlowPath = DefaultAskFlowPath
+	}
+	if cas.serverConfig.AskStreamFlowPath == "" {
+		cas.serverConfig.AskStreamFlowPath = DefaultAskStreamFlowPath
+	}
 	if cas.serverConfig.InformationPath == "" {
 		cas.serverConfig.InformationPath = DefaultInformationPath
 	}
@@ -148,19 +212,56 @@ func (cas *ChatAgentServer) Serve() error {
 	if cas.serverConfig.GetMessagesPath == "" {
 		cas.serverConfig.GetMessagesPath = DefaultGetMessagesPath
 	}
+	if cas.serverConfig.OpenAPIPath == "" {
+		cas.serverConfig.OpenAPIPath = DefaultOpenAPIPath
+	}
+	if cas.serverConfig.DiscoveryPath == "" {
+		cas.serverConfig.DiscoveryPath = DefaultDiscoveryPath
+	}
+	if cas.serverConfig.MetricsPath == "" {
+		cas.serverConfig.MetricsPath = DefaultMetricsPath
+	}
+	if cas.serverConfig.ReplaceMessagesPath == "" {
+		cas.serverConfig.ReplaceMessagesPath = DefaultReplaceMessagesPath
+	}
+	if cas.serverConfig.ReplaceWithSystemMessagesPath == "" {
+		cas.serverConfig.ReplaceWithSystemMessagesPath = DefaultReplaceWithSystemMessagesPath
+	}
+	if cas.serverConfig.ClearMessagesPath == "" {
+		cas.serverConfig.ClearMessagesPath = DefaultClearMessagesPath
+	}
+	if cas.serverConfig.TruncateMessagesPath == "" {
+		cas.serverConfig.TruncateMessagesPath = DefaultTruncateMessagesPath
+	}
+	if cas.serverConfig.SystemInstructionsPath == "" {
+		cas.serverConfig.SystemInstructionsPath = DefaultSystemInstructionsPath
+	}
+	if cas.serverConfig.CompressContextPath == "" {
+		cas.serverConfig.CompressContextPath = DefaultCompressContextPath
+	}
+	if cas.serverConfig.CompressContextStreamPath == "" {
+		cas.serverConfig.CompressContextStreamPath = DefaultCompressContextStreamPath
+	}
 
 	// Register healthcheck endpoint
 	healthcheckPath := cas.serverConfig.HealthcheckPath
-	mux.HandleFunc("GET "+healthcheckPath, func(w http.ResponseWriter, r *http.Request) {
+	cas.handle(mux, Endpoint{
+		Name: EndpointHealthcheck, Method: "GET", Path: healthcheckPath,
+		Summary:  "Check that the server is up",
+		response: StatusResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ok"}`))
 	})
-	cas.logger.Info("Registered endpoint: GET %s", healthcheckPath)
 
 	// Register agent information endpoint
 	informationPath := cas.serverConfig.InformationPath
-	mux.HandleFunc("GET "+informationPath, func(w http.ResponseWriter, r *http.Request) {
+	cas.handle(mux, Endpoint{
+		Name: EndpointInformation, Method: "GET", Path: informationPath,
+		Summary:  "Get the agent information",
+		response: agents.AgentInfo{},
+	}, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		info := agents.AgentInfo{
 			Name:    cas.agent.Name,
@@ -172,29 +273,48 @@ func (cas *ChatAgentServer) Serve() error {
 			return
 		}
 	})
-	cas.logger.Info("Registered endpoint: GET %s", informationPath)
 
 	// IMPORTANT: with memory flows
 	// Register chat flow endpoint if available
 	if cas.agent.GetChatFlowWithMemory() != nil && cas.serverConfig.ChatFlowHandler != nil {
 		chatFlowPath := cas.serverConfig.ChatFlowPath
-		mux.HandleFunc("POST "+chatFlowPath, cas.serverConfig.ChatFlowHandler)
-		cas.logger.Info("Registered endpoint: POST %s", chatFlowPath)
+		cas.handle(mux, Endpoint{
+			Name: EndpointChat, Method: "POST", Path: chatFlowPath,
+			Summary: "Ask a question (with conversational memory)",
+			request: FlowRequest{}, response: FlowResponse{},
+		}, cas.serverConfig.ChatFlowHandler)
 	}
 	// IMPORTANT: with memory flows
 	// Register chat stream flow endpoint if available
 	if cas.agent.GetChatStreamFlowWithMemory() != nil && cas.serverConfig.ChatStreamFlowHandler != nil {
 		chatStreamFlowPath := cas.serverConfig.ChatStreamFlowPath
-		mux.HandleFunc("POST "+chatStreamFlowPath, cas.serverConfig.ChatStreamFlowHandler)
-		cas.logger.Info("Registered endpoint: POST %s", chatStreamFlowPath)
+		cas.handle(mux, Endpoint{
+			Name: EndpointChatStream, Method: "POST", Path: chatStreamFlowPath, Streaming: true,
+			Summary: "Ask a question with streaming (with conversational memory), resumable with the Last-Event-ID header",
+			request: FlowRequest{}, response: StreamEvent{}, responseContentType: "text/event-stream",
+		}, cas.serverConfig.ChatStreamFlowHandler)
 	}
 
-	// Create server context with cancel
-	//serverCtx, cancel := context.WithCancel(cas.agent.ctx)
-	// NOTE: TODO: to be checked
-	serverCtx, cancel := context.WithCancel(cas.ctx)
-
-	cas.serverCancel = cancel
+	// IMPORTANT: without memory flows
+	// Register stateless chat flow endpoint if available
+	if cas.agent.GetChatFlow() != nil && cas.serverConfig.AskFlowHandler != nil {
+		askFlowPath := cas.serverConfig.AskFlowPath
+		cas.handle(mux, Endpoint{
+			Name: EndpointAsk, Method: "POST", Path: askFlowPath,
+			Summary: "Ask a question (without memory)",
+			request: FlowRequest{}, response: FlowResponse{},
+		}, cas.serverConfig.AskFlowHandler)
+	}
+	// IMPORTANT: without memory flows
+	// Register stateless chat stream flow endpoint if available
+	if cas.agent.GetChatStreamFlow() != nil && cas.serverConfig.AskStreamFlowHandler != nil {
+		askStreamFlowPath := cas.serverConfig.AskStreamFlowPath
+		cas.handle(mux, Endpoint{
+			Name: EndpointAskStream, Method: "POST", Path: askStreamFlowPath, Streaming: true,
+			Summary: "Ask a question with streaming (without memory), resumable with the Last-Event-ID header",
+			request: FlowRequest{}, response: StreamEvent{}, responseContentType: "text/event-stream",
+		}, cas.serverConfig.AskStreamFlowHandler)
+	}
 
 	// Register shutdown endpoint if enabled
 	shutdownPath := cas.serverConfig.ShutdownPath
@@ -202,7 +322,11 @@ func (cas *ChatAgentServer) Serve() error {
 		if shutdownPath == "" {
 			shutdownPath = DefaultShutdownPath
 		}
-		mux.HandleFunc("POST "+shutdownPath, func(w http.ResponseWriter, r *http.Request) {
+		cas.handle(mux, Endpoint{
+			Name: EndpointShutdown, Method: "POST", Path: shutdownPath,
+			Summary:  "Shut down the server",
+			response: StatusResponse{},
+		}, func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"status":"shutting down"}`))
@@ -210,20 +334,54 @@ func (cas *ChatAgentServer) Serve() error {
 			cas.logger.Info("Shutdown requested via HTTP endpoint")
 
 			// Trigger shutdown asynchronously to allow response to be sent
+			// (only effective when the server is started with Serve)
 			go func() {
 				time.Sleep(100 * time.Millisecond)
-				cancel()
+				if cas.serverCancel != nil {
+					cas.serverCancel()
+				}
 			}()
 		})
-		cas.logger.Info("Registered endpoint: POST %s", shutdownPath)
 	}
 
 	// Register cancel stream endpoint
 	cancelStreamPath := cas.serverConfig.CancelStreamPath
 	if cancelStreamPath != "" {
-		mux.HandleFunc("POST "+cancelStreamPath, func(w http.ResponseWriter, r *http.Request) {
+		cas.handle(mux, Endpoint{
+			Name: EndpointCancelStream, Method: "POST", Path: cancelStreamPath,
+			Summary:  "Cancel a streaming completion by its stream id, or the current one",
+			request:  CancelStreamRequest{},
+			response: StatusResponse{},
+		}, func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 
+			// The body is optional (the clients without stream id cancel the current completion)
+			var req CancelStreamRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"status":"error","message":"invalid request body"}`))
+				return
+			}
+
+			if req.StreamID != "" {
+				cancelled, alone := cas.getStreams().cancel(req.StreamID)
+				if !cancelled {
+					cas.logger.Info("No active stream %s to cancel", req.StreamID)
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"status":"no active stream"}`))
+					return
+				}
+				// The agent cancels its current completion: only when it is this stream,
+				// to stop it before its next chunk (e.g. while the model loads)
+				if cancelFunc := cas.agent.GetStreamCancel(); alone && cancelFunc != nil {
+					cancelFunc()
+				}
+				cas.logger.Info("Stream %s cancelled via HTTP endpoint", req.StreamID)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"status":"stream cancelled"}`))
+				return
+			}
+
 			cancelFunc := cas.agent.GetStreamCancel()
 			if cancelFunc != nil {
 				cancelFunc()
@@ -236,19 +394,20 @@ func (cas *ChatAgentServer) Serve() error {
 				w.Write([]byte(`{"status":"no active stream"}`))
 			}
 		})
-		cas.logger.Info("Registered endpoint: POST %s", cancelStreamPath)
 	}
 
 	// Register add context endpoint
 	addContextPath := cas.serverConfig.AddContextPath
 	if addContextPath != "" {
-		mux.HandleFunc("POST "+addContextPath, func(w http.ResponseWriter, r *http.Request) {
+		cas.handle(mux, Endpoint{
+			Name: EndpointAddSystemMessage, Method: "POST", Path: addContextPath,
+			Summary: "Add a system message to the conversation history",
+			request: AddSystemMessageRequest{}, response: StatusResponse{},
+		}, func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 
 			// Parse request body
-			var req struct {
-				Context string `json:"context"`
-			}
+			var req AddSystemMessageRequest
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				cas.logger.Error("Error decoding add context request: %v", err)
 				w.WriteHeader(http.StatusBadRequest)
@@ -268,13 +427,16 @@ func (cas *ChatAgentServer) Serve() error {
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"status":"success"}`))
 		})
-		cas.logger.Info("Registered endpoint: POST %s", addContextPath)
 	}
 
 	// Register get messages endpoint
 	getMessagesPath := cas.serverConfig.GetMessagesPath
 	if getMessagesPath != "" {
-		mux.HandleFunc("GET "+getMessagesPath, func(w http.ResponseWriter, r *http.Request) {
+		cas.handle(mux, Endpoint{
+			Name: EndpointGetMessages, Method: "GET", Path: getMessagesPath,
+			Summary:  "Get the conversation history",
+			response: []*ai.Message{},
+		}, func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 
 			// Encode messages to JSON
@@ -287,27 +449,89 @@ func (cas *ChatAgentServer) Serve() error {
 
 			cas.logger.Debug("Messages retrieved via HTTP endpoint")
 		})
-		cas.logger.Info("Registered endpoint: GET %s", getMessagesPath)
 	}
 
+	// Register metrics endpoint
+	metricsPath := cas.serverConfig.MetricsPath
+	if metricsPath != "-" && cas.agent.GetMetrics() != nil {
+		cas.handle(mux, Endpoint{
+			Name: EndpointMetrics, Method: "GET", Path: metricsPath,
+			Summary:  "Get the metrics in Prometheus text format",
+			response: "", responseContentType: "text/plain",
+		}, cas.agent.GetMetrics().Registry.Handler().ServeHTTP)
+	}
+
+	// Register history management endpoints
+	cas.registerHistoryEndpoints(mux)
+
+	// Register context compression endpoints (only if a compressor agent is configured)
+	if cas.compressorAgent != nil {
+		cas.registerCompressionEndpoints(mux)
+	}
+
+	// Register OpenAPI and discovery endpoints
+	cas.registerDiscoveryEndpoints(mux)
+
+	return cas.instrumentHandler(mux)
+}
+
+// Serve starts the HTTP server with the configured endpoints for the agent's flows
+// By default, the server handles SIGINT (Ctrl+C) and SIGTERM signals for graceful shutdown
+// (use WithSignalHandling(false) to disable it)
+// Serve returns when the server stops, the context of the server is cancelled, or the shutdown endpoint is called
+// Use the Stop() method to manually shutdown the server
+func (cas *ChatAgentServer) Serve() error {
+	if cas.serverConfig == nil {
+		return fmt.Errorf("server configuration is not set, use EnableServer option")
+	}
+
+	// Create server context with cancel
+	serverCtx, cancel := context.WithCancel(cas.ctx)
+	defer cancel()
+
+	cas.serverCancel = cancel
+
 	cas.httpServer = &http.Server{
-		Addr:    cas.serverConfig.Address,
-		Handler: mux,
+		Addr:              cas.serverConfig.Address,
+		Handler:           cas.Handler(),
+		ReadTimeout:       cas.readTimeout,
+		ReadHeaderTimeout: cas.readHeaderTimeout,
+		WriteTimeout:      cas.writeTimeout,
+		IdleTimeout:       cas.idleTimeout,
 	}
 
 	// Setup signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	// A nil channel blocks forever when signal handling is disabled
+	var sigChan chan os.Signal
+	if cas.handleSignals {
+		sigChan = make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigChan)
+	}
 
 	// Channel to listen for errors from server
 	serverErrors := make(chan error, 1)
 
 	// Start the server in a goroutine
 	go func() {
-		cas.logger.Info("Starting HTTP server on %s (Press Ctrl+C to stop)", cas.serverConfig.Address)
-		serverErrors <- cas.httpServer.ListenAndServe()
+		serverErrors <- cas.listenAndServe()
 	}()
 
+	// Announce the server in the registry, and remove it when the server stops
+	if cas.registry != nil {
+		announcement, err := registry.Announce(cas.registry, registry.Instance{
+			Name:    cas.GetName(),
+			Kind:    cas.Kind(),
+			Address: cas.getAdvertisedAddress(),
+		}, cas.heartbeatInterval, cas.logger)
+		if err != nil {
+			cas.logger.Error("Error registering the server: %v", err)
+		} else {
+			cas.announcement = announcement
+			defer announcement.Stop()
+		}
+	}
+
 	// Wait for either context cancellation, signal, or server error
 	select {
 	case err := <-serverErrors:
@@ -323,7 +547,52 @@ func (cas *ChatAgentServer) Serve() error {
 	}
 }
 
-// Stop gracefully shuts down the HTTP server with a 5-second timeout
+// getAdvertisedAddress returns the base URL of the server in the registry:
+// the address set with WithRegistry, or the address of the listener or of the configuration
+func (cas *ChatAgentServer) getAdvertisedAddress() string {
+	if cas.advertisedAddress != "" {
+		return strings.TrimRight(cas.advertisedAddress, "/")
+	}
+
+	address := cas.serverConfig.Address
+	if cas.listener != nil {
+		address = cas.listener.Addr().String()
+	}
+	// ":8080" or "0.0.0.0:8080" are not reachable addresses
+	if host, port, err := net.SplitHostPort(address); err == nil && (host == "" || host == "0.0.0.0" || host == "::") {
+		address = net.JoinHostPort("localhost", port)
+	}
+
+	if cas.tlsCertFile != "" && cas.tlsKeyFile != "" {
+		return "https://" + address
+	}
+	return "http://" + address
+}
+
+// listenAndServe serves on the listener provided with WithListener, or listens on the configured address
+// TLS is used when a certificate and a key are provided with WithTLS
+func (cas *ChatAgentServer) listenAndServe() error {
+	useTLS := cas.tlsCertFile != "" && cas.tlsKeyFile != ""
+
+	if cas.listener != nil {
+		cas.logger.Info("Starting HTTP server on %s (TLS: %v)", cas.listener.Addr(), useTLS)
+		if useTLS {
+			return cas.httpServer.ServeTLS(cas.listener, cas.tlsCertFile, cas.tlsKeyFile)
+		}
+		return cas.httpServer.Serve(cas.listener)
+	}
+
+	cas.logger.Info("Starting HTTP server on %s (TLS: %v)", cas.serverConfig.Address, useTLS)
+	if useTLS {
+		return cas.httpServer.ListenAndServeTLS(cas.tlsCertFile, cas.tlsKeyFile)
+	}
+	return cas.httpServer.ListenAndServe()
+}
+
+// Stop gracefully shuts down the HTTP server
+// Active streams, including the generations whose client left, are drained until the shutdown deadline
+// (5 seconds by default, see WithShutdownTimeout)
+// After the deadline, the current streaming completion is cancelled and the remaining connections are closed
 func (cas *ChatAgentServer) Stop() error {
 	if cas.httpServer == nil {
 		return fmt.Errorf("server is not running")
@@ -331,10 +600,29 @@ func (cas *ChatAgentServer) Stop() error {
 
 	cas.logger.Info("Shutting down server gracefully...")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// The server leaves the registry before draining, so that the clients stop choosing it
+	if cas.announcement != nil {
+		if err := cas.announcement.Stop(); err != nil {
+			cas.logger.Warn("Error deregistering the server: %v", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cas.shutdownTimeout)
 	defer cancel()
 
-	if err := cas.httpServer.Shutdown(shutdownCtx); err != nil {
+	err := cas.httpServer.Shutdown(shutdownCtx)
+	if err == nil {
+		err = cas.waitGenerations(shutdownCtx)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			cas.logger.Warn("Shutdown deadline exceeded, cancelling active streams")
+			cas.getStreams().cancelAll()
+			if cancelFunc := cas.agent.GetStreamCancel(); cancelFunc != nil {
+				cancelFunc()
+			}
+			cas.httpServer.Close()
+		}
 		return fmt.Errorf("error during shutdown: %w", err)
 	}
 
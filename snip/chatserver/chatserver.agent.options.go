@@ -1,6 +1,11 @@
 package chatserver
 
-import "github.com/snipwise/snip-sdk/snip/toolbox/logger"
+import (
+	"github.com/snipwise/snip-sdk/snip"
+	"github.com/snipwise/snip-sdk/snip/chat"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+	"github.com/snipwise/snip-sdk/snip/toolbox/metrics"
+)
 
 // ChatAgentServerOption defines a functional option for configuring the agent
 type ChatAgentServerOption func(*ChatAgentServer)
@@ -29,3 +34,19 @@ func WithLogLevel(level logger.LogLevel) ChatAgentServerOption {
 		chatAgentServer.logger = logger.NewConsoleLoggerWithPrefix(level, chatAgentServer.agent.Name)
 	}
 }
+
+// WithCompressorAgent sets the compressor agent used to compress the conversation history
+// It enables the compress context endpoints of the server
+func WithCompressorAgent(compressorAgent snip.AICompressorAgent) ChatAgentServerOption {
+	return func(chatAgentServer *ChatAgentServer) {
+		chatAgentServer.compressorAgent = compressorAgent
+	}
+}
+
+// WithMetrics sets the metrics recorded by the server and its agent
+// By default, the metrics are recorded in metrics.DefaultAgentMetrics()
+func WithMetrics(agentMetrics *metrics.AgentMetrics) ChatAgentServerOption {
+	return func(chatAgentServer *ChatAgentServer) {
+		chat.WithMetrics(agentMetrics)(chatAgentServer.agent)
+	}
+}
@@ -0,0 +1,303 @@
+package chatserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/chat"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// newTestServer creates a server around a chat agent without flows (no model needed)
+func newTestServer(config ConfigHTTP, opts ...ChatAgentServerOption) *ChatAgentServer {
+	cas := &ChatAgentServer{
+		agent: &chat.ChatAgent{
+			Name:               "test-agent",
+			SystemInstructions: "You are a helpful assistant",
+			Messages:           []*ai.Message{},
+		},
+		serverConfig:    &config,
+		ctx:             context.Background(),
+		logger:          &logger.NoOpLogger{},
+		shutdownTimeout: DefaultShutdownTimeout,
+		handleSignals:   true,
+	}
+	for _, opt := range opts {
+		opt(cas)
+	}
+	return cas
+}
+
+// ============================================================================
+// Tests for ChatAgentServer.Handler
+// ============================================================================
+
+func TestHandlerWithoutConfig(t *testing.T) {
+	cas := &ChatAgentServer{}
+	if cas.Handler() != nil {
+		t.Error("Handler() should return nil without server configuration")
+	}
+}
+
+func TestHandlerHealthcheck(t *testing.T) {
+	server := httptest.NewServer(newTestServer(ConfigHTTP{}).Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + DefaultHealthcheckPath)
+	if err != nil {
+		t.Fatalf("GET healthcheck unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestHandlerHistoryEndpoints(t *testing.T) {
+	cas := newTestServer(ConfigHTTP{})
+	server := httptest.NewServer(cas.Handler())
+	defer server.Close()
+
+	post := func(path, body string) int {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s unexpected error: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("replace messages", func(t *testing.T) {
+		body := `{"messages":[{"role":"user","content":[{"text":"Hello"}]},{"role":"model","content":[{"text":"Hi"}]},{"role":"user","content":[{"text":"Bye"}]}]}`
+		if code := post(DefaultReplaceMessagesPath, body); code != http.StatusOK {
+			t.Fatalf("StatusCode = %d, want %d", code, http.StatusOK)
+		}
+		if len(cas.GetMessages()) != 3 {
+			t.Errorf("Messages length = %d, want 3", len(cas.GetMessages()))
+		}
+	})
+
+	t.Run("truncate messages", func(t *testing.T) {
+		if code := post(DefaultTruncateMessagesPath, `{"keep_last":1}`); code != http.StatusOK {
+			t.Fatalf("StatusCode = %d, want %d", code, http.StatusOK)
+		}
+		if len(cas.GetMessages()) != 1 || cas.GetMessages()[0].Content[0].Text != "Bye" {
+			t.Errorf("Messages = %v, want only the last one", cas.GetMessages())
+		}
+		if code := post(DefaultTruncateMessagesPath, `{"keep_last":-1}`); code != http.StatusBadRequest {
+			t.Errorf("StatusCode = %d, want %d", code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("replace with system messages", func(t *testing.T) {
+		if code := post(DefaultReplaceWithSystemMessagesPath, `{"system_messages":["Be brief","Be kind"]}`); code != http.StatusOK {
+			t.Fatalf("StatusCode = %d, want %d", code, http.StatusOK)
+		}
+		if len(cas.GetMessages()) != 2 || cas.GetMessages()[0].Role != ai.RoleSystem {
+			t.Errorf("Messages = %v, want 2 system messages", cas.GetMessages())
+		}
+	})
+
+	t.Run("clear messages", func(t *testing.T) {
+		if code := post(DefaultClearMessagesPath, ``); code != http.StatusOK {
+			t.Fatalf("StatusCode = %d, want %d", code, http.StatusOK)
+		}
+		if len(cas.GetMessages()) != 0 {
+			t.Errorf("Messages length = %d, want 0", len(cas.GetMessages()))
+		}
+	})
+
+	t.Run("system instructions", func(t *testing.T) {
+		if code := post(DefaultSystemInstructionsPath, `{"system_instructions":"You are a Go expert"}`); code != http.StatusOK {
+			t.Fatalf("StatusCode = %d, want %d", code, http.StatusOK)
+		}
+
+		resp, err := http.Get(server.URL + DefaultSystemInstructionsPath)
+		if err != nil {
+			t.Fatalf("GET system instructions unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var body struct {
+			SystemInstructions string `json:"system_instructions"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.SystemInstructions != "You are a Go expert" {
+			t.Errorf("SystemInstructions = %q, want %q", body.SystemInstructions, "You are a Go expert")
+		}
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		if code := post(DefaultReplaceMessagesPath, `not json`); code != http.StatusBadRequest {
+			t.Errorf("StatusCode = %d, want %d", code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestHandlerCompressionEndpointsRequireCompressor(t *testing.T) {
+	server := httptest.NewServer(newTestServer(ConfigHTTP{}).Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+DefaultCompressContextPath, "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST compress context unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestHandlerDiscoveryDocument(t *testing.T) {
+	server := httptest.NewServer(newTestServer(ConfigHTTP{ClearMessagesPath: "/custom/clear"}).Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + DefaultDiscoveryPath)
+	if err != nil {
+		t.Fatalf("GET discovery unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var document DiscoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		t.Fatalf("Decode discovery document unexpected error: %v", err)
+	}
+
+	if document.Name != "test-agent" {
+		t.Errorf("Name = %q, want %q", document.Name, "test-agent")
+	}
+	if document.OpenAPI != DefaultOpenAPIPath {
+		t.Errorf("OpenAPI = %q, want %q", document.OpenAPI, DefaultOpenAPIPath)
+	}
+	if got := document.Endpoints[EndpointClearMessages].Path; got != "/custom/clear" {
+		t.Errorf("clear messages path = %q, want %q", got, "/custom/clear")
+	}
+	if got := document.Endpoints[EndpointTruncateMessages]; got.Method != "POST" || got.Path != DefaultTruncateMessagesPath {
+		t.Errorf("truncate messages endpoint = %+v, want POST %s", got, DefaultTruncateMessagesPath)
+	}
+	if _, ok := document.Endpoints[EndpointCompressContext]; ok {
+		t.Error("compress context endpoint should not be registered without a compressor")
+	}
+}
+
+func TestHandlerOpenAPIDocument(t *testing.T) {
+	server := httptest.NewServer(newTestServer(ConfigHTTP{}).Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + DefaultOpenAPIPath)
+	if err != nil {
+		t.Fatalf("GET openapi unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var document struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			RequestBody struct {
+				Content map[string]struct {
+					Schema map[string]any `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+		} `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		t.Fatalf("Decode OpenAPI document unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", document.OpenAPI)
+	}
+
+	operations, ok := document.Paths[DefaultSystemInstructionsPath]
+	if !ok {
+		t.Fatalf("paths should contain %s", DefaultSystemInstructionsPath)
+	}
+	if operations["get"].OperationID != EndpointGetSystemInstructions || operations["post"].OperationID != EndpointSetSystemInstructions {
+		t.Errorf("operations = %+v, want get and set system instructions", operations)
+	}
+
+	schema := operations["post"].RequestBody.Content["application/json"].Schema
+	properties, _ := schema["properties"].(map[string]any)
+	if _, ok := properties["system_instructions"]; !ok {
+		t.Errorf("request schema = %v, want a system_instructions property", schema)
+	}
+}
+
+// ============================================================================
+// Tests for ChatAgentServer.Serve and ChatAgentServer.Stop
+// ============================================================================
+
+func TestServeWithListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen unexpected error: %v", err)
+	}
+
+	cas := newTestServer(ConfigHTTP{},
+		WithListener(listener),
+		WithSignalHandling(false),
+		WithReadHeaderTimeout(time.Second),
+		WithShutdownTimeout(time.Second),
+	)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- cas.Serve()
+	}()
+
+	// Wait for the server to answer
+	url := "http://" + listener.Addr().String() + DefaultHealthcheckPath
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		resp, err = http.Get(url)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("GET healthcheck unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if cas.httpServer.ReadHeaderTimeout != time.Second {
+		t.Errorf("ReadHeaderTimeout = %v, want %v", cas.httpServer.ReadHeaderTimeout, time.Second)
+	}
+
+	if err := cas.Stop(); err != nil {
+		t.Errorf("Stop() unexpected error: %v", err)
+	}
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Errorf("Serve() unexpected error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Serve() did not return after Stop()")
+	}
+}
+
+func TestStopWithoutServe(t *testing.T) {
+	cas := newTestServer(ConfigHTTP{})
+	if err := cas.Stop(); err == nil {
+		t.Error("Stop() expected error when the server is not running, got nil")
+	}
+}
+
+func TestWithShutdownTimeoutDefault(t *testing.T) {
+	cas := newTestServer(ConfigHTTP{}, WithShutdownTimeout(0))
+	if cas.shutdownTimeout != DefaultShutdownTimeout {
+		t.Errorf("shutdownTimeout = %v, want %v", cas.shutdownTimeout, DefaultShutdownTimeout)
+	}
+}
@@ -0,0 +1,239 @@
+package chatserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip"
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+	"github.com/snipwise/snip-sdk/snip/tools"
+)
+
+// ConfigAgentHTTP configures the endpoints of the handlers of the tools, RAG and structured agents
+// (NewToolsAgentHandler, NewRagAgentHandler and NewStructuredAgentHandler)
+// Empty paths default to the Default*Path constants, the paths that do not apply to the agent are ignored
+type ConfigAgentHTTP struct {
+	HealthcheckPath        string
+	InformationPath        string
+	DiscoveryPath          string
+	ToolCallsPath          string
+	RagAddChunksPath       string
+	RagSearchPath          string
+	StructuredGeneratePath string
+
+	// Logger defaults to a no-op logger
+	Logger logger.Logger
+}
+
+// withDefaults returns the configuration with the default paths and logger
+func (config ConfigAgentHTTP) withDefaults() ConfigAgentHTTP {
+	config.HealthcheckPath = pathOrDefault(config.HealthcheckPath, DefaultHealthcheckPath)
+	config.InformationPath = pathOrDefault(config.InformationPath, DefaultInformationPath)
+	config.DiscoveryPath = pathOrDefault(config.DiscoveryPath, DefaultDiscoveryPath)
+	config.ToolCallsPath = pathOrDefault(config.ToolCallsPath, DefaultToolCallsPath)
+	config.RagAddChunksPath = pathOrDefault(config.RagAddChunksPath, DefaultRagAddChunksPath)
+	config.RagSearchPath = pathOrDefault(config.RagSearchPath, DefaultRagSearchPath)
+	config.StructuredGeneratePath = pathOrDefault(config.StructuredGeneratePath, DefaultStructuredGeneratePath)
+	if config.Logger == nil {
+		config.Logger = &logger.NoOpLogger{}
+	}
+	return config
+}
+
+// pathOrDefault returns path, or defaultPath if path is empty
+func pathOrDefault(path, defaultPath string) string {
+	if path == "" {
+		return defaultPath
+	}
+	return path
+}
+
+// agentHandler is the mux of the handler of a tools, RAG or structured agent
+type agentHandler struct {
+	mux       *http.ServeMux
+	config    ConfigAgentHTTP
+	endpoints []Endpoint
+}
+
+// newAgentHandler creates the mux and registers the healthcheck and information endpoints
+// information returns the body of the information endpoint, nil if the agent has none
+func newAgentHandler(config ConfigAgentHTTP, information func() (any, error)) *agentHandler {
+	h := &agentHandler{mux: http.NewServeMux(), config: config.withDefaults()}
+
+	h.handle(Endpoint{
+		Name: EndpointHealthcheck, Method: "GET", Path: h.config.HealthcheckPath,
+		Summary:  "Check that the server is up",
+		response: StatusResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, StatusResponse{Status: "healthy"})
+	})
+
+	if information != nil {
+		info, _ := information()
+		h.handle(Endpoint{
+			Name: EndpointInformation, Method: "GET", Path: h.config.InformationPath,
+			Summary:  "Get the agent information",
+			response: info,
+		}, func(w http.ResponseWriter, r *http.Request) {
+			info, err := information()
+			if err != nil {
+				h.config.Logger.Error("Error getting agent information: %v", err)
+				writeJSON(w, http.StatusInternalServerError, StatusResponse{Status: "error", Message: "failed to get agent information"})
+				return
+			}
+			writeJSON(w, http.StatusOK, info)
+		})
+	}
+	return h
+}
+
+// handle registers handler on the mux for the endpoint
+func (h *agentHandler) handle(endpoint Endpoint, handler http.HandlerFunc) {
+	registerEndpoint(h.mux, &h.endpoints, h.config.Logger, endpoint, handler)
+}
+
+// finish registers the discovery endpoint and returns the handler
+func (h *agentHandler) finish(name string, kind agents.AgentKind) http.Handler {
+	h.handle(Endpoint{
+		Name: EndpointDiscovery, Method: "GET", Path: h.config.DiscoveryPath,
+		Summary:  "Get the discovery document of the server (agent information and endpoint paths)",
+		response: DiscoveryDocument{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		document := DiscoveryDocument{
+			Name:      name,
+			Kind:      kind,
+			Endpoints: make(map[string]Endpoint, len(h.endpoints)),
+		}
+		for _, endpoint := range h.endpoints {
+			document.Endpoints[endpoint.Name] = endpoint
+		}
+		writeJSON(w, http.StatusOK, document)
+	})
+	return h.mux
+}
+
+// decodeBody decodes the JSON body of the request into req, answering 400 if it is invalid
+func (h *agentHandler) decodeBody(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.config.Logger.Error("Error decoding request body: %v", err)
+		writeJSON(w, http.StatusBadRequest, StatusResponse{Status: "error", Message: "invalid request body"})
+		return false
+	}
+	return true
+}
+
+// writeJSON writes value as the JSON body of the response
+func writeJSON(w http.ResponseWriter, statusCode int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(value)
+}
+
+// NewToolsAgentHandler returns an http.Handler exposing a tools agent
+// The tools are executed on the server side, the client gets the result of the tool calls
+func NewToolsAgentHandler(agent snip.AIToolsAgent, config ConfigAgentHTTP) http.Handler {
+	h := newAgentHandler(config, func() (any, error) { return agent.GetInfo() })
+
+	h.handle(Endpoint{
+		Name: EndpointToolCalls, Method: "POST", Path: h.config.ToolCallsPath,
+		Summary: "Detect and run the tool calls of a prompt",
+		request: tools.ToolCallsRequest{}, response: tools.ToolCallsResult{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		var req tools.ToolCallsRequest
+		if !h.decodeBody(w, r, &req) {
+			return
+		}
+
+		result, err := agent.RunToolCalls(strings.TrimSpace(req.Prompt))
+		if err != nil {
+			h.config.Logger.Error("Error running tool calls: %v", err)
+			writeJSON(w, http.StatusInternalServerError, StatusResponse{Status: "error", Message: "failed to run tool calls"})
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+
+	return h.finish(agent.GetName(), agent.Kind())
+}
+
+// NewRagAgentHandler returns an http.Handler exposing a RAG agent (its embeddings and its store)
+func NewRagAgentHandler(agent snip.AIRagAgent, config ConfigAgentHTTP) http.Handler {
+	h := newAgentHandler(config, func() (any, error) { return agent.GetInfo() })
+
+	h.handle(Endpoint{
+		Name: EndpointRagAddChunks, Method: "POST", Path: h.config.RagAddChunksPath,
+		Summary: "Add text chunks to the store",
+		request: RagAddChunksRequest{}, response: RagAddChunksResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		var req RagAddChunksRequest
+		if !h.decodeBody(w, r, &req) {
+			return
+		}
+
+		count, err := agent.AddTextChunksToStore(req.Chunks)
+		if err != nil {
+			h.config.Logger.Error("Error adding text chunks: %v", err)
+			writeJSON(w, http.StatusInternalServerError, StatusResponse{Status: "error", Message: "failed to add text chunks"})
+			return
+		}
+		writeJSON(w, http.StatusOK, RagAddChunksResponse{Count: count})
+	})
+
+	h.handle(Endpoint{
+		Name: EndpointRagSearch, Method: "POST", Path: h.config.RagSearchPath,
+		Summary: "Search the chunks similar to a query, with their score and metadata",
+		request: RagSearchRequest{}, response: RagSearchResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		var req RagSearchRequest
+		if !h.decodeBody(w, r, &req) {
+			return
+		}
+
+		matches, err := agent.Search(req.Query, rag.WithSearchOptions(req.Options))
+		if err != nil {
+			h.config.Logger.Error("Error searching similarities: %v", err)
+			writeJSON(w, http.StatusInternalServerError, StatusResponse{Status: "error", Message: "failed to search similarities"})
+			return
+		}
+		if matches == nil {
+			matches = []rag.SearchResult{}
+		}
+		results := make([]string, 0, len(matches))
+		for _, match := range matches {
+			results = append(results, match.Content)
+		}
+		writeJSON(w, http.StatusOK, RagSearchResponse{Results: results, Matches: matches})
+	})
+
+	return h.finish(agent.GetName(), agent.Kind())
+}
+
+// NewStructuredAgentHandler returns an http.Handler exposing a structured agent
+// name is the name of the agent in the discovery document
+func NewStructuredAgentHandler[O any](name string, agent snip.StructuredAgentInterface[O], config ConfigAgentHTTP) http.Handler {
+	h := newAgentHandler(config, nil)
+
+	h.handle(Endpoint{
+		Name: EndpointStructuredGenerate, Method: "POST", Path: h.config.StructuredGeneratePath,
+		Summary: "Generate structured data from a text",
+		request: StructuredGenerateRequest{}, response: StructuredGenerateResponse[O]{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		var req StructuredGenerateRequest
+		if !h.decodeBody(w, r, &req) {
+			return
+		}
+
+		result, err := agent.GenerateStructuredData(req.Text)
+		if err != nil {
+			h.config.Logger.Error("Error generating structured data: %v", err)
+			writeJSON(w, http.StatusInternalServerError, StatusResponse{Status: "error", Message: "failed to generate structured data"})
+			return
+		}
+		writeJSON(w, http.StatusOK, StructuredGenerateResponse[O]{Result: result})
+	})
+
+	return h.finish(name, agent.Kind())
+}
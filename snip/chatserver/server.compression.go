@@ -0,0 +1,118 @@
+package chatserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/agents"
+)
+
+// CompressContext compresses the conversation history using the configured compressor agent
+// Returns an error if no compressor agent is configured
+// After compression, the agent's messages are replaced with a single system message containing the compressed context
+func (cas *ChatAgentServer) CompressContext() (agents.ChatResponse, error) {
+	if cas.compressorAgent == nil {
+		return agents.ChatResponse{}, fmt.Errorf("no compressor agent configured, use WithCompressorAgent option")
+	}
+
+	response, err := cas.compressorAgent.CompressMessages(cas.agent.GetMessages())
+	if err != nil {
+		return agents.ChatResponse{}, err
+	}
+
+	// Replace the agent's messages with the compressed context
+	compressedMessages := []*ai.Message{
+		ai.NewSystemTextMessage(strings.TrimSpace(response.Text)),
+	}
+	if err := cas.agent.ReplaceMessagesWith(compressedMessages); err != nil {
+		return agents.ChatResponse{}, err
+	}
+
+	return response, nil
+}
+
+// CompressContextStream compresses the conversation history using streaming with the configured compressor agent
+// Returns an error if no compressor agent is configured
+// After compression, the agent's messages are replaced with a single system message containing the compressed context
+func (cas *ChatAgentServer) CompressContextStream(callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	if cas.compressorAgent == nil {
+		return agents.ChatResponse{}, fmt.Errorf("no compressor agent configured, use WithCompressorAgent option")
+	}
+
+	response, err := cas.compressorAgent.CompressMessagesStream(cas.agent.GetMessages(), callback)
+	if err != nil {
+		return agents.ChatResponse{}, err
+	}
+
+	// Replace the agent's messages with the compressed context
+	compressedMessages := []*ai.Message{
+		ai.NewSystemTextMessage(strings.TrimSpace(response.Text)),
+	}
+	if err := cas.agent.ReplaceMessagesWith(compressedMessages); err != nil {
+		return agents.ChatResponse{}, err
+	}
+
+	return response, nil
+}
+
+// registerCompressionEndpoints registers the context compression endpoints
+// The streaming endpoint uses the same server-sent events format as the Genkit flow handlers:
+// `data: {"message": <chunk>}` for each chunk and `data: {"result": <response>}` at the end
+func (cas *ChatAgentServer) registerCompressionEndpoints(mux *http.ServeMux) {
+
+	// Register compress context endpoint
+	compressContextPath := cas.serverConfig.CompressContextPath
+	cas.handle(mux, Endpoint{
+		Name: EndpointCompressContext, Method: "POST", Path: compressContextPath,
+		Summary:  "Compress the conversation history with the compressor agent",
+		response: agents.ChatResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		response, err := cas.CompressContext()
+		if err != nil {
+			cas.logger.Error("Error compressing context: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","message":"failed to compress context"}`))
+			return
+		}
+
+		cas.logger.Info("Context compressed via HTTP endpoint")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			cas.logger.Error("Error encoding compression response: %v", err)
+		}
+	})
+
+	// Register compress context stream endpoint
+	compressContextStreamPath := cas.serverConfig.CompressContextStreamPath
+	cas.handle(mux, Endpoint{
+		Name: EndpointCompressContextStream, Method: "POST", Path: compressContextStreamPath, Streaming: true,
+		Summary:  "Compress the conversation history with the compressor agent, with streaming",
+		response: StreamEvent{}, responseContentType: "text/event-stream",
+	}, func(w http.ResponseWriter, r *http.Request) {
+		setStreamHeaders(w)
+
+		response, err := cas.CompressContextStream(func(chunk agents.ChatResponse) error {
+			// Stop the compression if the client is gone
+			if r.Context().Err() != nil {
+				return r.Context().Err()
+			}
+			return writeStreamEvent(w, StreamEvent{Message: &chunk})
+		})
+		if err != nil {
+			cas.logger.Error("Error compressing context with streaming: %v", err)
+			writeStreamEvent(w, StreamEvent{Error: &StreamError{
+				Status:  "INTERNAL",
+				Message: "failed to compress context",
+				Details: err.Error(),
+			}})
+			return
+		}
+
+		cas.logger.Info("Context compressed with streaming via HTTP endpoint")
+		writeStreamEvent(w, StreamEvent{Result: &response})
+	})
+}
@@ -9,6 +9,12 @@ const (
 	// DefaultChatStreamFlowPath is the default endpoint path for the streaming chat flow
 	DefaultChatStreamFlowPath = "/api/chat-stream"
 
+	// DefaultAskFlowPath is the default endpoint path for the stateless chat flow (without memory)
+	DefaultAskFlowPath = "/api/ask"
+
+	// DefaultAskStreamFlowPath is the default endpoint path for the stateless streaming chat flow (without memory)
+	DefaultAskStreamFlowPath = "/api/ask-stream"
+
 	// DefaultInformationPath is the default endpoint path for agent information
 	DefaultInformationPath = "/api/information"
 
@@ -27,6 +33,48 @@ const (
 
 	// DefaultGetMessagesPath is the default endpoint path for retrieving conversation messages
 	DefaultGetMessagesPath = "/api/messages"
+
+	// DefaultOpenAPIPath is the default endpoint path for the OpenAPI document of the server
+	DefaultOpenAPIPath = "/openapi.json"
+
+	// DefaultDiscoveryPath is the default endpoint path for the discovery document of the server
+	DefaultDiscoveryPath = "/.well-known/snip-agent"
+
+	// DefaultMetricsPath is the default endpoint path for the Prometheus metrics
+	DefaultMetricsPath = "/metrics"
+
+	// DefaultReplaceMessagesPath is the default endpoint path for replacing the conversation messages
+	DefaultReplaceMessagesPath = "/api/replace-messages"
+
+	// DefaultReplaceWithSystemMessagesPath is the default endpoint path for replacing the conversation messages with system messages
+	DefaultReplaceWithSystemMessagesPath = "/api/replace-messages-with-system-messages"
+
+	// DefaultClearMessagesPath is the default endpoint path for clearing the conversation messages
+	DefaultClearMessagesPath = "/api/clear-messages"
+
+	// DefaultTruncateMessagesPath is the default endpoint path for keeping only the last conversation messages
+	DefaultTruncateMessagesPath = "/api/truncate-messages"
+
+	// DefaultSystemInstructionsPath is the default endpoint path for reading and changing the system instructions
+	DefaultSystemInstructionsPath = "/api/system-instructions"
+
+	// DefaultCompressContextPath is the default endpoint path for compressing the conversation messages
+	DefaultCompressContextPath = "/api/compress-context"
+
+	// DefaultCompressContextStreamPath is the default endpoint path for compressing the conversation messages with streaming
+	DefaultCompressContextStreamPath = "/api/compress-context-stream"
+
+	// DefaultToolCallsPath is the default endpoint path for the tool calls of a tools agent
+	DefaultToolCallsPath = "/api/tool-calls"
+
+	// DefaultRagAddChunksPath is the default endpoint path to add text chunks to the store of a RAG agent
+	DefaultRagAddChunksPath = "/api/rag/chunks"
+
+	// DefaultRagSearchPath is the default endpoint path for the similarity search of a RAG agent
+	DefaultRagSearchPath = "/api/rag/search"
+
+	// DefaultStructuredGeneratePath is the default endpoint path for the generation of a structured agent
+	DefaultStructuredGeneratePath = "/api/structured/generate"
 )
 
 // ConfigHTTP holds the HTTP server configuration for exposing agent flows
@@ -42,6 +90,14 @@ type ConfigHTTP struct {
 	// If empty, defaults to DefaultChatStreamFlowPath ("/api/chat-stream")
 	ChatStreamFlowPath string
 
+	// AskFlowPath is the endpoint path for the stateless chat flow (without memory)
+	// If empty, defaults to DefaultAskFlowPath ("/api/ask")
+	AskFlowPath string
+
+	// AskStreamFlowPath is the endpoint path for the stateless streaming chat flow (without memory)
+	// If empty, defaults to DefaultAskStreamFlowPath ("/api/ask-stream")
+	AskStreamFlowPath string
+
 	// InformationPath is the endpoint path for agent information
 	// If empty, defaults to DefaultInformationPath ("/api/information")
 	InformationPath string
@@ -67,6 +123,49 @@ type ConfigHTTP struct {
 	// If empty, defaults to DefaultGetMessagesPath ("/api/messages")
 	GetMessagesPath string
 
+	// OpenAPIPath is the endpoint path for the OpenAPI 3 document describing the registered endpoints
+	// If empty, defaults to DefaultOpenAPIPath ("/openapi.json")
+	OpenAPIPath string
+
+	// DiscoveryPath is the endpoint path for the discovery document (agent name, kind and endpoint paths)
+	// If empty, defaults to DefaultDiscoveryPath ("/.well-known/snip-agent")
+	DiscoveryPath string
+
+	// MetricsPath is the endpoint path for the metrics in Prometheus text format
+	// If empty, defaults to DefaultMetricsPath ("/metrics")
+	// Set to "-" to disable the metrics endpoint
+	MetricsPath string
+
+	// ReplaceMessagesPath is the endpoint path for replacing the conversation messages
+	// If empty, defaults to DefaultReplaceMessagesPath ("/api/replace-messages")
+	ReplaceMessagesPath string
+
+	// ReplaceWithSystemMessagesPath is the endpoint path for replacing the conversation messages with system messages
+	// If empty, defaults to DefaultReplaceWithSystemMessagesPath ("/api/replace-messages-with-system-messages")
+	ReplaceWithSystemMessagesPath string
+
+	// ClearMessagesPath is the endpoint path for clearing the conversation messages
+	// If empty, defaults to DefaultClearMessagesPath ("/api/clear-messages")
+	ClearMessagesPath string
+
+	// TruncateMessagesPath is the endpoint path for keeping only the last conversation messages
+	// If empty, defaults to DefaultTruncateMessagesPath ("/api/truncate-messages")
+	TruncateMessagesPath string
+
+	// SystemInstructionsPath is the endpoint path for reading (GET) and changing (POST) the system instructions
+	// If empty, defaults to DefaultSystemInstructionsPath ("/api/system-instructions")
+	SystemInstructionsPath string
+
+	// CompressContextPath is the endpoint path for compressing the conversation messages
+	// Only registered when a compressor agent is configured with WithCompressorAgent
+	// If empty, defaults to DefaultCompressContextPath ("/api/compress-context")
+	CompressContextPath string
+
+	// CompressContextStreamPath is the endpoint path for compressing the conversation messages with streaming
+	// Only registered when a compressor agent is configured with WithCompressorAgent
+	// If empty, defaults to DefaultCompressContextStreamPath ("/api/compress-context-stream")
+	CompressContextStreamPath string
+
 	// ChatFlowHandler is the HTTP handler for the standard chat flow endpoint
 	// If nil, will be auto-configured from the agent's chatFlow
 	ChatFlowHandler http.HandlerFunc
@@ -74,4 +173,12 @@ type ConfigHTTP struct {
 	// ChatStreamFlowHandler is the HTTP handler for the streaming chat flow endpoint
 	// If nil, will be auto-configured from the agent's chatStreamFlow
 	ChatStreamFlowHandler http.HandlerFunc
+
+	// AskFlowHandler is the HTTP handler for the stateless chat flow endpoint
+	// If nil, will be auto-configured from the agent's chat flow without memory
+	AskFlowHandler http.HandlerFunc
+
+	// AskStreamFlowHandler is the HTTP handler for the stateless streaming chat flow endpoint
+	// If nil, will be auto-configured from the agent's chat stream flow without memory
+	AskStreamFlowHandler http.HandlerFunc
 }
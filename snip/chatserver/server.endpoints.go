@@ -0,0 +1,69 @@
+package chatserver
+
+import (
+	"net/http"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// Names of the endpoints, used as keys of the discovery document
+const (
+	EndpointHealthcheck               = "healthcheck"
+	EndpointInformation               = "information"
+	EndpointChat                      = "chat"
+	EndpointChatStream                = "chat_stream"
+	EndpointAsk                       = "ask"
+	EndpointAskStream                 = "ask_stream"
+	EndpointShutdown                  = "shutdown"
+	EndpointCancelStream              = "cancel_stream"
+	EndpointAddSystemMessage          = "add_system_message"
+	EndpointGetMessages               = "get_messages"
+	EndpointMetrics                   = "metrics"
+	EndpointReplaceMessages           = "replace_messages"
+	EndpointReplaceWithSystemMessages = "replace_with_system_messages"
+	EndpointClearMessages             = "clear_messages"
+	EndpointTruncateMessages          = "truncate_messages"
+	EndpointGetSystemInstructions     = "get_system_instructions"
+	EndpointSetSystemInstructions     = "set_system_instructions"
+	EndpointCompressContext           = "compress_context"
+	EndpointCompressContextStream     = "compress_context_stream"
+	EndpointOpenAPI                   = "openapi"
+	EndpointDiscovery                 = "discovery"
+	EndpointToolCalls                 = "tool_calls"
+	EndpointRagAddChunks              = "rag_add_chunks"
+	EndpointRagSearch                 = "rag_search"
+	EndpointStructuredGenerate        = "structured_generate"
+)
+
+// Endpoint describes an endpoint registered by the server
+type Endpoint struct {
+	Name      string `json:"name"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Summary   string `json:"summary,omitempty"`
+	Streaming bool   `json:"streaming,omitempty"`
+
+	// request and response are zero values of the body types, used to infer the JSON schemas
+	// request is nil when the endpoint expects no body
+	request  any
+	response any
+	// responseContentType defaults to "application/json"
+	responseContentType string
+}
+
+// GetEndpoints returns the endpoints registered by the last call to Handler (or Serve)
+func (cas *ChatAgentServer) GetEndpoints() []Endpoint {
+	return cas.endpoints
+}
+
+// handle registers handler on mux for the endpoint and records its description
+func (cas *ChatAgentServer) handle(mux *http.ServeMux, endpoint Endpoint, handler http.HandlerFunc) {
+	registerEndpoint(mux, &cas.endpoints, cas.logger, endpoint, handler)
+}
+
+// registerEndpoint registers handler on mux for the endpoint and appends its description to endpoints
+func registerEndpoint(mux *http.ServeMux, endpoints *[]Endpoint, log logger.Logger, endpoint Endpoint, handler http.HandlerFunc) {
+	mux.HandleFunc(endpoint.Method+" "+endpoint.Path, handler)
+	*endpoints = append(*endpoints, endpoint)
+	log.Info("Registered endpoint: %s %s", endpoint.Method, endpoint.Path)
+}
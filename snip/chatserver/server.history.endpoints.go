@@ -0,0 +1,176 @@
+package chatserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// registerHistoryEndpoints registers the endpoints used to manage the conversation history
+// and the system instructions of the agent
+func (cas *ChatAgentServer) registerHistoryEndpoints(mux *http.ServeMux) {
+
+	// Register replace messages endpoint
+	replaceMessagesPath := cas.serverConfig.ReplaceMessagesPath
+	cas.handle(mux, Endpoint{
+		Name: EndpointReplaceMessages, Method: "POST", Path: replaceMessagesPath,
+		Summary: "Replace the conversation history",
+		request: ReplaceMessagesRequest{}, response: StatusResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// Parse request body
+		var req ReplaceMessagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			cas.logger.Error("Error decoding replace messages request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"invalid request body"}`))
+			return
+		}
+		if req.Messages == nil {
+			req.Messages = []*ai.Message{}
+		}
+
+		if err := cas.ReplaceMessagesWith(req.Messages); err != nil {
+			cas.logger.Error("Error replacing messages: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","message":"failed to replace messages"}`))
+			return
+		}
+
+		cas.logger.Info("Messages replaced via HTTP endpoint")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	})
+
+	// Register replace messages with system messages endpoint
+	replaceWithSystemMessagesPath := cas.serverConfig.ReplaceWithSystemMessagesPath
+	cas.handle(mux, Endpoint{
+		Name: EndpointReplaceWithSystemMessages, Method: "POST", Path: replaceWithSystemMessagesPath,
+		Summary: "Replace the conversation history with system messages",
+		request: ReplaceWithSystemMessagesRequest{}, response: StatusResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// Parse request body
+		var req ReplaceWithSystemMessagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			cas.logger.Error("Error decoding replace with system messages request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"invalid request body"}`))
+			return
+		}
+		if req.SystemMessages == nil {
+			req.SystemMessages = []string{}
+		}
+
+		if err := cas.ReplaceMessagesWithSystemMessages(req.SystemMessages); err != nil {
+			cas.logger.Error("Error replacing messages with system messages: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","message":"failed to replace messages"}`))
+			return
+		}
+
+		cas.logger.Info("Messages replaced with system messages via HTTP endpoint")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	})
+
+	// Register clear messages endpoint
+	clearMessagesPath := cas.serverConfig.ClearMessagesPath
+	cas.handle(mux, Endpoint{
+		Name: EndpointClearMessages, Method: "POST", Path: clearMessagesPath,
+		Summary:  "Clear the conversation history",
+		response: StatusResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := cas.ClearMessages(); err != nil {
+			cas.logger.Error("Error clearing messages: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","message":"failed to clear messages"}`))
+			return
+		}
+
+		cas.logger.Info("Messages cleared via HTTP endpoint")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	})
+
+	// Register truncate messages endpoint
+	truncateMessagesPath := cas.serverConfig.TruncateMessagesPath
+	cas.handle(mux, Endpoint{
+		Name: EndpointTruncateMessages, Method: "POST", Path: truncateMessagesPath,
+		Summary: "Keep only the last messages of the conversation history",
+		request: TruncateMessagesRequest{}, response: StatusResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// Parse request body
+		var req TruncateMessagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			cas.logger.Error("Error decoding truncate messages request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"invalid request body"}`))
+			return
+		}
+
+		if err := cas.TruncateMessages(req.KeepLast); err != nil {
+			cas.logger.Error("Error truncating messages: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"failed to truncate messages"}`))
+			return
+		}
+
+		cas.logger.Info("Messages truncated to the last %d via HTTP endpoint", req.KeepLast)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	})
+
+	// Register system instructions endpoints
+	systemInstructionsPath := cas.serverConfig.SystemInstructionsPath
+	cas.handle(mux, Endpoint{
+		Name: EndpointGetSystemInstructions, Method: "GET", Path: systemInstructionsPath,
+		Summary:  "Get the system instructions",
+		response: SystemInstructions{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		resp := SystemInstructions{
+			SystemInstructions: cas.GetSystemInstructions(),
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	cas.handle(mux, Endpoint{
+		Name: EndpointSetSystemInstructions, Method: "POST", Path: systemInstructionsPath,
+		Summary: "Change the system instructions",
+		request: SystemInstructions{}, response: StatusResponse{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// Parse request body
+		var req SystemInstructions
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			cas.logger.Error("Error decoding system instructions request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"invalid request body"}`))
+			return
+		}
+
+		if err := cas.SetSystemInstructions(req.SystemInstructions); err != nil {
+			cas.logger.Error("Error setting system instructions: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status":"error","message":"failed to set system instructions"}`))
+			return
+		}
+
+		cas.logger.Info("System instructions changed via HTTP endpoint")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	})
+}
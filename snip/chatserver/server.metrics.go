@@ -0,0 +1,62 @@
+package chatserver
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/metrics"
+)
+
+// GetMetrics returns the metrics recorded by the server and its agent
+func (cas *ChatAgentServer) GetMetrics() *metrics.AgentMetrics {
+	return cas.agent.GetMetrics()
+}
+
+// instrumentHandler wraps the server mux to record the number and the latency of the requests
+// The "endpoint" label is the route pattern matched by the mux (e.g. "POST /api/chat")
+func (cas *ChatAgentServer) instrumentHandler(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			pattern = "unmatched"
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		mux.ServeHTTP(recorder, r)
+
+		cas.agent.GetMetrics().ObserveRequest(cas.agent.Name, pattern, recorder.status, time.Since(start))
+	})
+}
+
+// statusRecorder captures the status code written by a handler
+// It implements http.Flusher so that streaming endpoints keep working
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	recorder.wroteHeader = true
+	return recorder.ResponseWriter.Write(data)
+}
+
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying ResponseWriter
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -0,0 +1,145 @@
+package chatserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/firebase/genkit/go/core"
+	"github.com/snipwise/snip-sdk/snip/agents"
+)
+
+// DiscoveryDocument is served on the discovery path (by default "/.well-known/snip-agent")
+// It gives the clients the paths of the endpoints, keyed by endpoint name (see the Endpoint* constants)
+type DiscoveryDocument struct {
+	Name      string              `json:"name"`
+	Kind      agents.AgentKind    `json:"kind"`
+	ModelID   string              `json:"model_id,omitempty"`
+	OpenAPI   string              `json:"openapi"`
+	Endpoints map[string]Endpoint `json:"endpoints"`
+}
+
+// GetDiscoveryDocument returns the discovery document of the endpoints registered by Handler
+func (cas *ChatAgentServer) GetDiscoveryDocument() DiscoveryDocument {
+	document := DiscoveryDocument{
+		Name:      cas.GetName(),
+		Kind:      cas.Kind(),
+		OpenAPI:   cas.serverConfig.OpenAPIPath,
+		Endpoints: make(map[string]Endpoint, len(cas.endpoints)),
+	}
+	if info, err := cas.GetInfo(); err == nil {
+		document.ModelID = info.ModelID
+	}
+	for _, endpoint := range cas.endpoints {
+		document.Endpoints[endpoint.Name] = endpoint
+	}
+	return document
+}
+
+// GetOpenAPIDocument returns an OpenAPI 3 document describing the endpoints registered by Handler
+// The JSON schemas of the request and response bodies are inferred from the Go types
+func (cas *ChatAgentServer) GetOpenAPIDocument() map[string]any {
+	paths := map[string]any{}
+	for _, endpoint := range cas.endpoints {
+		operations, ok := paths[endpoint.Path].(map[string]any)
+		if !ok {
+			operations = map[string]any{}
+			paths[endpoint.Path] = operations
+		}
+		operations[httpMethodToOpenAPI(endpoint.Method)] = endpointOperation(endpoint)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   cas.GetName(),
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	}
+}
+
+// endpointOperation builds the OpenAPI operation object of an endpoint
+func endpointOperation(endpoint Endpoint) map[string]any {
+	contentType := endpoint.responseContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	response := map[string]any{
+		"description": "Successful response",
+	}
+	if endpoint.response != nil {
+		response["content"] = map[string]any{
+			contentType: map[string]any{
+				"schema": core.InferSchemaMap(endpoint.response),
+			},
+		}
+	}
+
+	operation := map[string]any{
+		"operationId": endpoint.Name,
+		"summary":     endpoint.Summary,
+		"responses": map[string]any{
+			"200": response,
+		},
+	}
+	if endpoint.request != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": core.InferSchemaMap(endpoint.request),
+				},
+			},
+		}
+	}
+	return operation
+}
+
+// httpMethodToOpenAPI converts an HTTP method to the key of an OpenAPI path item ("GET" -> "get")
+func httpMethodToOpenAPI(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "get"
+	case http.MethodPost:
+		return "post"
+	case http.MethodPut:
+		return "put"
+	case http.MethodDelete:
+		return "delete"
+	case http.MethodPatch:
+		return "patch"
+	}
+	return method
+}
+
+// registerDiscoveryEndpoints registers the OpenAPI and discovery endpoints
+// They must be registered last, so that the documents describe every other endpoint
+func (cas *ChatAgentServer) registerDiscoveryEndpoints(mux *http.ServeMux) {
+
+	// Register OpenAPI endpoint
+	cas.handle(mux, Endpoint{
+		Name: EndpointOpenAPI, Method: "GET", Path: cas.serverConfig.OpenAPIPath,
+		Summary:  "Get the OpenAPI document of the server",
+		response: map[string]any{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(cas.GetOpenAPIDocument()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	// Register discovery endpoint
+	cas.handle(mux, Endpoint{
+		Name: EndpointDiscovery, Method: "GET", Path: cas.serverConfig.DiscoveryPath,
+		Summary:  "Get the discovery document of the server (agent information and endpoint paths)",
+		response: DiscoveryDocument{},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(cas.GetDiscoveryDocument()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+}
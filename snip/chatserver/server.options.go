@@ -1,9 +1,16 @@
 package chatserver
 
 import (
+	"net"
+	"time"
+
 	"github.com/firebase/genkit/go/genkit"
+	"github.com/snipwise/snip-sdk/snip/registry"
 )
 
+// DefaultShutdownTimeout is the default deadline to drain the active requests when the server stops
+const DefaultShutdownTimeout = 5 * time.Second
+
 // EnableServer configures the agent to expose its flows via HTTP endpoints
 func EnableServer(config ConfigHTTP) ChatAgentServerOption {
 	return func(cas *ChatAgentServer) {
@@ -12,10 +19,105 @@ func EnableServer(config ConfigHTTP) ChatAgentServerOption {
 			config.ChatFlowHandler = genkit.Handler(cas.agent.GetChatFlowWithMemory())
 		}
 
+		// The streaming endpoints buffer their events, so that a client can resume a stream with Last-Event-ID
 		if cas.agent.GetChatStreamFlowWithMemory() != nil && config.ChatStreamFlowHandler == nil {
-			config.ChatStreamFlowHandler = genkit.Handler(cas.agent.GetChatStreamFlowWithMemory())
+			config.ChatStreamFlowHandler = cas.streamFlowHandler(cas.agent.AskStreamWithMemory)
+		}
+
+		// Set up HTTP handlers for the stateless flows (without memory)
+		if cas.agent.GetChatFlow() != nil && config.AskFlowHandler == nil {
+			config.AskFlowHandler = genkit.Handler(cas.agent.GetChatFlow())
+		}
+
+		if cas.agent.GetChatStreamFlow() != nil && config.AskStreamFlowHandler == nil {
+			config.AskStreamFlowHandler = cas.streamFlowHandler(cas.agent.AskStream)
 		}
 
 		cas.serverConfig = &config
 	}
 }
+
+// WithReadTimeout sets the maximum duration for reading an entire request, including the body
+func WithReadTimeout(timeout time.Duration) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.readTimeout = timeout
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum duration for reading the request headers
+func WithReadHeaderTimeout(timeout time.Duration) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.readHeaderTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out the writes of a response
+// NOTE: it also limits the duration of the streaming completions
+func WithWriteTimeout(timeout time.Duration) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.writeTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets the maximum duration to wait for the next request when keep-alives are enabled
+func WithIdleTimeout(timeout time.Duration) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.idleTimeout = timeout
+	}
+}
+
+// WithShutdownTimeout sets the deadline to drain the active requests and streams when the server stops
+// If zero or negative, DefaultShutdownTimeout is used
+func WithShutdownTimeout(timeout time.Duration) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		if timeout <= 0 {
+			timeout = DefaultShutdownTimeout
+		}
+		cas.shutdownTimeout = timeout
+	}
+}
+
+// WithTLS serves HTTPS with the given certificate and key files
+func WithTLS(certFile, keyFile string) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.tlsCertFile = certFile
+		cas.tlsKeyFile = keyFile
+	}
+}
+
+// WithListener serves on the given listener instead of listening on ConfigHTTP.Address
+func WithListener(listener net.Listener) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.listener = listener
+	}
+}
+
+// WithSignalHandling enables or disables the handling of SIGINT and SIGTERM by Serve (enabled by default)
+// Disable it when the server is embedded in an application that manages its own signals
+func WithSignalHandling(enabled bool) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.handleSignals = enabled
+	}
+}
+
+// WithStreamBuffer sets the number of recent events kept per stream, and how long a finished stream is kept,
+// for the clients that resume a stream with Last-Event-ID
+// Zero or negative values fall back to DefaultStreamBufferSize and DefaultStreamRetention
+func WithStreamBuffer(size int, retention time.Duration) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.streamBufferSize = size
+		cas.streamRetention = retention
+	}
+}
+
+// WithRegistry announces the server in reg while it serves, under the agent name,
+// with a heartbeat every interval (registry.DefaultHeartbeatInterval if zero)
+// advertisedAddress is the base URL the clients use to reach the server (e.g. "http://10.0.0.12:8080");
+// if empty, it is built from the listener or ConfigHTTP.Address
+func WithRegistry(reg registry.Registry, advertisedAddress string, interval time.Duration) ChatAgentServerOption {
+	return func(cas *ChatAgentServer) {
+		cas.registry = reg
+		cas.advertisedAddress = advertisedAddress
+		cas.heartbeatInterval = interval
+	}
+}
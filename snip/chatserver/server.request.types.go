@@ -0,0 +1,116 @@
+package chatserver
+
+import (
+	"fmt"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+// Request and response bodies of the server endpoints
+// They are shared with the remote clients and used to generate the OpenAPI document
+
+// FlowRequest is the body expected by the flow endpoints (Genkit format)
+type FlowRequest struct {
+	Data agents.ChatRequest `json:"data"`
+}
+
+// FlowResponse is the body returned by the non-streaming flow endpoints (Genkit format)
+type FlowResponse struct {
+	Result agents.ChatResponse `json:"result"`
+}
+
+// StreamEvent is the JSON data of a server-sent event of the streaming endpoints (Genkit format)
+// Exactly one field is set: a chunk (message), the final response (result) or an error
+type StreamEvent struct {
+	Message *agents.ChatResponse `json:"message,omitempty"`
+	Result  *agents.ChatResponse `json:"result,omitempty"`
+	Error   *StreamError         `json:"error,omitempty"`
+}
+
+// StreamError is an error sent by the server in a stream
+type StreamError struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+func (e *StreamError) Error() string {
+	if e.Details == "" {
+		return fmt.Sprintf("%s: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Status, e.Message, e.Details)
+}
+
+// CancelStreamRequest is the body of the cancel stream endpoint
+// Without stream id, the current streaming completion of the agent is cancelled, whatever its client
+type CancelStreamRequest struct {
+	StreamID string `json:"stream_id,omitempty"`
+}
+
+// AddSystemMessageRequest is the body of the add system message endpoint
+type AddSystemMessageRequest struct {
+	Context string `json:"context"`
+}
+
+// ReplaceMessagesRequest is the body of the replace messages endpoint
+type ReplaceMessagesRequest struct {
+	Messages []*ai.Message `json:"messages"`
+}
+
+// ReplaceWithSystemMessagesRequest is the body of the replace messages with system messages endpoint
+type ReplaceWithSystemMessagesRequest struct {
+	SystemMessages []string `json:"system_messages"`
+}
+
+// TruncateMessagesRequest is the body of the truncate messages endpoint
+type TruncateMessagesRequest struct {
+	KeepLast int `json:"keep_last"`
+}
+
+// SystemInstructions is the body of the system instructions endpoints
+type SystemInstructions struct {
+	SystemInstructions string `json:"system_instructions"`
+}
+
+// StatusResponse is the body returned by the endpoints that do not return data
+type StatusResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// RagAddChunksRequest is the body of the RAG add chunks endpoint
+type RagAddChunksRequest struct {
+	Chunks []text.TextChunk `json:"chunks"`
+}
+
+// RagAddChunksResponse is the body returned by the RAG add chunks endpoint
+type RagAddChunksResponse struct {
+	Count int `json:"count"`
+}
+
+// RagSearchRequest is the body of the RAG search endpoint
+// Options are the settings of the search (the defaults of rag.NewSearchOptions if empty)
+type RagSearchRequest struct {
+	Query   string            `json:"query"`
+	Options rag.SearchOptions `json:"options"`
+}
+
+// RagSearchResponse is the body returned by the RAG search endpoint
+// Results are the contents of Matches, for the clients that only need the text
+type RagSearchResponse struct {
+	Results []string           `json:"results"`
+	Matches []rag.SearchResult `json:"matches"`
+}
+
+// StructuredGenerateRequest is the body of the structured generation endpoint
+type StructuredGenerateRequest struct {
+	Text string `json:"text"`
+}
+
+// StructuredGenerateResponse is the body returned by the structured generation endpoint
+type StructuredGenerateResponse[O any] struct {
+	Result *O `json:"result"`
+}
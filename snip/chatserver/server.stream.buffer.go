@@ -0,0 +1,193 @@
+package chatserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStreamBufferSize is the default number of recent events kept per stream for the clients that resume it
+	DefaultStreamBufferSize = 1024
+
+	// DefaultStreamRetention is the default duration a finished stream is kept for the clients that resume it
+	DefaultStreamRetention = time.Minute
+)
+
+// bufferedEvent is an event of a stream, with its sequence number
+type bufferedEvent struct {
+	seq  int
+	data []byte
+}
+
+// bufferedStream keeps the recent events of a streaming completion
+// The generation writes to the buffer, and the HTTP handlers (the first request and the resumed ones) read from it
+type bufferedStream struct {
+	id string
+
+	// ctx is cancelled when the stream is cancelled (see streamRegistry.cancel) or finished
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	events  []bufferedEvent
+	size    int
+	nextSeq int
+	done    bool
+	endedAt time.Time
+	// changed is closed (and replaced) each time an event is added, to wake up the readers
+	changed chan struct{}
+}
+
+// eventID returns the SSE id of the event seq of the stream
+func (s *bufferedStream) eventID(seq int) string {
+	return s.id + ":" + strconv.Itoa(seq)
+}
+
+// append adds an event to the stream, dropping the oldest one if the buffer is full
+func (s *bufferedStream) append(data []byte, last bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return
+	}
+	s.events = append(s.events, bufferedEvent{seq: s.nextSeq, data: data})
+	if len(s.events) > s.size {
+		s.events = s.events[len(s.events)-s.size:]
+	}
+	s.nextSeq++
+	if last {
+		s.done = true
+		s.endedAt = time.Now()
+		s.cancel()
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// since returns the events after the sequence number afterSeq (-1 for all),
+// whether the stream is finished, and a channel closed when new events are added
+// ok is false if events after afterSeq were already dropped from the buffer
+func (s *bufferedStream) since(afterSeq int) (events []bufferedEvent, done bool, changed <-chan struct{}, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.events) > 0 && s.events[0].seq > afterSeq+1 {
+		return nil, s.done, s.changed, false
+	}
+	for _, event := range s.events {
+		if event.seq > afterSeq {
+			events = append(events, event)
+		}
+	}
+	return events, s.done, s.changed, true
+}
+
+// streamRegistry keeps the streams of the server, by id
+type streamRegistry struct {
+	mu         sync.Mutex
+	streams    map[string]*bufferedStream
+	bufferSize int
+	retention  time.Duration
+}
+
+func newStreamRegistry(bufferSize int, retention time.Duration) *streamRegistry {
+	if bufferSize <= 0 {
+		bufferSize = DefaultStreamBufferSize
+	}
+	if retention <= 0 {
+		retention = DefaultStreamRetention
+	}
+	return &streamRegistry{
+		streams:    map[string]*bufferedStream{},
+		bufferSize: bufferSize,
+		retention:  retention,
+	}
+}
+
+// start creates a new stream, and removes the streams finished for longer than the retention
+func (r *streamRegistry) start() *bufferedStream {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &bufferedStream{
+		id:      hex.EncodeToString(idBytes),
+		ctx:     ctx,
+		cancel:  cancel,
+		size:    r.bufferSize,
+		changed: make(chan struct{}),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, s := range r.streams {
+		s.mu.Lock()
+		expired := s.done && time.Since(s.endedAt) > r.retention
+		s.mu.Unlock()
+		if expired {
+			delete(r.streams, id)
+		}
+	}
+	r.streams[stream.id] = stream
+	return stream
+}
+
+// lookup returns the stream and the sequence number of a Last-Event-ID
+func (r *streamRegistry) lookup(lastEventID string) (*bufferedStream, int, error) {
+	streamID, seqText, found := strings.Cut(lastEventID, ":")
+	seq, err := strconv.Atoi(seqText)
+	if !found || err != nil {
+		return nil, 0, fmt.Errorf("invalid event id %q", lastEventID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stream, ok := r.streams[streamID]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown or expired stream %q", streamID)
+	}
+	return stream, seq, nil
+}
+
+// cancel cancels the generation of a running stream
+// alone is true if no other stream of the registry is running
+func (r *streamRegistry) cancel(streamID string) (cancelled, alone bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	running := 0
+	for _, s := range r.streams {
+		s.mu.Lock()
+		if !s.done {
+			running++
+		}
+		s.mu.Unlock()
+	}
+	stream, ok := r.streams[streamID]
+	if !ok {
+		return false, false
+	}
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.done {
+		return false, false
+	}
+	stream.cancel()
+	return true, running == 1
+}
+
+// cancelAll cancels the generations of all the streams
+func (r *streamRegistry) cancelAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.streams {
+		s.cancel()
+	}
+}
@@ -0,0 +1,191 @@
+package chatserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/toolbox/sse"
+)
+
+// StreamIDHeader is the response header of the streaming endpoints with the id of the stream,
+// used to cancel it with the cancel stream endpoint before its first event
+const StreamIDHeader = "X-Stream-ID"
+
+// setStreamHeaders sets the headers of a server-sent events response
+func setStreamHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
+
+// writeStreamEvent writes event as a server-sent event and flushes it to the client
+func writeStreamEvent(w http.ResponseWriter, event StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := sse.Write(w, sse.Event{Data: string(data)}); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// streamFlowHandler returns the handler of a streaming endpoint for ask (e.g. the agent's AskStreamWithMemory)
+// It uses the Genkit request and events format, and adds an id to each event:
+//   - the generation runs independently of the request and its events are buffered,
+//     so a client whose connection drops does not lose the answer
+//   - a client sending the header Last-Event-ID gets the events after this id, then the next ones, instead of starting a new completion
+//
+// Use the cancel stream endpoint with the stream id (StreamIDHeader, or the prefix of the event ids) to stop a generation
+func (cas *ChatAgentServer) streamFlowHandler(ask func(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Resume an existing stream
+		if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+			stream, seq, err := cas.getStreams().lookup(lastEventID)
+			if err != nil {
+				cas.logger.Warn("Cannot resume stream: %v", err)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusGone)
+				w.Write([]byte(`{"status":"error","message":"stream not available anymore"}`))
+				return
+			}
+			cas.logger.Info("Resuming stream %s after event %d", stream.id, seq)
+			cas.followStream(w, r, stream, seq)
+			return
+		}
+
+		// Parse request body
+		var req FlowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			cas.logger.Error("Error decoding stream request: %v", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"invalid request body"}`))
+			return
+		}
+
+		stream := cas.getStreams().start()
+		appendEvent := func(event StreamEvent, last bool) {
+			data, err := json.Marshal(event)
+			if err != nil {
+				cas.logger.Error("Error encoding stream event: %v", err)
+				return
+			}
+			stream.append(data, last)
+		}
+
+		// The generation goes on when the client leaves, until its end or the shutdown deadline (see Stop)
+		cas.generations.Go(func() {
+			defer func() {
+				// A panic of the agent or of a callback ends the stream, not the server
+				if recovered := recover(); recovered != nil {
+					cas.logger.Error("Stream %s: generation panic: %v", stream.id, recovered)
+					appendEvent(StreamEvent{Error: &StreamError{
+						Status:  "INTERNAL",
+						Message: "stream flow panic",
+						Details: fmt.Sprint(recovered),
+					}}, true)
+				}
+			}()
+
+			response, err := ask(req.Data.UserMessage, func(chunk agents.ChatResponse) error {
+				// A cancelled stream stops the generation at the next chunk
+				if err := stream.ctx.Err(); err != nil {
+					return err
+				}
+				appendEvent(StreamEvent{Message: &chunk}, false)
+				return nil
+			})
+			if err != nil && stream.ctx.Err() != nil {
+				appendEvent(StreamEvent{Error: &StreamError{
+					Status:  "CANCELLED",
+					Message: "stream cancelled",
+				}}, true)
+				return
+			}
+			if err != nil {
+				appendEvent(StreamEvent{Error: &StreamError{
+					Status:  "INTERNAL",
+					Message: "stream flow error",
+					Details: err.Error(),
+				}}, true)
+				return
+			}
+			appendEvent(StreamEvent{Result: &response}, true)
+		})
+
+		w.Header().Set(StreamIDHeader, stream.id)
+		cas.followStream(w, r, stream, -1)
+	}
+}
+
+// followStream writes the events of the stream after the sequence number afterSeq, until the stream ends or the client leaves
+func (cas *ChatAgentServer) followStream(w http.ResponseWriter, r *http.Request, stream *bufferedStream, afterSeq int) {
+	events, done, changed, ok := stream.since(afterSeq)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(`{"status":"error","message":"stream events not buffered anymore"}`))
+		return
+	}
+
+	setStreamHeaders(w)
+	flusher, _ := w.(http.Flusher)
+	for {
+		for _, event := range events {
+			if err := sse.Write(w, sse.Event{ID: stream.eventID(event.seq), Data: string(event.data)}); err != nil {
+				return
+			}
+			afterSeq = event.seq
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			cas.logger.Info("Client left stream %s, the generation goes on", stream.id)
+			return
+		}
+
+		events, done, changed, ok = stream.since(afterSeq)
+		if !ok {
+			// The client is too slow: the buffer dropped events it did not read
+			cas.logger.Error("Stream %s: events dropped before being sent", stream.id)
+			return
+		}
+	}
+}
+
+// waitGenerations waits for the end of the streaming generations, until ctx is done
+func (cas *ChatAgentServer) waitGenerations(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		cas.generations.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getStreams returns the registry of the streams of the server
+func (cas *ChatAgentServer) getStreams() *streamRegistry {
+	cas.streamsOnce.Do(func() {
+		cas.streams = newStreamRegistry(cas.streamBufferSize, cas.streamRetention)
+	})
+	return cas.streams
+}
@@ -0,0 +1,64 @@
+package chatserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+type stubRagAgent struct{}
+
+func (stubRagAgent) GetName() string { return "rag" }
+func (stubRagAgent) GetInfo() (agents.RagAgentInfo, error) {
+	return agents.RagAgentInfo{Name: "rag"}, nil
+}
+func (stubRagAgent) Kind() agents.AgentKind { return agents.Rag }
+func (stubRagAgent) AddTextChunksToStore(chunks []text.TextChunk) (int, error) {
+	return len(chunks), nil
+}
+func (stubRagAgent) SearchSimilarities(query string) ([]string, error) { return nil, nil }
+func (stubRagAgent) Search(query string, opts ...rag.SearchOption) ([]rag.SearchResult, error) {
+	return nil, nil
+}
+
+func TestRagAgentHandler(t *testing.T) {
+	handler := NewRagAgentHandler(stubRagAgent{}, ConfigAgentHTTP{RagSearchPath: "/search"})
+
+	t.Run("invalid body", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", DefaultRagAddChunksPath, strings.NewReader("{")))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("empty results", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/search", strings.NewReader(`{"query":"q"}`)))
+		if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"results":[],"matches":[]}` {
+			t.Errorf("response = %d %s, want 200 with an empty list", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("discovery", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", DefaultDiscoveryPath, nil))
+
+		var document DiscoveryDocument
+		if err := json.Unmarshal(rec.Body.Bytes(), &document); err != nil {
+			t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+		}
+		if document.Kind != agents.Rag || document.Endpoints[EndpointRagSearch].Path != "/search" {
+			t.Errorf("discovery document = %+v, want the RAG endpoints", document)
+		}
+		if _, ok := document.Endpoints[EndpointToolCalls]; ok {
+			t.Error("discovery document should not list the tools endpoints")
+		}
+	})
+}
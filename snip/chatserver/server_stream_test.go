@@ -0,0 +1,230 @@
+package chatserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/toolbox/sse"
+)
+
+// readEvents reads the server-sent events of a response
+func readEvents(t *testing.T, body io.Reader) []sse.Event {
+	t.Helper()
+	decoder := sse.NewDecoder(body)
+	var events []sse.Event
+	for {
+		event, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %v", err)
+		}
+		events = append(events, event)
+	}
+}
+
+// fakeAsk streams the words of the answer
+func fakeAsk(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	for _, word := range []string{"Hello ", "from ", question} {
+		if err := callback(agents.ChatResponse{Text: word}); err != nil {
+			return agents.ChatResponse{}, err
+		}
+	}
+	return agents.ChatResponse{Text: "Hello from " + question, FinishReason: "stop"}, nil
+}
+
+func TestStreamFlowHandler(t *testing.T) {
+	cas := newTestServer(ConfigHTTP{})
+	server := httptest.NewServer(cas.streamFlowHandler(fakeAsk))
+	defer server.Close()
+
+	post := func(lastEventID string) *http.Response {
+		req, _ := http.NewRequest("POST", server.URL, strings.NewReader(`{"data":{"message":"Bob"}}`))
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("")
+	events := readEvents(t, resp.Body)
+	resp.Body.Close()
+
+	if len(events) != 4 {
+		t.Fatalf("events = %d, want 4 (3 chunks and the result)", len(events))
+	}
+	if !strings.HasSuffix(events[0].ID, ":0") || !strings.HasSuffix(events[3].ID, ":3") {
+		t.Errorf("event ids = %q ... %q, want <stream>:0 ... <stream>:3", events[0].ID, events[3].ID)
+	}
+	if !strings.Contains(events[3].Data, `"result"`) {
+		t.Errorf("last event = %s, want the result", events[3].Data)
+	}
+
+	t.Run("resume with Last-Event-ID", func(t *testing.T) {
+		resp := post(events[1].ID)
+		defer resp.Body.Close()
+
+		resumed := readEvents(t, resp.Body)
+		if len(resumed) != 2 || resumed[0].ID != events[2].ID || resumed[1].Data != events[3].Data {
+			t.Errorf("resumed events = %+v, want the last 2 events", resumed)
+		}
+	})
+
+	t.Run("unknown stream", func(t *testing.T) {
+		resp := post("unknown:0")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusGone {
+			t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusGone)
+		}
+	})
+
+	t.Run("generation error", func(t *testing.T) {
+		failing := httptest.NewServer(cas.streamFlowHandler(func(string, func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+			return agents.ChatResponse{}, errors.New("model unavailable")
+		}))
+		defer failing.Close()
+
+		resp, err := http.Post(failing.URL, "application/json", strings.NewReader(`{"data":{"message":"Bob"}}`))
+		if err != nil {
+			t.Fatalf("POST unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		events := readEvents(t, resp.Body)
+		if len(events) != 1 || !strings.Contains(events[0].Data, "model unavailable") {
+			t.Errorf("events = %+v, want an error event", events)
+		}
+	})
+
+	t.Run("generation panic", func(t *testing.T) {
+		panicking := httptest.NewServer(cas.streamFlowHandler(func(string, func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+			panic("nil model")
+		}))
+		defer panicking.Close()
+
+		resp, err := http.Post(panicking.URL, "application/json", strings.NewReader(`{"data":{"message":"Bob"}}`))
+		if err != nil {
+			t.Fatalf("POST unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		events := readEvents(t, resp.Body)
+		if len(events) != 1 || !strings.Contains(events[0].Data, "stream flow panic") || !strings.Contains(events[0].Data, "nil model") {
+			t.Errorf("events = %+v, want an error event", events)
+		}
+	})
+}
+
+func TestWaitGenerations(t *testing.T) {
+	cas := newTestServer(ConfigHTTP{})
+	release := make(chan struct{})
+	server := httptest.NewServer(cas.streamFlowHandler(func(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+		callback(agents.ChatResponse{Text: "Hello"})
+		<-release
+		return agents.ChatResponse{Text: "Hello"}, nil
+	}))
+	defer server.Close()
+
+	// The client leaves after the first chunk, the generation goes on
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"data":{"message":"Bob"}}`))
+	if err != nil {
+		t.Fatalf("POST unexpected error: %v", err)
+	}
+	sse.NewDecoder(resp.Body).Next()
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := cas.waitGenerations(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waitGenerations() = %v, want the deadline exceeded while generating", err)
+	}
+
+	close(release)
+	if err := cas.waitGenerations(context.Background()); err != nil {
+		t.Errorf("waitGenerations() after the generation = %v, want nil", err)
+	}
+}
+
+func TestBufferedStreamDropsOldEvents(t *testing.T) {
+	stream := newStreamRegistry(2, 0).start()
+	for i := 0; i < 3; i++ {
+		stream.append([]byte("{}"), i == 2)
+	}
+
+	if _, _, _, ok := stream.since(-1); ok {
+		t.Error("since(-1) should fail when the first event was dropped")
+	}
+	events, done, _, ok := stream.since(0)
+	if !ok || !done || len(events) != 2 {
+		t.Errorf("since(0) = %d events, done %v, ok %v, want 2 events of a finished stream", len(events), done, ok)
+	}
+}
+
+func TestCancelStreamByID(t *testing.T) {
+	cas := newTestServer(ConfigHTTP{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.Handle("/", cas.Handler())
+	mux.Handle("POST /stream", cas.streamFlowHandler(func(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+		for {
+			if err := callback(agents.ChatResponse{Text: question}); err != nil {
+				return agents.ChatResponse{}, err
+			}
+			select {
+			case <-release:
+				return agents.ChatResponse{Text: question}, nil
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	start := func(question string) (*http.Response, string) {
+		resp, err := http.Post(server.URL+"/stream", "application/json", strings.NewReader(`{"data":{"message":"`+question+`"}}`))
+		if err != nil {
+			t.Fatalf("POST unexpected error: %v", err)
+		}
+		return resp, resp.Header.Get(StreamIDHeader)
+	}
+	alice, aliceID := start("Alice")
+	defer alice.Body.Close()
+	bob, bobID := start("Bob")
+	defer bob.Body.Close()
+	if aliceID == "" || aliceID == bobID {
+		t.Fatalf("stream ids = %q and %q, want 2 ids", aliceID, bobID)
+	}
+
+	resp, err := http.Post(server.URL+DefaultCancelStreamPath, "application/json", strings.NewReader(`{"stream_id":"`+aliceID+`"}`))
+	if err != nil {
+		t.Fatalf("POST cancel unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "stream cancelled") {
+		t.Errorf("cancel response = %s, want the stream cancelled", body)
+	}
+
+	events := readEvents(t, alice.Body)
+	if last := events[len(events)-1]; !strings.Contains(last.Data, "CANCELLED") {
+		t.Errorf("last event of the cancelled stream = %s, want a cancelled error", last.Data)
+	}
+
+	close(release)
+	events = readEvents(t, bob.Body)
+	if last := events[len(events)-1]; !strings.Contains(last.Data, `"result"`) {
+		t.Errorf("last event of the other stream = %s, want its result", last.Data)
+	}
+}
@@ -0,0 +1,144 @@
+// Package loaders reads files of common formats (Markdown, HTML, CSV, JSON, JSONL, Go, plain text)
+// into documents with metadata, ready to be split into RAG chunks
+package loaders
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/files"
+)
+
+// Metadata set by the loaders on each document
+const (
+	// MetadataSource is the path of the file
+	MetadataSource = "source"
+	// MetadataTitle is the title of the document (from its content, or the name of the file)
+	MetadataTitle = "title"
+	// MetadataMime is the MIME type of the file
+	MetadataMime = "mime"
+	// MetadataModified is the modification time of the file (RFC 3339)
+	MetadataModified = "modified"
+	// MetadataRecord is the index of the record in the file (from 1), for the CSV, JSON and JSONL files
+	MetadataRecord = "record"
+)
+
+// ErrUnsupported is returned for a file without loader for its extension
+var ErrUnsupported = errors.New("unsupported file type")
+
+// Document is the content of a file, or of a record of a file, with its metadata
+type Document struct {
+	Content  string
+	Metadata map[string]any
+}
+
+// Loader reads documents from their content
+type Loader interface {
+	// Parse returns the documents of the content of a file
+	// metadata holds the metadata of the file (source, title, mime, modified), to copy into each document
+	Parse(content []byte, metadata map[string]any) ([]Document, error)
+	// Mime returns the MIME type of the files of the loader
+	Mime() string
+}
+
+// Loaders are the loaders by extension (lowercase, with the dot), used by Load and LoadDirectory
+// Add or replace entries to support other extensions
+var Loaders = map[string]Loader{
+	".md":       MarkdownLoader{},
+	".markdown": MarkdownLoader{},
+	".html":     HTMLLoader{},
+	".htm":      HTMLLoader{},
+	".csv":      CSVLoader{},
+	".json":     JSONLoader{},
+	".jsonl":    JSONLinesLoader{},
+	".ndjson":   JSONLinesLoader{},
+	".go":       GoLoader{},
+	".txt":      TextLoader{},
+	".text":     TextLoader{},
+}
+
+// LoaderFor returns the loader of the extension of a file
+func LoaderFor(path string) (Loader, bool) {
+	loader, ok := Loaders[strings.ToLower(filepath.Ext(path))]
+	return loader, ok
+}
+
+// Load reads the documents of a file with the loader of its extension
+func Load(path string) ([]Document, error) {
+	loader, ok := LoaderFor(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, path)
+	}
+	return LoadWith(loader, path)
+}
+
+// LoadWith reads the documents of a file with a loader
+func LoadWith(loader Loader, path string) ([]Document, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]any{
+		MetadataSource:   path,
+		MetadataTitle:    strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		MetadataMime:     loader.Mime(),
+		MetadataModified: info.ModTime().UTC().Format(time.RFC3339),
+	}
+	docs, err := loader.Parse(content, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", path, err)
+	}
+	return docs, nil
+}
+
+// LoadDirectory reads the documents of the files of a directory and its subdirectories
+// with the extension ext (".*" for all the files), skipping the files without loader
+func LoadDirectory(dirPath string, ext string) ([]Document, error) {
+	docs := []Document{}
+	_, err := files.ForEachFile(dirPath, ext, func(path string) error {
+		fileDocs, err := Load(path)
+		if errors.Is(err, ErrUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		docs = append(docs, fileDocs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// Split returns the chunks of the documents, split with the splitter
+// A nil splitter keeps one chunk per document (e.g. for the records of the CSV and JSON files)
+func Split(docs []Document, splitter text.Splitter) []text.TextChunk {
+	chunks := []text.TextChunk{}
+	for _, doc := range docs {
+		if splitter == nil {
+			chunks = append(chunks, text.TextChunk{Content: doc.Content, Metadata: doc.Metadata})
+			continue
+		}
+		chunks = append(chunks, splitter.Split(doc.Content, doc.Metadata)...)
+	}
+	return chunks
+}
+
+// newDocument returns a document with a copy of metadata
+func newDocument(content string, metadata map[string]any) Document {
+	documentMetadata := make(map[string]any, len(metadata)+1)
+	maps.Copy(documentMetadata, metadata)
+	return Document{Content: content, Metadata: documentMetadata}
+}
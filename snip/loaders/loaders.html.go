@@ -0,0 +1,66 @@
+package loaders
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// htmlRemovedPattern matches the elements without text content, and the comments
+	htmlRemovedPattern = regexp.MustCompile(`(?is)<(script|style|noscript|template|svg|head)\b.*?</(?:script|style|noscript|template|svg|head)\s*>|<!--.*?-->`)
+	htmlTitlePattern   = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title\s*>`)
+	htmlHeadingPattern = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</h[1-6]\s*>`)
+	htmlListItemTag    = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlBlockTag       = regexp.MustCompile(`(?i)</?(?:p|div|section|article|header|footer|nav|aside|main|blockquote|pre|table|tr|ul|ol|dl|dt|dd|li|br|hr|figure|figcaption)\b[^>]*>`)
+	htmlCellTag        = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+	htmlTag            = regexp.MustCompile(`(?s)<[^>]*>`)
+	spacesPattern      = regexp.MustCompile(`[ \t\r\f\v\x{a0}]+`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLLoader extracts the text of an HTML file as a single document
+// The headings are kept as Markdown headings (so that text.MarkdownSplitter splits by sections),
+// the list items as Markdown list items; the title is the <title> of the page, or its first heading
+type HTMLLoader struct{}
+
+func (HTMLLoader) Mime() string { return "text/html" }
+
+func (HTMLLoader) Parse(content []byte, metadata map[string]any) ([]Document, error) {
+	page := string(content)
+	doc := newDocument("", metadata)
+
+	title := ""
+	if match := htmlTitlePattern.FindStringSubmatch(page); match != nil {
+		title = htmlText(match[1])
+	}
+	page = htmlRemovedPattern.ReplaceAllString(page, "")
+	page = htmlHeadingPattern.ReplaceAllStringFunc(page, func(heading string) string {
+		match := htmlHeadingPattern.FindStringSubmatch(heading)
+		text := htmlText(match[2])
+		if title == "" {
+			title = text
+		}
+		return "\n\n" + strings.Repeat("#", int(match[1][0]-'0')) + " " + text + "\n\n"
+	})
+	page = htmlListItemTag.ReplaceAllString(page, "\n- ")
+	page = htmlBlockTag.ReplaceAllString(page, "\n")
+	page = htmlCellTag.ReplaceAllString(page, " ")
+	page = html.UnescapeString(htmlTag.ReplaceAllString(page, ""))
+
+	lines := strings.Split(page, "\n")
+	for idx, line := range lines {
+		lines[idx] = strings.TrimSpace(spacesPattern.ReplaceAllString(line, " "))
+	}
+	doc.Content = strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+	if title != "" {
+		doc.Metadata[MetadataTitle] = title
+	}
+	return []Document{doc}, nil
+}
+
+// htmlText returns the text of an HTML fragment on a single line
+func htmlText(fragment string) string {
+	text := html.UnescapeString(htmlTag.ReplaceAllString(fragment, ""))
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,47 @@
+package loaders
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// frontMatterPattern matches a YAML front matter at the start of a Markdown file
+	frontMatterPattern = regexp.MustCompile(`(?s)\A---[ \t]*\r?\n(.*?)\r?\n---[ \t]*(?:\r?\n|\z)`)
+	// markdownTitlePattern matches the first level 1 heading of a Markdown document
+	markdownTitlePattern = regexp.MustCompile(`(?m)^#[ \t]+(.+?)[ \t#]*$`)
+)
+
+// MarkdownLoader reads a Markdown file as a single document, without its YAML front matter
+// The values of the front matter are added to the metadata (except source, mime and modified);
+// the title is the title of the front matter, or the first level 1 heading
+type MarkdownLoader struct{}
+
+func (MarkdownLoader) Mime() string { return "text/markdown" }
+
+func (MarkdownLoader) Parse(content []byte, metadata map[string]any) ([]Document, error) {
+	doc := newDocument(string(content), metadata)
+
+	if match := frontMatterPattern.FindSubmatchIndex(content); match != nil {
+		frontMatter := map[string]any{}
+		if err := yaml.Unmarshal(content[match[2]:match[3]], &frontMatter); err != nil {
+			return nil, fmt.Errorf("invalid front matter: %w", err)
+		}
+		for key, value := range frontMatter {
+			if key != MetadataSource && key != MetadataMime && key != MetadataModified {
+				doc.Metadata[key] = value
+			}
+		}
+		doc.Content = string(bytes.TrimLeft(content[match[1]:], "\r\n"))
+		if _, ok := frontMatter[MetadataTitle]; ok {
+			return []Document{doc}, nil
+		}
+	}
+	if match := markdownTitlePattern.FindStringSubmatch(doc.Content); match != nil {
+		doc.Metadata[MetadataTitle] = match[1]
+	}
+	return []Document{doc}, nil
+}
@@ -0,0 +1,134 @@
+package loaders
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVLoader reads a CSV file with a header row as one document per record
+// The content of a record is a "column: value" line per column
+type CSVLoader struct {
+	// Comma is the field delimiter (',' if zero)
+	Comma rune
+}
+
+func (CSVLoader) Mime() string { return "text/csv" }
+
+func (l CSVLoader) Parse(content []byte, metadata map[string]any) ([]Document, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	if l.Comma != 0 {
+		reader.Comma = l.Comma
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []Document{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	docs := []Document{}
+	for record := 1; ; record++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV record %d: %w", record, err)
+		}
+		var lines strings.Builder
+		for idx, value := range row {
+			column := fmt.Sprintf("column %d", idx+1)
+			if idx < len(header) {
+				column = header[idx]
+			}
+			fmt.Fprintf(&lines, "%s: %s\n", column, value)
+		}
+		doc := newDocument(strings.TrimSuffix(lines.String(), "\n"), metadata)
+		doc.Metadata[MetadataRecord] = record
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// JSONLoader reads a JSON file as one document per element if it is an array, or a single document
+// The content of a document is the JSON of the element (the text if it is a string)
+type JSONLoader struct{}
+
+func (JSONLoader) Mime() string { return "application/json" }
+
+func (JSONLoader) Parse(content []byte, metadata map[string]any) ([]Document, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(content, &elements); err != nil {
+		var element json.RawMessage
+		if err := json.Unmarshal(content, &element); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		doc, err := jsonDocument(element, metadata)
+		if err != nil {
+			return nil, err
+		}
+		return []Document{doc}, nil
+	}
+
+	docs := make([]Document, 0, len(elements))
+	for idx, element := range elements {
+		doc, err := jsonDocument(element, metadata)
+		if err != nil {
+			return nil, err
+		}
+		doc.Metadata[MetadataRecord] = idx + 1
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// JSONLinesLoader reads a JSON Lines file as one document per line, skipping the blank lines
+type JSONLinesLoader struct{}
+
+func (JSONLinesLoader) Mime() string { return "application/jsonl" }
+
+func (JSONLinesLoader) Parse(content []byte, metadata map[string]any) ([]Document, error) {
+	docs := []Document{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("invalid JSON on line %d", line)
+		}
+		doc, err := jsonDocument(data, metadata)
+		if err != nil {
+			return nil, err
+		}
+		doc.Metadata[MetadataRecord] = line
+		docs = append(docs, doc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// jsonDocument returns the document of a JSON element: its compact JSON, or its text if it is a string
+func jsonDocument(element json.RawMessage, metadata map[string]any) (Document, error) {
+	var text string
+	if err := json.Unmarshal(element, &text); err == nil {
+		return newDocument(text, metadata), nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, element); err != nil {
+		return Document{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return newDocument(compact.String(), metadata), nil
+}
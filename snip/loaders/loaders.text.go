@@ -0,0 +1,32 @@
+package loaders
+
+import (
+	"go/parser"
+	"go/token"
+)
+
+// MetadataPackage is the package of a Go file
+const MetadataPackage = "package"
+
+// TextLoader reads a plain text file as a single document
+type TextLoader struct{}
+
+func (TextLoader) Mime() string { return "text/plain" }
+
+func (TextLoader) Parse(content []byte, metadata map[string]any) ([]Document, error) {
+	return []Document{newDocument(string(content), metadata)}, nil
+}
+
+// GoLoader reads a Go source file as a single document, with its package (see text.CodeSplitter to split it)
+type GoLoader struct{}
+
+func (GoLoader) Mime() string { return "text/x-go" }
+
+func (GoLoader) Parse(content []byte, metadata map[string]any) ([]Document, error) {
+	doc := newDocument(string(content), metadata)
+	// A file that does not parse is still loaded, without its package
+	if file, err := parser.ParseFile(token.NewFileSet(), "", content, parser.PackageClauseOnly); err == nil {
+		doc.Metadata[MetadataPackage] = file.Name.Name
+	}
+	return []Document{doc}, nil
+}
@@ -0,0 +1,177 @@
+package loaders
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+// writeFiles writes the files (by name) in a temporary directory and returns its path
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMarkdown(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"guide.md":  "---\ntitle: User guide\ntags: [install, usage]\nsource: ignored\n---\n\n# Install\nDownload the binary.\n",
+		"notes.md":  "Some notes\n\n# Release notes #\n\nNothing yet.",
+		"plain.txt": "Just text",
+	})
+	path := filepath.Join(dir, "guide.md")
+
+	docs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Content != "# Install\nDownload the binary.\n" {
+		t.Fatalf("Load() = %q, want the content without the front matter", docs)
+	}
+	metadata := docs[0].Metadata
+	if metadata[MetadataTitle] != "User guide" || metadata[MetadataSource] != path || metadata[MetadataMime] != "text/markdown" {
+		t.Errorf("metadata = %v, want the title of the front matter and the file metadata", metadata)
+	}
+	if tags, ok := metadata["tags"].([]any); !ok || len(tags) != 2 || tags[0] != "install" {
+		t.Errorf("tags = %v, want the front matter tags", metadata["tags"])
+	}
+	if modified, ok := metadata[MetadataModified].(string); !ok || modified == "" {
+		t.Errorf("modified = %v, want the modification time", metadata[MetadataModified])
+	}
+
+	docs, _ = Load(filepath.Join(dir, "notes.md"))
+	if docs[0].Metadata[MetadataTitle] != "Release notes" {
+		t.Errorf("title = %v, want the first level 1 heading", docs[0].Metadata[MetadataTitle])
+	}
+	docs, _ = Load(filepath.Join(dir, "plain.txt"))
+	if docs[0].Content != "Just text" || docs[0].Metadata[MetadataTitle] != "plain" || docs[0].Metadata[MetadataMime] != "text/plain" {
+		t.Errorf("Load() = %v, want the text titled with the file name", docs[0])
+	}
+}
+
+func TestHTMLLoader(t *testing.T) {
+	page := `<html><head><title>Snip &amp; docs</title><style>body { color: red }</style></head>
+<body>
+<script>alert("hidden")</script>
+<h1>Getting <em>started</em></h1>
+<p>Install   the
+<b>SDK</b>.</p>
+<h2>Requirements</h2>
+<ul><li>Go</li><li>Docker</li></ul>
+</body></html>`
+	docs, err := HTMLLoader{}.Parse([]byte(page), map[string]any{MetadataTitle: "index"})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	want := "# Getting started\n\nInstall the\nSDK.\n\n## Requirements\n\n- Go\n\n- Docker"
+	if docs[0].Content != want {
+		t.Errorf("Parse() = %q, want %q", docs[0].Content, want)
+	}
+	if docs[0].Metadata[MetadataTitle] != "Snip & docs" {
+		t.Errorf("title = %v, want the title of the page", docs[0].Metadata[MetadataTitle])
+	}
+
+	docs, _ = HTMLLoader{}.Parse([]byte("<h2>Only a heading</h2>"), map[string]any{})
+	if docs[0].Metadata[MetadataTitle] != "Only a heading" {
+		t.Errorf("title = %v, want the first heading", docs[0].Metadata[MetadataTitle])
+	}
+}
+
+func TestRecordLoaders(t *testing.T) {
+	metadata := map[string]any{MetadataSource: "data"}
+	tests := []struct {
+		name    string
+		loader  Loader
+		content string
+		want    []string
+	}{
+		{"csv", CSVLoader{}, "name,kind\nDolphin,mammal\n\"Eagle, bald\",bird,extra\n", []string{"name: Dolphin\nkind: mammal", "name: Eagle, bald\nkind: bird\ncolumn 3: extra"}},
+		{"json array", JSONLoader{}, `[{"name": "Dolphin"}, "an eagle"]`, []string{`{"name":"Dolphin"}`, "an eagle"}},
+		{"json object", JSONLoader{}, `{"name": "Dolphin"}`, []string{`{"name":"Dolphin"}`}},
+		{"json lines", JSONLinesLoader{}, "{\"name\": \"Dolphin\"}\n\n{\"name\": \"Eagle\"}\n", []string{`{"name":"Dolphin"}`, `{"name":"Eagle"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := tt.loader.Parse([]byte(tt.content), metadata)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			got := []string{}
+			for _, doc := range docs {
+				got = append(got, doc.Content)
+				if doc.Metadata[MetadataSource] != "data" {
+					t.Errorf("metadata = %v, want the file metadata", doc.Metadata)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	docs, _ := JSONLinesLoader{}.Parse([]byte("\"a\"\n\n\"b\""), metadata)
+	if docs[1].Metadata[MetadataRecord] != 3 {
+		t.Errorf("record = %v, want the line of the record", docs[1].Metadata[MetadataRecord])
+	}
+	if _, ok := metadata[MetadataRecord]; ok {
+		t.Error("Parse() changed the file metadata")
+	}
+	if _, err := (JSONLinesLoader{}).Parse([]byte("{\"a\": 1}\n{broken"), metadata); err == nil {
+		t.Error("Parse() of invalid JSON Lines: expected an error")
+	}
+}
+
+func TestGoLoader(t *testing.T) {
+	docs, _ := GoLoader{}.Parse([]byte("// Package zoo\npackage zoo\n\nfunc New() {}\n"), map[string]any{})
+	if docs[0].Metadata[MetadataPackage] != "zoo" {
+		t.Errorf("package = %v, want zoo", docs[0].Metadata[MetadataPackage])
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"docs/readme.md":     "# Readme\nFirst paragraph.\n\nSecond paragraph.",
+		"data/animals.csv":   "name\nDolphin\nEagle\n",
+		"images/logo.png":    "not a document",
+		"docs/sub/notes.TXT": "Notes",
+	})
+
+	docs, err := LoadDirectory(dir, ".*")
+	if err != nil {
+		t.Fatalf("LoadDirectory() unexpected error: %v", err)
+	}
+	sources := []string{}
+	for _, doc := range docs {
+		rel, _ := filepath.Rel(dir, doc.Metadata[MetadataSource].(string))
+		sources = append(sources, filepath.ToSlash(rel))
+	}
+	want := []string{"data/animals.csv", "data/animals.csv", "docs/readme.md", "docs/sub/notes.TXT"}
+	if !slices.Equal(sources, want) {
+		t.Errorf("LoadDirectory() sources = %v, want %v", sources, want)
+	}
+
+	chunks := Split(docs, text.NewRecursiveSplitter(text.WithChunkSize(20)))
+	if len(chunks) != 6 || chunks[4].Content != "Second paragraph." || chunks[4].Metadata[MetadataTitle] != "Readme" {
+		t.Errorf("Split() = %v, want the readme split by paragraphs", chunks)
+	}
+	if chunks := Split(docs, nil); len(chunks) != len(docs) {
+		t.Errorf("Split() without splitter = %d chunks, want one per document", len(chunks))
+	}
+
+	if _, err := Load(filepath.Join(dir, "images/logo.png")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Load() error = %v, want ErrUnsupported", err)
+	}
+}
@@ -2,6 +2,7 @@ package snip
 
 import (
 	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/rag"
 	"github.com/snipwise/snip-sdk/snip/text"
 )
 
@@ -11,4 +12,6 @@ type AIRagAgent interface {
 	Kind() agents.AgentKind
 	AddTextChunksToStore(chunks []text.TextChunk) (int, error)
 	SearchSimilarities(query string) ([]string, error)
+	// Search returns the chunks most similar to the query, with their score and metadata
+	Search(query string, opts ...rag.SearchOption) ([]rag.SearchResult, error)
 }
@@ -0,0 +1,215 @@
+// Package bm25 is an in-memory inverted index ranking the chunks of a RAG agent with the Okapi BM25 function
+// It finds the exact identifiers, error codes and function names that an embedding search misses
+package bm25
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+)
+
+const (
+	// DefaultK1 is the default term frequency saturation
+	DefaultK1 = 1.2
+	// DefaultB is the default document length normalization
+	DefaultB = 0.75
+)
+
+// Document is a chunk indexed by its ID
+type Document struct {
+	ID       string
+	Content  string
+	Metadata map[string]any
+}
+
+// Hit is a document matching a query, with its BM25 score
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// document is the indexed form of a document
+type document struct {
+	length   int
+	terms    map[string]int
+	metadata map[string]any
+}
+
+// Index is a BM25 inverted index, safe for concurrent use
+type Index struct {
+	mu sync.RWMutex
+
+	k1 float64
+	b  float64
+
+	docs map[string]*document
+	// postings are the term frequencies of the documents, by term
+	postings    map[string]map[string]int
+	totalLength int
+}
+
+// Option configures an Index
+type Option func(*Index)
+
+// WithK1 sets the term frequency saturation (DefaultK1 by default)
+func WithK1(k1 float64) Option {
+	return func(idx *Index) {
+		idx.k1 = k1
+	}
+}
+
+// WithB sets the document length normalization, from 0 (none) to 1 (full) (DefaultB by default)
+func WithB(b float64) Option {
+	return func(idx *Index) {
+		idx.b = b
+	}
+}
+
+// NewIndex creates an empty index
+func NewIndex(opts ...Option) *Index {
+	idx := &Index{
+		k1:       DefaultK1,
+		b:        DefaultB,
+		docs:     map[string]*document{},
+		postings: map[string]map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(idx)
+	}
+	return idx
+}
+
+// Upsert indexes the documents, replacing the ones with the same ID
+func (idx *Index) Upsert(docs ...Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, doc := range docs {
+		idx.remove(doc.ID)
+
+		tokens := Tokenize(doc.Content)
+		indexed := &document{length: len(tokens), terms: map[string]int{}, metadata: doc.Metadata}
+		for _, token := range tokens {
+			indexed.terms[token]++
+		}
+		for term, frequency := range indexed.terms {
+			postings, ok := idx.postings[term]
+			if !ok {
+				postings = map[string]int{}
+				idx.postings[term] = postings
+			}
+			postings[doc.ID] = frequency
+		}
+		idx.docs[doc.ID] = indexed
+		idx.totalLength += indexed.length
+	}
+}
+
+// Delete removes the documents with these IDs, and returns the number of documents removed
+func (idx *Index) Delete(ids ...string) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		if idx.remove(id) {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// DeleteByMetadata removes the documents whose metadata have all the values of filter,
+// and returns the number of documents removed
+func (idx *Index) DeleteByMetadata(filter map[string]any) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	deleted := 0
+	for id, doc := range idx.docs {
+		if vectorstore.MatchMetadata(doc.metadata, filter) && idx.remove(id) {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// remove removes a document, the lock must be held
+func (idx *Index) remove(id string) bool {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return false
+	}
+	for term := range doc.terms {
+		postings := idx.postings[term]
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.docs, id)
+	return true
+}
+
+// Len returns the number of documents
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search returns the k documents with the best BM25 score for the query (all the matches if k is zero
+// or negative), sorted by descending score. filter keeps the documents whose metadata have all its values
+func (idx *Index) Search(query string, k int, filter map[string]any) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.docs) == 0 {
+		return []Hit{}
+	}
+	n := float64(len(idx.docs))
+	averageLength := float64(idx.totalLength) / n
+
+	scores := map[string]float64{}
+	// A term repeated in the query counts once
+	seen := map[string]bool{}
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, frequency := range postings {
+			tf := float64(frequency)
+			length := float64(idx.docs[id].length)
+			scores[id] += idf * tf * (idx.k1 + 1) / (tf + idx.k1*(1-idx.b+idx.b*length/averageLength))
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		if vectorstore.MatchMetadata(idx.docs[id].metadata, filter) {
+			hits = append(hits, Hit{ID: id, Score: score})
+		}
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
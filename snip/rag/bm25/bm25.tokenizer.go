@@ -0,0 +1,36 @@
+package bm25
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isSeparator reports whether r joins the parts of an identifier (snake_case, kebab-case, package.Name)
+func isSeparator(r rune) bool {
+	return r == '_' || r == '-' || r == '.'
+}
+
+// Tokenize splits a text into lowercase terms
+// An identifier such as ERR_CONN_RESET, user-039 or vectorstore.DocumentID is kept as a whole term,
+// followed by its parts, so that it matches exactly and by its parts
+func Tokenize(text string) []string {
+	terms := []string{}
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !isSeparator(r)
+	})
+	for _, word := range words {
+		word = strings.TrimFunc(word, isSeparator)
+		if word == "" {
+			continue
+		}
+		word = strings.ToLower(word)
+		terms = append(terms, word)
+
+		if strings.IndexFunc(word, isSeparator) >= 0 {
+			for _, part := range strings.FieldsFunc(word, isSeparator) {
+				terms = append(terms, part)
+			}
+		}
+	}
+	return terms
+}
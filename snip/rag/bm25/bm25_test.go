@@ -0,0 +1,63 @@
+package bm25
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Retry on ERR_CONN_RESET, see vectorstore.DocumentID (user-039).")
+	want := []string{"retry", "on", "err_conn_reset", "err", "conn", "reset", "see", "vectorstore.documentid", "vectorstore", "documentid", "user-039", "user", "039"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := NewIndex()
+	idx.Upsert(
+		Document{ID: "retry", Content: "The client retries the request on ERR_CONN_RESET", Metadata: map[string]any{"source": "client.md"}},
+		Document{ID: "timeout", Content: "The client gives up after a timeout", Metadata: map[string]any{"source": "client.md"}},
+		Document{ID: "server", Content: "The server closes idle connections", Metadata: map[string]any{"source": "server.md"}},
+	)
+
+	t.Run("exact identifier", func(t *testing.T) {
+		hits := idx.Search("ERR_CONN_RESET", 0, nil)
+		if len(hits) != 1 || hits[0].ID != "retry" || hits[0].Score <= 0 {
+			t.Errorf("Search() = %+v, want retry", hits)
+		}
+	})
+
+	t.Run("rare terms rank first", func(t *testing.T) {
+		hits := idx.Search("client timeout", 0, nil)
+		if len(hits) != 2 || hits[0].ID != "timeout" {
+			t.Errorf("Search() = %+v, want timeout then retry", hits)
+		}
+		if hits := idx.Search("client timeout", 1, nil); len(hits) != 1 {
+			t.Errorf("Search() with k = 1 returned %d hits", len(hits))
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		hits := idx.Search("the", 0, map[string]any{"source": "server.md"})
+		if len(hits) != 1 || hits[0].ID != "server" {
+			t.Errorf("Search() with filter = %+v, want server", hits)
+		}
+	})
+
+	t.Run("upsert and delete", func(t *testing.T) {
+		idx.Upsert(Document{ID: "timeout", Content: "The client waits forever", Metadata: map[string]any{"source": "client.md"}})
+		if hits := idx.Search("timeout", 0, nil); len(hits) != 0 {
+			t.Errorf("Search() after Upsert() = %+v, want no hit", hits)
+		}
+		if deleted := idx.DeleteByMetadata(map[string]any{"source": "client.md"}); deleted != 2 {
+			t.Errorf("DeleteByMetadata() = %d, want 2", deleted)
+		}
+		if deleted := idx.Delete("server", "unknown"); deleted != 1 {
+			t.Errorf("Delete() = %d, want 1", deleted)
+		}
+		if idx.Len() != 0 || len(idx.postings) != 0 || idx.totalLength != 0 {
+			t.Errorf("index not empty after deleting all the documents: %d documents, %d terms", idx.Len(), len(idx.postings))
+		}
+	})
+}
@@ -0,0 +1,173 @@
+// Package eval measures the retrieval of RAG agents on a labelled dataset of queries:
+// recall@k, precision@k, MRR and nDCG, to compare chunk sizes, embedding models or search options
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip/rag"
+)
+
+// DefaultCutoffs are the default ranks k of the metrics
+var DefaultCutoffs = []int{1, 3, 5, 10}
+
+// Case is a labelled query of the dataset, a line of the JSONL file, e.g.:
+//
+//	{"id": "install", "query": "How to install the SDK?", "relevant_sources": ["docs/install.md"]}
+type Case struct {
+	// ID identifies the query in the report (its line number if empty)
+	ID    string `json:"id,omitempty"`
+	Query string `json:"query"`
+	// RelevantIDs are the IDs of the relevant chunks
+	RelevantIDs []string `json:"relevant_ids,omitempty"`
+	// RelevantSources are the relevant documents: a chunk is relevant if its "source" metadata is one of them
+	RelevantSources []string `json:"relevant_sources,omitempty"`
+}
+
+// Target is a RAG agent (or any rag.Searcher) evaluated with its search options
+type Target struct {
+	Name     string
+	Searcher rag.Searcher
+	// Options are the options of the searches, the number of results being the largest cutoff
+	Options []rag.SearchOption
+}
+
+// LoadDataset reads a JSONL dataset file
+func LoadDataset(path string) ([]Case, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadDataset(file)
+}
+
+// ReadDataset reads a JSONL dataset, one Case per line, skipping the blank lines
+func ReadDataset(reader io.Reader) ([]Case, error) {
+	cases := []Case{}
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return nil, fmt.Errorf("invalid dataset line %d: %w", line, err)
+		}
+		if strings.TrimSpace(c.Query) == "" {
+			return nil, fmt.Errorf("invalid dataset line %d: empty query", line)
+		}
+		if len(c.RelevantIDs)+len(c.RelevantSources) == 0 {
+			return nil, fmt.Errorf("invalid dataset line %d: no relevant ids nor sources", line)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line-%d", line)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading dataset: %w", err)
+	}
+	return cases, nil
+}
+
+// evalConfig is the configuration of an evaluation
+type evalConfig struct {
+	cutoffs  []int
+	progress func(target string, done, total int)
+}
+
+// EvalOption configures Evaluate
+type EvalOption func(*evalConfig)
+
+// WithCutoffs sets the ranks k of the metrics (DefaultCutoffs by default)
+func WithCutoffs(cutoffs ...int) EvalOption {
+	return func(c *evalConfig) {
+		c.cutoffs = cutoffs
+	}
+}
+
+// WithProgress sets a function called after each query with the number of queries evaluated for the target
+func WithProgress(progress func(target string, done, total int)) EvalOption {
+	return func(c *evalConfig) {
+		c.progress = progress
+	}
+}
+
+// Evaluate runs the queries of the dataset against each target, and returns the metrics of the targets
+// A failed search counts as a query without relevant result, and its error is recorded in the report
+func Evaluate(dataset []Case, targets []Target, opts ...EvalOption) (Report, error) {
+	config := evalConfig{cutoffs: DefaultCutoffs}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	cutoffs := slices.Clone(config.cutoffs)
+	slices.Sort(cutoffs)
+	cutoffs = slices.Compact(cutoffs)
+	if len(cutoffs) == 0 || cutoffs[0] <= 0 {
+		return Report{}, fmt.Errorf("the cutoffs must be positive")
+	}
+	if len(dataset) == 0 {
+		return Report{}, fmt.Errorf("empty dataset")
+	}
+	if len(targets) == 0 {
+		return Report{}, fmt.Errorf("no target to evaluate")
+	}
+
+	report := Report{Cutoffs: cutoffs, Queries: len(dataset)}
+	for _, target := range targets {
+		if target.Searcher == nil {
+			return Report{}, fmt.Errorf("target %q has no searcher", target.Name)
+		}
+		if target.Name == "" {
+			target.Name = target.Searcher.GetName()
+		}
+		report.Targets = append(report.Targets, evaluateTarget(dataset, target, cutoffs, config.progress))
+	}
+	return report, nil
+}
+
+// evaluateTarget runs the queries of the dataset against a target
+func evaluateTarget(dataset []Case, target Target, cutoffs []int, progress func(string, int, int)) TargetReport {
+	k := cutoffs[len(cutoffs)-1]
+	options := append(slices.Clone(target.Options), rag.WithTopK(k))
+
+	targetReport := TargetReport{Name: target.Name}
+	sums := make([]Metrics, len(cutoffs))
+	for idx, c := range dataset {
+		caseReport := CaseReport{ID: c.ID, Query: c.Query}
+		results, err := target.Searcher.Search(c.Query, options...)
+		if err != nil {
+			caseReport.Error = err.Error()
+			targetReport.Failed++
+		}
+		if len(results) > k {
+			results = results[:k]
+		}
+
+		relevance := judge(c, results)
+		for _, result := range results {
+			caseReport.Retrieved = append(caseReport.Retrieved, result.ID)
+		}
+		for i, cutoff := range cutoffs {
+			metrics := computeMetrics(relevance, relevantCount(c), cutoff)
+			caseReport.Metrics = append(caseReport.Metrics, metrics)
+			sums[i].add(metrics)
+		}
+		targetReport.Cases = append(targetReport.Cases, caseReport)
+		if progress != nil {
+			progress(target.Name, idx+1, len(dataset))
+		}
+	}
+	for i, cutoff := range cutoffs {
+		targetReport.Metrics = append(targetReport.Metrics, sums[i].mean(cutoff, len(dataset)))
+	}
+	return targetReport
+}
@@ -0,0 +1,100 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/snipwise/snip-sdk/snip/rag"
+)
+
+// Metrics are the retrieval metrics at the rank K, averaged over the queries in a TargetReport
+type Metrics struct {
+	K int `json:"k"`
+	// Recall is the fraction of the relevant chunks and sources found in the K first results
+	Recall float64 `json:"recall"`
+	// Precision is the fraction of the K first results that are relevant
+	Precision float64 `json:"precision"`
+	// MRR is the reciprocal rank of the first relevant result (0 if none in the K first results)
+	MRR float64 `json:"mrr"`
+	// NDCG is the normalized discounted cumulative gain of the K first results (binary relevance)
+	NDCG float64 `json:"ndcg"`
+}
+
+func (m *Metrics) add(other Metrics) {
+	m.Recall += other.Recall
+	m.Precision += other.Precision
+	m.MRR += other.MRR
+	m.NDCG += other.NDCG
+}
+
+func (m Metrics) mean(k, n int) Metrics {
+	return Metrics{K: k, Recall: m.Recall / float64(n), Precision: m.Precision / float64(n), MRR: m.MRR / float64(n), NDCG: m.NDCG / float64(n)}
+}
+
+// judgement is the relevance of a result: relevant tells if it is relevant, and newKeys is the number
+// of relevant chunks and sources it matches that were not matched by the previous results
+type judgement struct {
+	relevant bool
+	newKeys  int
+}
+
+// judge returns the relevance of each result to the case
+// Several chunks of a relevant source are all relevant, but only the first one counts in the recall and nDCG,
+// and a relevant chunk of a relevant source counts twice: once for the chunk, once for the source
+func judge(c Case, results []rag.SearchResult) []judgement {
+	found := map[string]bool{}
+	judgements := make([]judgement, len(results))
+	for idx, result := range results {
+		keys := []string{}
+		if slices.Contains(c.RelevantIDs, result.ID) {
+			keys = append(keys, "id:"+result.ID)
+		}
+		if source, ok := result.Metadata["source"]; ok && slices.Contains(c.RelevantSources, fmt.Sprint(source)) {
+			keys = append(keys, "source:"+fmt.Sprint(source))
+		}
+		for _, key := range keys {
+			judgements[idx].relevant = true
+			if !found[key] {
+				found[key] = true
+				judgements[idx].newKeys++
+			}
+		}
+	}
+	return judgements
+}
+
+// relevantCount returns the number of relevant chunks and sources of the case
+func relevantCount(c Case) int {
+	return len(slices.Compact(slices.Sorted(slices.Values(c.RelevantIDs)))) + len(slices.Compact(slices.Sorted(slices.Values(c.RelevantSources))))
+}
+
+// computeMetrics returns the metrics of the k first judgements of a query with relevant relevant items
+func computeMetrics(judgements []judgement, relevant, k int) Metrics {
+	metrics := Metrics{K: k}
+	var found, relevantResults int
+	var dcg float64
+	for rank, j := range judgements[:min(k, len(judgements))] {
+		if j.relevant {
+			relevantResults++
+			if metrics.MRR == 0 {
+				metrics.MRR = 1 / float64(rank+1)
+			}
+		}
+		found += j.newKeys
+		dcg += float64(j.newKeys) / math.Log2(float64(rank+2))
+	}
+	var idcg float64
+	for rank := range min(k, relevant) {
+		idcg += 1 / math.Log2(float64(rank+2))
+	}
+
+	if relevant > 0 {
+		metrics.Recall = float64(min(found, relevant)) / float64(relevant)
+	}
+	metrics.Precision = float64(relevantResults) / float64(k)
+	if idcg > 0 {
+		metrics.NDCG = min(dcg/idcg, 1)
+	}
+	return metrics
+}
@@ -0,0 +1,87 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Report is the result of an evaluation, with the metrics of each target
+type Report struct {
+	Cutoffs []int          `json:"cutoffs"`
+	Queries int            `json:"queries"`
+	Targets []TargetReport `json:"targets"`
+}
+
+// TargetReport are the metrics of a target averaged over the queries, and the metrics of each query
+type TargetReport struct {
+	Name string `json:"name"`
+	// Failed is the number of failed searches
+	Failed  int          `json:"failed"`
+	Metrics []Metrics    `json:"metrics"`
+	Cases   []CaseReport `json:"cases"`
+}
+
+// CaseReport are the metrics of a query for a target
+type CaseReport struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+	// Retrieved are the IDs of the chunks found, in the order of the results
+	Retrieved []string  `json:"retrieved"`
+	Metrics   []Metrics `json:"metrics"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// WriteTable writes the metrics of the targets as a table, a row per target and a column per metric and cutoff
+func (report Report) WriteTable(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{"TARGET"}
+	for _, name := range []string{"RECALL", "PRECISION", "MRR", "NDCG"} {
+		for _, k := range report.Cutoffs {
+			header = append(header, fmt.Sprintf("%s@%d", name, k))
+		}
+	}
+	header = append(header, "FAILED")
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+
+	for _, target := range report.Targets {
+		row := []string{target.Name}
+		for _, value := range []func(Metrics) float64{
+			func(m Metrics) float64 { return m.Recall },
+			func(m Metrics) float64 { return m.Precision },
+			func(m Metrics) float64 { return m.MRR },
+			func(m Metrics) float64 { return m.NDCG },
+		} {
+			for _, metrics := range target.Metrics {
+				row = append(row, fmt.Sprintf("%.3f", value(metrics)))
+			}
+		}
+		row = append(row, fmt.Sprintf("%d/%d", target.Failed, report.Queries))
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	return table.Flush()
+}
+
+// WriteJSON writes the report, with the metrics of each query, as indented JSON
+func (report Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// SaveJSON writes the report as JSON in a file
+func (report Report) SaveJSON(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJSON(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
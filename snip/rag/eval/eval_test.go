@@ -0,0 +1,164 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/rag"
+)
+
+// fakeSearcher returns the results of each query, in order
+type fakeSearcher struct {
+	name    string
+	results map[string][]rag.SearchResult
+	k       int
+}
+
+func (s *fakeSearcher) GetName() string {
+	return s.name
+}
+
+func (s *fakeSearcher) Search(query string, opts ...rag.SearchOption) ([]rag.SearchResult, error) {
+	s.k = rag.NewSearchOptions(opts...).K
+	results, ok := s.results[query]
+	if !ok {
+		return nil, errors.New("search failed")
+	}
+	return results, nil
+}
+
+func chunk(id, source string) rag.SearchResult {
+	return rag.SearchResult{ID: id, Metadata: map[string]any{"source": source}}
+}
+
+const dataset = `{"id": "swim", "query": "Which animals swim?", "relevant_ids": ["dolphins", "whales"]}
+
+{"query": "What flies?", "relevant_sources": ["birds.md"]}
+{"id": "broken", "query": "Unknown", "relevant_ids": ["ants"]}
+`
+
+func TestReadDataset(t *testing.T) {
+	cases, err := ReadDataset(strings.NewReader(dataset))
+	if err != nil {
+		t.Fatalf("ReadDataset() unexpected error: %v", err)
+	}
+	if len(cases) != 3 || cases[1].ID != "line-3" || cases[1].RelevantSources[0] != "birds.md" {
+		t.Errorf("ReadDataset() = %+v, want 3 cases, the second one named after its line", cases)
+	}
+
+	for _, invalid := range []string{`{"query": "x"}`, `{"relevant_ids": ["a"]}`, `{"query": `} {
+		if _, err := ReadDataset(strings.NewReader(invalid)); err == nil {
+			t.Errorf("ReadDataset(%s): expected an error", invalid)
+		}
+	}
+}
+
+func TestComputeMetrics(t *testing.T) {
+	// relevant results at the ranks 2 and 3, out of 2 relevant items
+	judgements := []judgement{{}, {relevant: true, newKeys: 1}, {relevant: true, newKeys: 1}}
+	metrics := computeMetrics(judgements, 2, 3)
+	idcg := 1 + 1/math.Log2(3)
+	dcg := 1/math.Log2(3) + 1/math.Log2(4)
+	want := Metrics{K: 3, Recall: 1, Precision: 2.0 / 3, MRR: 0.5, NDCG: dcg / idcg}
+	if math.Abs(metrics.NDCG-want.NDCG) > 1e-9 || metrics.Recall != want.Recall ||
+		math.Abs(metrics.Precision-want.Precision) > 1e-9 || metrics.MRR != want.MRR {
+		t.Errorf("computeMetrics() = %+v, want %+v", metrics, want)
+	}
+
+	metrics = computeMetrics(judgements, 2, 1)
+	if metrics != (Metrics{K: 1}) {
+		t.Errorf("computeMetrics() at 1 = %+v, want zeros", metrics)
+	}
+}
+
+func TestJudgeCountsSourcesOnce(t *testing.T) {
+	c := Case{RelevantSources: []string{"birds.md"}}
+	judgements := judge(c, []rag.SearchResult{chunk("eagles", "birds.md"), chunk("owls", "birds.md")})
+	if judgements[0].newKeys != 1 || !judgements[1].relevant || judgements[1].newKeys != 0 {
+		t.Errorf("judge() = %+v, want both chunks relevant and the source found once", judgements)
+	}
+	metrics := computeMetrics(judgements, relevantCount(c), 2)
+	if metrics.Recall != 1 || metrics.Precision != 1 || metrics.NDCG != 1 {
+		t.Errorf("metrics = %+v, want a perfect retrieval", metrics)
+	}
+}
+
+func TestJudgeCountsIDsAndSources(t *testing.T) {
+	c := Case{RelevantIDs: []string{"eagles"}, RelevantSources: []string{"birds.md"}}
+	judgements := judge(c, []rag.SearchResult{chunk("eagles", "birds.md"), chunk("frogs", "ponds.md")})
+	if judgements[0].newKeys != 2 || judgements[1].relevant {
+		t.Errorf("judge() = %+v, want the first chunk matching its ID and its source", judgements)
+	}
+	metrics := computeMetrics(judgements, relevantCount(c), 1)
+	if metrics.Recall != 1 || metrics.Precision != 1 || metrics.MRR != 1 || metrics.NDCG != 1 {
+		t.Errorf("metrics = %+v, want a perfect retrieval", metrics)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	cases, _ := ReadDataset(strings.NewReader(dataset))
+	good := &fakeSearcher{name: "good", results: map[string][]rag.SearchResult{
+		"Which animals swim?": {chunk("dolphins", "sea.md"), chunk("whales", "sea.md"), chunk("eagles", "birds.md")},
+		"What flies?":         {chunk("eagles", "birds.md")},
+	}}
+	bad := &fakeSearcher{name: "bad", results: map[string][]rag.SearchResult{
+		"Which animals swim?": {chunk("eagles", "birds.md"), chunk("dolphins", "sea.md")},
+		"What flies?":         {chunk("whales", "sea.md")},
+	}}
+
+	report, err := Evaluate(cases, []Target{{Searcher: good}, {Name: "baseline", Searcher: bad}}, WithCutoffs(3, 1))
+	if err != nil {
+		t.Fatalf("Evaluate() unexpected error: %v", err)
+	}
+	if good.k != 3 || len(report.Cutoffs) != 2 || report.Cutoffs[0] != 1 {
+		t.Errorf("searched %d results with the cutoffs %v, want 3 and sorted cutoffs", good.k, report.Cutoffs)
+	}
+	goodReport, badReport := report.Targets[0], report.Targets[1]
+	if goodReport.Name != "good" || badReport.Name != "baseline" || goodReport.Failed != 1 || goodReport.Cases[2].Error == "" {
+		t.Errorf("targets = %s (%d failed), %s, want the searcher name and the unknown query failed", goodReport.Name, goodReport.Failed, badReport.Name)
+	}
+	// 2 of the 3 queries are perfectly retrieved by good at 1: swim (1 of 2 relevant) and flies
+	if recall := goodReport.Metrics[0].Recall; math.Abs(recall-0.5) > 1e-9 {
+		t.Errorf("recall@1 = %f, want (0.5 + 1 + 0) / 3", recall)
+	}
+	if mrr := badReport.Metrics[1].MRR; math.Abs(mrr-0.5/3) > 1e-9 {
+		t.Errorf("bad MRR@3 = %f, want (0.5 + 0 + 0) / 3", mrr)
+	}
+
+	var table bytes.Buffer
+	if err := report.WriteTable(&table); err != nil {
+		t.Fatalf("WriteTable() unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "RECALL@1") || !strings.Contains(lines[0], "NDCG@3") ||
+		!strings.HasPrefix(lines[1], "good ") || !strings.HasSuffix(lines[1], "1/3") {
+		t.Errorf("table:\n%s", table.String())
+	}
+
+	var output bytes.Buffer
+	if err := report.WriteJSON(&output); err != nil {
+		t.Fatalf("WriteJSON() unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(output.Bytes(), &decoded); err != nil || len(decoded.Targets[0].Cases) != 3 || decoded.Targets[0].Metrics[1].K != 3 {
+		t.Errorf("WriteJSON() = %s, %v", output.String(), err)
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	cases := []Case{{ID: "q", Query: "q", RelevantIDs: []string{"a"}}}
+	searcher := &fakeSearcher{name: "s"}
+	if _, err := Evaluate(cases, []Target{{Searcher: searcher}}, WithCutoffs(0, 3)); err == nil {
+		t.Error("Evaluate() with a zero cutoff: expected an error")
+	}
+	if _, err := Evaluate(nil, []Target{{Searcher: searcher}}); err == nil {
+		t.Error("Evaluate() of an empty dataset: expected an error")
+	}
+	if _, err := Evaluate(cases, []Target{{Name: "none"}}); err == nil {
+		t.Error("Evaluate() of a target without searcher: expected an error")
+	}
+}
@@ -3,9 +3,12 @@ package rag
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/snipwise/snip-sdk/snip/agents"
 	openaihelpers "github.com/snipwise/snip-sdk/snip/openai-helpers"
+	"github.com/snipwise/snip-sdk/snip/rag/bm25"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
 	"github.com/snipwise/snip-sdk/snip/text"
 	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
 
@@ -25,12 +28,23 @@ type RagAgent struct {
 	storeName string
 	storePath string
 
-	genKitInstance    *genkit.Genkit
-	embedder          ai.Embedder
-	docStore          *localvec.DocStore
-	documentRetriever ai.Retriever
+	genKitInstance *genkit.Genkit
+	embedder       ai.Embedder
+	store          vectorstore.VectorStore
+	// lexical is the BM25 index of the chunks of the store, nil if disabled (see WithLexicalIndex)
+	lexical *bm25.Index
+	// reranker reorders the results of the searches, nil if disabled (see WithReranker)
+	reranker Reranker
+	// transformers rewrite the queries of the searches, none if disabled (see WithQueryTransformer)
+	transformers []QueryTransformer
+	// embeddingCache stores the embeddings on disk, nil if disabled (see WithEmbeddingCache)
+	embeddingCache *EmbeddingCache
 
 	embeddingDimension int
+	// embeddingInfo is the embedding model of the store, its fingerprint is part of the keys of the embedding cache
+	embeddingInfo EmbeddingModelInfo
+	// verifyEmbeddingModel is set while embeddingInfo comes from the store and the embedder has not been checked against it
+	verifyEmbeddingModel atomic.Bool
 
 	logger logger.Logger
 
@@ -59,57 +73,61 @@ func NewRagAgent(ctx context.Context, ragAgentConfig agents.AgentConfig, storeCo
 	// == you don't need to prefix the model name with the provider
 	embedder := oaiPlugin.DefineEmbedder(ragAgentConfig.ModelID, nil)
 
-	// get embedder to calculate embedding dimension
-	// calculate embedding dimension
-	embeddingDimension, err := calculateEmbeddingDimensionForModel(ctx, genKitInstance, embedder)
-	if err != nil {
-		//log.Printf("Warning: could not calculate embedding dimension: %v", err)
-		return nil, fmt.Errorf("error calculating embedding dimension: %w", err)
+	ragAgent := &RagAgent{
+		ctx:            ctx,
+		Name:           ragAgentConfig.Name,
+		ModelID:        ragAgentConfig.ModelID,
+		storeName:      storeConfig.StoreName,
+		storePath:      storeConfig.StorePath,
+		genKitInstance: genKitInstance,
+		embedder:       embedder,
+		logger:         logger.GetLoggerFromEnvWithPrefix(ragAgentConfig.Name), // Default logger from env
 	}
 
-	if err := localvec.Init(); err != nil {
-		return nil, fmt.Errorf("error initializing localvec: %w", err)
+	// Apply all options (can override logger and store)
+	for _, opt := range opts {
+		opt(ragAgent)
 	}
-	docStore, documentRetriever, err := localvec.DefineRetriever(
-		genKitInstance,
-		storeConfig.StoreName,
-		localvec.Config{
-			Embedder: embedder,
-			Dir:      storeConfig.StorePath,
-		},
-		nil,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("error defining retriever: %w", err)
+
+	// NOTE: the default store is the localvec store of the StoreConfig
+	if ragAgent.store == nil {
+		if err := localvec.Init(); err != nil {
+			return nil, fmt.Errorf("error initializing localvec: %w", err)
+		}
+		docStore, _, err := localvec.DefineRetriever(
+			genKitInstance,
+			storeConfig.StoreName,
+			localvec.Config{
+				Embedder: embedder,
+				Dir:      storeConfig.StorePath,
+			},
+			nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error defining retriever: %w", err)
+		}
+		ragAgent.store = vectorstore.NewLocalvecStore(docStore)
 	}
 
-	ragAgent := &RagAgent{
-		ctx:                ctx,
-		Name:               ragAgentConfig.Name,
-		ModelID:            ragAgentConfig.ModelID,
-		storeName:          storeConfig.StoreName,
-		storePath:          storeConfig.StorePath,
-		genKitInstance:     genKitInstance,
-		embedder:           embedder,
-		docStore:           docStore,
-		documentRetriever:  documentRetriever,
-		embeddingDimension: embeddingDimension,
-		logger:             logger.GetLoggerFromEnvWithPrefix(ragAgentConfig.Name), // Default logger from env
-	}
-
-	// Apply all options (can override logger)
-	for _, opt := range opts {
-		opt(ragAgent)
+	// NOTE: the embedding model is set after the options, to use the store of WithVectorStore
+	if err := ragAgent.initEmbeddingModel(); err != nil {
+		return nil, err
+	}
+
+	if ragAgent.lexical != nil {
+		if err := ragAgent.rebuildLexicalIndex(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Log model and store information
+	numberOfDocuments := ragAgent.GetNumberOfDocuments()
 	ragAgent.logger.Info("✅ Model %s is available at %s", ragAgentConfig.ModelID, ragAgentConfig.EngineURL)
-	ragAgent.logger.Debug("🔎 Retriever: %v", documentRetriever)
-	ragAgent.logger.Debug("📚 DocStore: %d documents", len(docStore.Data))
-	if len(docStore.Data) == 0 {
+	ragAgent.logger.Debug("📚 Store: %d documents", numberOfDocuments)
+	if numberOfDocuments == 0 {
 		ragAgent.logger.Info("🚧 The document store is empty.")
 	} else {
-		ragAgent.logger.Info("🚧 The document store has %d documents.", len(docStore.Data))
+		ragAgent.logger.Info("🚧 The document store has %d documents.", numberOfDocuments)
 	}
 
 	return ragAgent, nil
@@ -118,15 +136,33 @@ func NewRagAgent(ctx context.Context, ragAgentConfig agents.AgentConfig, storeCo
 
 // isStoreInitialized checks if the document store is initialized
 func (agent *RagAgent) IsStoreInitialized() bool {
-	return agent.docStore != nil
+	return agent.store != nil
 }
 
 // getNumberOfDocuments returns the number of documents in the store
 func (agent *RagAgent) GetNumberOfDocuments() int {
-	if agent.docStore == nil {
+	if agent.store == nil {
 		return 0
 	}
-	return len(agent.docStore.Data)
+	count, err := agent.store.Count(agent.ctx)
+	if err != nil {
+		agent.logger.Error("Error counting documents: %v", err)
+		return 0
+	}
+	return count
+}
+
+// GetVectorStore returns the store of the agent
+func (agent *RagAgent) GetVectorStore() vectorstore.VectorStore {
+	return agent.store
+}
+
+// Close closes the store of the agent
+func (agent *RagAgent) Close() error {
+	if agent.store == nil {
+		return nil
+	}
+	return agent.store.Close()
 }
 
 func (agent *RagAgent) Kind() agents.AgentKind {
@@ -144,62 +180,41 @@ func (agent *RagAgent) GetInfo() (agents.RagAgentInfo, error) {
 	}, nil
 }
 
+// AddTextChunksToStore adds the chunks to the store and returns the number of chunks given
+// The chunks whose ID is already in the store are skipped without being embedded, use UpsertTextChunks to update them
 func (agent *RagAgent) AddTextChunksToStore(chunks []text.TextChunk) (int, error) {
-	docs := []*ai.Document{}
-
-	for idx, chunk := range chunks {
-		agent.logger.Debug("💾 Adding chunk %d: %v", idx, chunk)
-		if chunk.Metadata != nil {
-			docs = append(docs, ai.DocumentFromText(chunk.Content, chunk.Metadata))
-		} else {
-			docs = append(docs, ai.DocumentFromText(chunk.Content, nil))
-		}
+	if !agent.IsStoreInitialized() {
+		return 0, fmt.Errorf("document store is not initialized")
 	}
-	agent.logger.Info("🗂️ Indexing %d documents...", len(docs))
-	err := localvec.Index(agent.ctx, docs, agent.docStore)
+	records, err := agent.newRecords(chunks, false)
 	if err != nil {
 		return 0, fmt.Errorf("error indexing documents: %w", err)
 	}
+	if len(records) == 0 {
+		return len(chunks), nil
+	}
+	if err := agent.embedRecords(records); err != nil {
+		return 0, err
+	}
+	if _, err := agent.store.Add(agent.ctx, records); err != nil {
+		return 0, fmt.Errorf("error indexing documents: %w", err)
+	}
+	agent.indexLexical(records)
 	agent.logger.Info("✅ Document indexing completed.")
-	return len(docs), nil
+	return len(chunks), nil
 }
 
+// SearchSimilarities returns the content of the DefaultSearchK chunks most similar to the query
+// Use Search to get the scores and the metadata, or to set the number of results
 func (agent *RagAgent) SearchSimilarities(query string) ([]string, error) {
-	// === SIMILARITY SEARCH ===
-	// Create a query document from the user question
-	queryDoc := ai.DocumentFromText(query, nil)
-	// Create a retriever request with custom options
-	request := &ai.RetrieverRequest{
-		Query: queryDoc,
-	}
-	// Retrieve documents relevant to a query
-	retrieveResponse, err := agent.documentRetriever.Retrieve(agent.ctx, request)
+	results, err := agent.Search(query)
 	if err != nil {
-		retrieveResponse = &ai.RetrieverResponse{Documents: []*ai.Document{}}
 		return nil, fmt.Errorf("error retrieving documents: %w", err)
 	}
-	//fmt.Println("📝 Retrieved documents:", retrieveResponse.Documents)
 
-	// Process the retrieved documents
 	similarDocuments := []string{}
-	for _, doc := range retrieveResponse.Documents {
-		//fmt.Println(doc.Metadata, doc.Content[0].Text)
-		similarDocuments = append(similarDocuments, doc.Content[0].Text)
+	for _, result := range results {
+		similarDocuments = append(similarDocuments, result.Content)
 	}
 	return similarDocuments, nil
 }
-
-// calculateEmbeddingDimensionForModel calculates the embedding dimension for a given model by generating a sample embedding.
-func calculateEmbeddingDimensionForModel(ctx context.Context, genKitInstance *genkit.Genkit, embedder ai.Embedder) (int, error) {
-	res, err := genkit.Embed(
-		ctx,
-		genKitInstance,
-		ai.WithEmbedder(embedder),
-		ai.WithTextDocs("Hello World"),
-	)
-	if err != nil {
-		return 0, fmt.Errorf("error when calculating embedding dimension: %w", err)
-	}
-
-	return len(res.Embeddings[0].Embedding), nil
-}
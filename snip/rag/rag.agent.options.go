@@ -1,6 +1,8 @@
 package rag
 
 import (
+	"github.com/snipwise/snip-sdk/snip/rag/bm25"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
 	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
 )
 
@@ -31,3 +33,42 @@ func WithLogLevel(level logger.LogLevel) RagAgentOption {
 		a.logger = logger.NewConsoleLoggerWithPrefix(level, a.Name)
 	}
 }
+
+// WithVectorStore sets the store of the agent instead of the localvec store of the StoreConfig
+// (e.g. vectorstore.NewMemoryStore(), vectorstore.NewSegmentedStore(dir),
+// or vectorstore.NewHNSWStore(...) for an approximate search on large stores)
+func WithVectorStore(store vectorstore.VectorStore) RagAgentOption {
+	return func(a *RagAgent) {
+		a.store = store
+	}
+}
+
+// WithLexicalIndex keeps a BM25 index of the chunks alongside the store, for the lexical and hybrid
+// searches (see WithHybridSearch). The index is built from the store when the agent is created,
+// then kept up to date by the methods of the agent (not by the writes made directly to the store)
+func WithLexicalIndex() RagAgentOption {
+	return func(a *RagAgent) {
+		a.lexical = bm25.NewIndex()
+	}
+}
+
+// WithReranker reranks the results of the searches: a search fetches more candidates
+// (see WithRerankCandidates), scores them with the reranker and keeps the best ones
+// e.g. WithReranker(llmReranker) with an LLMReranker
+func WithReranker(reranker Reranker) RagAgentOption {
+	return func(a *RagAgent) {
+		a.reranker = reranker
+	}
+}
+
+// WithEmbeddingCache reads and writes the embeddings in the cache, so that the texts
+// already embedded by the model (by any agent sharing the cache) are not sent to the engine again
+//
+//	cacheDir, _ := rag.DefaultEmbeddingCacheDir()
+//	cache, err := rag.NewEmbeddingCache(cacheDir)
+//	agent, err := rag.NewRagAgent(ctx, agentConfig, storeConfig, rag.WithEmbeddingCache(cache))
+func WithEmbeddingCache(cache *EmbeddingCache) RagAgentOption {
+	return func(a *RagAgent) {
+		a.embeddingCache = cache
+	}
+}
@@ -0,0 +1,160 @@
+package rag
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/snipwise/snip-sdk/snip/rag/bm25"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+// UpsertTextChunks adds the chunks to the store, replacing the ones with the same ID,
+// and returns the number of chunks written
+// The chunks already in the store with the same content and metadata are skipped without being embedded,
+// so that re-running an ingestion only embeds the new and changed chunks
+func (agent *RagAgent) UpsertTextChunks(chunks []text.TextChunk) (int, error) {
+	if !agent.IsStoreInitialized() {
+		return 0, fmt.Errorf("document store is not initialized")
+	}
+	records, err := agent.newRecords(chunks, true)
+	if err != nil {
+		return 0, fmt.Errorf("error upserting documents: %w", err)
+	}
+	agent.logger.Debug("📚 %d chunks unchanged", len(chunks)-len(records))
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := agent.embedRecords(records); err != nil {
+		return 0, err
+	}
+	if err := agent.store.Upsert(agent.ctx, records); err != nil {
+		return 0, fmt.Errorf("error upserting documents: %w", err)
+	}
+	agent.indexLexical(records)
+	agent.logger.Info("✅ %d documents upserted.", len(records))
+	return len(records), nil
+}
+
+// DeleteByID removes the chunks with these IDs from the store, and returns the number of chunks removed
+func (agent *RagAgent) DeleteByID(ids ...string) (int, error) {
+	if !agent.IsStoreInitialized() {
+		return 0, fmt.Errorf("document store is not initialized")
+	}
+	deleted, err := agent.store.Delete(agent.ctx, ids)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting documents: %w", err)
+	}
+	if agent.lexical != nil {
+		agent.lexical.Delete(ids...)
+	}
+	return deleted, nil
+}
+
+// DeleteByMetadata removes the chunks whose metadata have all the values of filter
+// (for example map[string]any{"source": "docs/intro.md"}), and returns the number of chunks removed
+func (agent *RagAgent) DeleteByMetadata(filter map[string]any) (int, error) {
+	if !agent.IsStoreInitialized() {
+		return 0, fmt.Errorf("document store is not initialized")
+	}
+	deleted, err := vectorstore.DeleteByMetadata(agent.ctx, agent.store, filter)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting documents: %w", err)
+	}
+	if agent.lexical != nil {
+		agent.lexical.DeleteByMetadata(filter)
+	}
+	return deleted, nil
+}
+
+// ChunkID returns the ID of a chunk in the store: its ID, or the hash of its content and metadata if empty
+func ChunkID(chunk text.TextChunk) string {
+	if chunk.ID != "" {
+		return chunk.ID
+	}
+	return vectorstore.DocumentID(chunk.Content, chunk.Metadata)
+}
+
+// newRecords returns the records of the chunks to write, without their embedding
+// The chunks whose ID is in the store are skipped, or only the unchanged ones if replace is true;
+// when several chunks have the same ID, the first one is kept, or the last one if replace is true
+func (agent *RagAgent) newRecords(chunks []text.TextChunk, replace bool) ([]vectorstore.Record, error) {
+	records := make([]vectorstore.Record, 0, len(chunks))
+	index := make(map[string]int, len(chunks))
+	for _, chunk := range chunks {
+		record := vectorstore.Record{ID: ChunkID(chunk), Content: chunk.Content, Metadata: chunk.Metadata}
+		if i, ok := index[record.ID]; ok {
+			if replace {
+				records[i] = record
+			}
+			continue
+		}
+		index[record.ID] = len(records)
+		records = append(records, record)
+	}
+
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	existing, err := agent.store.Get(agent.ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	skip := make(map[string]bool, len(existing))
+	for _, stored := range existing {
+		record := records[index[stored.ID]]
+		if !replace || vectorstore.DocumentID(stored.Content, stored.Metadata) == vectorstore.DocumentID(record.Content, record.Metadata) {
+			skip[stored.ID] = true
+		}
+	}
+	return slices.DeleteFunc(records, func(record vectorstore.Record) bool { return skip[record.ID] }), nil
+}
+
+// embedRecords sets the embeddings of the records, with a single request to the embedder
+// for the contents not in the embedding cache
+func (agent *RagAgent) embedRecords(records []vectorstore.Record) error {
+	texts := make([]string, len(records))
+	for idx, record := range records {
+		agent.logger.Debug("💾 Adding chunk %s: %s", record.ID, record.Content)
+		texts[idx] = record.Content
+	}
+
+	agent.logger.Info("🗂️ Indexing %d documents...", len(texts))
+	embeddings, err := agent.embedTexts(texts)
+	if err != nil {
+		return fmt.Errorf("error indexing documents: %w", err)
+	}
+	for idx := range records {
+		records[idx].Embedding = embeddings[idx]
+	}
+	return nil
+}
+
+// indexLexical adds the records to the BM25 index, if enabled
+func (agent *RagAgent) indexLexical(records []vectorstore.Record) {
+	if agent.lexical == nil {
+		return
+	}
+	docs := make([]bm25.Document, len(records))
+	for idx, record := range records {
+		docs[idx] = bm25.Document{ID: record.ID, Content: record.Content, Metadata: record.Metadata}
+	}
+	agent.lexical.Upsert(docs...)
+}
+
+// rebuildLexicalIndex indexes all the chunks of the store in the BM25 index
+func (agent *RagAgent) rebuildLexicalIndex() error {
+	agent.lexical = bm25.NewIndex()
+	records := []vectorstore.Record{}
+	err := agent.store.Scan(agent.ctx, func(record vectorstore.Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error building the lexical index: %w", err)
+	}
+	agent.indexLexical(records)
+	agent.logger.Debug("🔤 Lexical index: %d documents", agent.lexical.Len())
+	return nil
+}
@@ -0,0 +1,115 @@
+package rag
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// embeddingCacheFile is the file of the embeddings in the directory of an EmbeddingCache
+const embeddingCacheFile = "embeddings.jsonl"
+
+// EmbeddingCache stores the embeddings on disk by embedding model and content, so that a text
+// already embedded by a model is never sent to the engine again, whatever the agent or the store
+// The cache is safe for concurrent use, and can be shared by the agents with WithEmbeddingCache
+// The agents identify their model by its ID and its fingerprint, so that a model replaced under the same ID
+// does not read the embeddings of the previous one
+type EmbeddingCache struct {
+	mu         sync.RWMutex
+	file       *os.File
+	embeddings map[string][]float32
+}
+
+// embeddingCacheEntry is a line of the cache file
+type embeddingCacheEntry struct {
+	Key       string    `json:"key"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// DefaultEmbeddingCacheDir returns the default directory of the embedding cache: snip/embeddings
+// in the user cache directory (e.g. ~/.cache/snip/embeddings on Linux)
+func DefaultEmbeddingCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snip", "embeddings"), nil
+}
+
+// NewEmbeddingCache opens the embedding cache of a directory, creating it if needed
+func NewEmbeddingCache(dir string) (*EmbeddingCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating embedding cache: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, embeddingCacheFile), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening embedding cache: %w", err)
+	}
+	cache := &EmbeddingCache{file: file, embeddings: map[string][]float32{}}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		// A line cut by a crash is skipped, its text is embedded again
+		var entry embeddingCacheEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Key != "" {
+			cache.embeddings[entry.Key] = entry.Embedding
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading embedding cache: %w", err)
+	}
+	return cache, nil
+}
+
+// embeddingCacheKey identifies the embedding of a content by a model
+func embeddingCacheKey(model, content string) string {
+	hash := sha256.Sum256([]byte(model + "\x00" + content))
+	return hex.EncodeToString(hash[:])
+}
+
+// Get returns the embedding of the content by the model, if cached
+func (c *EmbeddingCache) Get(model, content string) ([]float32, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	embedding, ok := c.embeddings[embeddingCacheKey(model, content)]
+	return embedding, ok
+}
+
+// Put adds the embedding of the content by the model to the cache
+func (c *EmbeddingCache) Put(model, content string, embedding []float32) error {
+	key := embeddingCacheKey(model, content)
+	line, err := json.Marshal(embeddingCacheEntry{Key: key, Embedding: embedding})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.embeddings[key]; ok {
+		return nil
+	}
+	// One write per line, so that the processes sharing the directory do not mix their lines
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing embedding cache: %w", err)
+	}
+	c.embeddings[key] = embedding
+	return nil
+}
+
+// Len returns the number of embeddings in the cache
+func (c *EmbeddingCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.embeddings)
+}
+
+// Close closes the cache file
+func (c *EmbeddingCache) Close() error {
+	return c.file.Close()
+}
@@ -0,0 +1,244 @@
+package rag
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+)
+
+// embeddingProbe is the text embedded to learn the dimension and the fingerprint of the embedding model
+const embeddingProbe = "Hello World"
+
+// minFingerprintSimilarity is the cosine similarity under which two embeddings of embeddingProbe
+// come from different models (the same model on another engine gives slightly different values)
+const minFingerprintSimilarity = 0.99
+
+// ErrEmbeddingModelMismatch is returned by NewRagAgent when the store was built with another embedding model
+var ErrEmbeddingModelMismatch = errors.New("embedding model mismatch")
+
+// EmbeddingModelInfo identifies the embedding model of a store, saved with the store
+// in <store path>/<store name>.embedding.json, or next to the file of a store set with WithVectorStore
+// (<file or directory of the store>.embedding.json, see vectorstore.FileStore)
+// NOTE: a store without name nor file (e.g. vectorstore.NewMemoryStore()) has no embedding model file:
+// only the dimension of its vectors is checked
+type EmbeddingModelInfo struct {
+	ModelID   string `json:"model_id"`
+	Dimension int    `json:"dimension"`
+	// Fingerprint is the embedding of a probe text, to detect a model replaced under the same ID
+	Fingerprint []float32 `json:"fingerprint"`
+}
+
+// embedTexts returns the embeddings of the texts, from the cache if enabled,
+// the texts not cached being embedded with a single request to the embedder
+// If the embedding model comes from the store and is not verified yet, the probe text is embedded
+// with the texts to check the model before using the embeddings
+func (agent *RagAgent) embedTexts(texts []string) ([][]float32, error) {
+	cacheModel := agent.embeddingCacheModel()
+	embeddings := make([][]float32, len(texts))
+	missing := []int{}
+	for idx, content := range texts {
+		if agent.embeddingCache != nil {
+			if embedding, ok := agent.embeddingCache.Get(cacheModel, content); ok {
+				embeddings[idx] = embedding
+				continue
+			}
+		}
+		missing = append(missing, idx)
+	}
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	verify := agent.verifyEmbeddingModel.Load()
+	docs := []*ai.Document{}
+	if verify {
+		docs = append(docs, ai.DocumentFromText(embeddingProbe, nil))
+	}
+	for _, idx := range missing {
+		docs = append(docs, ai.DocumentFromText(texts[idx], nil))
+	}
+	response, err := agent.embedder.Embed(agent.ctx, &ai.EmbedRequest{Input: docs})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) != len(docs) {
+		return nil, fmt.Errorf("%d embeddings for %d documents", len(response.Embeddings), len(docs))
+	}
+	results := response.Embeddings
+	if verify {
+		probe := results[0].Embedding
+		info := EmbeddingModelInfo{ModelID: agent.ModelID, Dimension: len(probe), Fingerprint: probe}
+		if err := agent.compareEmbeddingModel(info); err != nil {
+			return nil, err
+		}
+		agent.verifyEmbeddingModel.Store(false)
+		results = results[1:]
+	}
+	for i, idx := range missing {
+		embeddings[idx] = results[i].Embedding
+		if agent.embeddingCache != nil {
+			if err := agent.embeddingCache.Put(cacheModel, texts[idx], embeddings[idx]); err != nil {
+				agent.logger.Warn("⚠️ %v", err)
+			}
+		}
+	}
+	agent.logger.Debug("🧮 %d texts embedded, %d from the cache", len(missing), len(texts)-len(missing))
+	return embeddings, nil
+}
+
+// embeddingCacheModel returns the model of the embeddings in the cache: the model ID and a hash
+// of the fingerprint, so that another model served under the same ID does not use the cached embeddings
+func (agent *RagAgent) embeddingCacheModel() string {
+	if len(agent.embeddingInfo.Fingerprint) == 0 {
+		return agent.ModelID
+	}
+	hash := sha256.New()
+	for _, value := range agent.embeddingInfo.Fingerprint {
+		binary.Write(hash, binary.LittleEndian, math.Float32bits(value))
+	}
+	return agent.ModelID + "@" + hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// probeEmbeddingModel embeds the probe text, without the cache, to learn the dimension and the fingerprint of the embedder
+func (agent *RagAgent) probeEmbeddingModel() (EmbeddingModelInfo, error) {
+	response, err := agent.embedder.Embed(agent.ctx, &ai.EmbedRequest{Input: []*ai.Document{ai.DocumentFromText(embeddingProbe, nil)}})
+	if err != nil {
+		return EmbeddingModelInfo{}, fmt.Errorf("error when calculating embedding dimension: %w", err)
+	}
+	if len(response.Embeddings) != 1 {
+		return EmbeddingModelInfo{}, fmt.Errorf("error when calculating embedding dimension: %d embeddings for 1 document", len(response.Embeddings))
+	}
+	fingerprint := response.Embeddings[0].Embedding
+	return EmbeddingModelInfo{ModelID: agent.ModelID, Dimension: len(fingerprint), Fingerprint: fingerprint}, nil
+}
+
+// embeddingInfoPath returns the file of the embedding model of the store, empty without store name nor store file
+func (agent *RagAgent) embeddingInfoPath() string {
+	if agent.storeName != "" {
+		return filepath.Join(agent.storePath, agent.storeName+".embedding.json")
+	}
+	if store, ok := agent.store.(vectorstore.FileStore); ok && store.Path() != "" {
+		return filepath.Clean(store.Path()) + ".embedding.json"
+	}
+	return ""
+}
+
+// errStopScan stops a scan of the store after the first record
+var errStopScan = errors.New("stop scan")
+
+// storedDimension returns the dimension of the vectors of the store, 0 if the store is empty
+func (agent *RagAgent) storedDimension() (int, error) {
+	dimension := 0
+	err := agent.store.Scan(agent.ctx, func(record vectorstore.Record) error {
+		dimension = len(record.Embedding)
+		return errStopScan
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return 0, fmt.Errorf("error reading the vectors of the store: %w", err)
+	}
+	return dimension, nil
+}
+
+// initEmbeddingModel sets the embedding model of the agent
+// A store with documents keeps the model saved with it: the ID is checked at once, and the dimension and the fingerprint
+// with the first embeddings (or with CheckEmbeddingModel), without an embedding request at startup
+// Otherwise the embedder is probed, and its model is saved with the store: the documents of a store
+// without saved model (built before the model was saved, or without file) must have its dimension
+func (agent *RagAgent) initEmbeddingModel() error {
+	hasDocuments := agent.GetNumberOfDocuments() > 0
+	if path := agent.embeddingInfoPath(); path != "" && hasDocuments {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error reading the embedding model of the store: %w", err)
+		}
+		if err == nil {
+			var stored EmbeddingModelInfo
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return fmt.Errorf("invalid embedding model file %s: %w", path, err)
+			}
+			if stored.Dimension > 0 && len(stored.Fingerprint) == stored.Dimension {
+				agent.embeddingInfo = stored
+				agent.embeddingDimension = stored.Dimension
+				agent.verifyEmbeddingModel.Store(true)
+				return agent.compareEmbeddingModel(EmbeddingModelInfo{ModelID: agent.ModelID, Dimension: stored.Dimension, Fingerprint: stored.Fingerprint})
+			}
+		}
+	}
+
+	info, err := agent.probeEmbeddingModel()
+	if err != nil {
+		return err
+	}
+	if hasDocuments {
+		dimension, err := agent.storedDimension()
+		if err != nil {
+			return err
+		}
+		if dimension != 0 && dimension != info.Dimension {
+			return fmt.Errorf("%w: the vectors of the store %s have the dimension %d, not the dimension %d of %s: use the same embedding model, or rebuild the store",
+				ErrEmbeddingModelMismatch, agent.storeName, dimension, info.Dimension, info.ModelID)
+		}
+	}
+	agent.embeddingInfo = info
+	agent.embeddingDimension = info.Dimension
+	agent.verifyEmbeddingModel.Store(false)
+	return agent.saveEmbeddingModel()
+}
+
+// CheckEmbeddingModel embeds the probe text to check that the embedder is still the embedding model of the store,
+// instead of waiting for the first embeddings
+func (agent *RagAgent) CheckEmbeddingModel() error {
+	info, err := agent.probeEmbeddingModel()
+	if err != nil {
+		return err
+	}
+	if err := agent.compareEmbeddingModel(info); err != nil {
+		return err
+	}
+	agent.verifyEmbeddingModel.Store(false)
+	return nil
+}
+
+// compareEmbeddingModel returns ErrEmbeddingModelMismatch if info is not the embedding model of the store
+func (agent *RagAgent) compareEmbeddingModel(info EmbeddingModelInfo) error {
+	stored := agent.embeddingInfo
+	if stored.ModelID != info.ModelID || stored.Dimension != info.Dimension {
+		return fmt.Errorf("%w: the store %s was built with %s (dimension %d), not %s (dimension %d): use the same embedding model, or rebuild the store",
+			ErrEmbeddingModelMismatch, agent.storeName, stored.ModelID, stored.Dimension, info.ModelID, info.Dimension)
+	}
+	if similarity := vectorstore.CosineSimilarity(stored.Fingerprint, info.Fingerprint); similarity < minFingerprintSimilarity {
+		return fmt.Errorf("%w: the store %s was built with another version of %s (fingerprint similarity %.3f): rebuild the store",
+			ErrEmbeddingModelMismatch, agent.storeName, info.ModelID, similarity)
+	}
+	return nil
+}
+
+// saveEmbeddingModel saves the embedding model of the agent with the store
+func (agent *RagAgent) saveEmbeddingModel() error {
+	path := agent.embeddingInfoPath()
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(agent.embeddingInfo)
+	if err != nil {
+		return err
+	}
+	if agent.storePath != "" {
+		if err := os.MkdirAll(agent.storePath, 0755); err != nil {
+			return fmt.Errorf("error saving the embedding model of the store: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error saving the embedding model of the store: %w", err)
+	}
+	return nil
+}
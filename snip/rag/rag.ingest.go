@@ -0,0 +1,309 @@
+package rag
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+const (
+	// DefaultIngestBatchSize is the default number of chunks embedded per request
+	DefaultIngestBatchSize = 32
+	// DefaultIngestWorkers is the default number of batches embedded in parallel
+	DefaultIngestWorkers = 4
+	// DefaultIngestRetries is the default number of retries of a failed batch
+	DefaultIngestRetries = 3
+	// DefaultIngestBackoff is the default delay before the first retry, doubled at each retry
+	DefaultIngestBackoff = 500 * time.Millisecond
+)
+
+// IngestProgress is the state of an ingestion, reported after each batch
+type IngestProgress struct {
+	// Total is the number of chunks to ingest
+	Total int `json:"total"`
+	// Processed is the number of chunks written, skipped or failed so far
+	Processed int `json:"processed"`
+	// Written is the number of chunks embedded and written to the store
+	Written int `json:"written"`
+	// Skipped is the number of chunks unchanged in the store or already in the checkpoint
+	Skipped int `json:"skipped"`
+	// Failed is the number of chunks of the batches that failed after all the retries
+	Failed int `json:"failed"`
+}
+
+// String returns a one-line summary, e.g. to update the suffix of a spinner
+func (p IngestProgress) String() string {
+	summary := fmt.Sprintf("%d/%d chunks (%d written, %d skipped", p.Processed, p.Total, p.Written, p.Skipped)
+	if p.Failed > 0 {
+		summary += fmt.Sprintf(", %d failed", p.Failed)
+	}
+	return summary + ")"
+}
+
+// ingestConfig is the configuration of an ingestion
+type ingestConfig struct {
+	batchSize  int
+	workers    int
+	retries    int
+	backoff    time.Duration
+	progress   func(IngestProgress)
+	checkpoint string
+}
+
+// IngestOption configures IngestTextChunks
+type IngestOption func(*ingestConfig)
+
+// WithBatchSize sets the number of chunks embedded per request (DefaultIngestBatchSize by default)
+func WithBatchSize(size int) IngestOption {
+	return func(c *ingestConfig) {
+		if size > 0 {
+			c.batchSize = size
+		}
+	}
+}
+
+// WithWorkers sets the number of batches embedded in parallel (DefaultIngestWorkers by default)
+func WithWorkers(workers int) IngestOption {
+	return func(c *ingestConfig) {
+		if workers > 0 {
+			c.workers = workers
+		}
+	}
+}
+
+// WithRetries sets the number of retries of a failed batch and the delay before the first retry,
+// doubled at each retry (DefaultIngestRetries and DefaultIngestBackoff by default)
+func WithRetries(retries int, backoff time.Duration) IngestOption {
+	return func(c *ingestConfig) {
+		if retries >= 0 {
+			c.retries = retries
+		}
+		if backoff >= 0 {
+			c.backoff = backoff
+		}
+	}
+}
+
+// WithProgress sets a function called after each batch with the progress of the ingestion
+// The calls are serialized, e.g.:
+//
+//	s := spinner.New("").SetSuffix("ingesting...")
+//	s.Start()
+//	agent.IngestTextChunks(chunks, rag.WithProgress(func(p rag.IngestProgress) { s.UpdateSuffix(p.String()) }))
+//	s.Success("ingestion completed")
+func WithProgress(progress func(IngestProgress)) IngestOption {
+	return func(c *ingestConfig) {
+		c.progress = progress
+	}
+}
+
+// WithCheckpoint sets the file recording the chunks ingested, one per line
+// An ingestion interrupted by a crash continues where it stopped: the chunks of the checkpoint
+// are skipped without reading the store. The file is removed when all the chunks are ingested
+func WithCheckpoint(path string) IngestOption {
+	return func(c *ingestConfig) {
+		c.checkpoint = path
+	}
+}
+
+// IngestTextChunks upserts the chunks to the store by batches embedded in parallel
+// The chunks unchanged in the store are skipped (see UpsertTextChunks). A failed batch is retried,
+// then the ingestion goes on with the other batches: the error joins the errors of the failed batches
+func (agent *RagAgent) IngestTextChunks(chunks []text.TextChunk, opts ...IngestOption) (IngestProgress, error) {
+	config := ingestConfig{
+		batchSize: DefaultIngestBatchSize,
+		workers:   DefaultIngestWorkers,
+		retries:   DefaultIngestRetries,
+		backoff:   DefaultIngestBackoff,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	progress := IngestProgress{Total: len(chunks)}
+	if !agent.IsStoreInitialized() {
+		return progress, fmt.Errorf("document store is not initialized")
+	}
+
+	var checkpoint *ingestCheckpoint
+	if config.checkpoint != "" {
+		var err error
+		if checkpoint, err = openIngestCheckpoint(config.checkpoint); err != nil {
+			return progress, err
+		}
+		defer checkpoint.close()
+
+		pending := make([]text.TextChunk, 0, len(chunks))
+		for _, chunk := range chunks {
+			if !checkpoint.has(chunk) {
+				pending = append(pending, chunk)
+			}
+		}
+		progress.Skipped = len(chunks) - len(pending)
+		progress.Processed = progress.Skipped
+		chunks = pending
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	batches := make(chan []text.TextChunk)
+	for range config.workers {
+		wg.Go(func() {
+			for batch := range batches {
+				written, err := agent.ingestBatch(batch, config)
+				if err == nil && checkpoint != nil {
+					err = checkpoint.add(batch)
+				}
+
+				mu.Lock()
+				progress.Processed += len(batch)
+				if err != nil {
+					progress.Failed += len(batch)
+					errs = append(errs, err)
+				} else {
+					progress.Written += written
+					progress.Skipped += len(batch) - written
+				}
+				if config.progress != nil {
+					config.progress(progress)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+
+	agent.logger.Info("🗂️ Ingesting %d chunks (%d already in the checkpoint)...", progress.Total, progress.Skipped)
+	for start := 0; start < len(chunks); start += config.batchSize {
+		if agent.ctx.Err() != nil {
+			break
+		}
+		batches <- chunks[start:min(start+config.batchSize, len(chunks))]
+	}
+	close(batches)
+	wg.Wait()
+
+	if err := agent.ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return progress, fmt.Errorf("error ingesting documents: %w", errors.Join(errs...))
+	}
+	if checkpoint != nil {
+		checkpoint.remove()
+	}
+	agent.logger.Info("✅ Ingestion completed: %s", progress)
+	return progress, nil
+}
+
+// ingestBatch upserts a batch of chunks, retrying on errors, and returns the number of chunks written
+func (agent *RagAgent) ingestBatch(batch []text.TextChunk, config ingestConfig) (int, error) {
+	backoff := config.backoff
+	for attempt := 0; ; attempt++ {
+		written, err := agent.upsertBatch(batch)
+		if err == nil {
+			return written, nil
+		}
+		if attempt >= config.retries {
+			return 0, fmt.Errorf("batch of %d chunks failed after %d attempts: %w", len(batch), attempt+1, err)
+		}
+		agent.logger.Warn("⚠️ Batch of %d chunks failed (attempt %d/%d): %v", len(batch), attempt+1, config.retries+1, err)
+		select {
+		case <-agent.ctx.Done():
+			return 0, agent.ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// upsertBatch embeds and writes the new and changed chunks of a batch
+func (agent *RagAgent) upsertBatch(batch []text.TextChunk) (int, error) {
+	records, err := agent.newRecords(batch, true)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := agent.embedRecords(records); err != nil {
+		return 0, err
+	}
+	if err := agent.store.Upsert(agent.ctx, records); err != nil {
+		return 0, err
+	}
+	agent.indexLexical(records)
+	return len(records), nil
+}
+
+// ingestCheckpoint is the file of the chunks ingested, one key per line
+type ingestCheckpoint struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	keys map[string]bool
+}
+
+// openIngestCheckpoint loads the keys of the checkpoint file, creating it if needed
+func openIngestCheckpoint(path string) (*ingestCheckpoint, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening checkpoint: %w", err)
+	}
+	checkpoint := &ingestCheckpoint{path: path, file: file, keys: map[string]bool{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// A line cut by a crash is only a key that does not match, its chunk is ingested again
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			checkpoint.keys[key] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// checkpointKey identifies a version of a chunk: its ID and the hash of its content and metadata
+func checkpointKey(chunk text.TextChunk) string {
+	return ChunkID(chunk) + " " + vectorstore.DocumentID(chunk.Content, chunk.Metadata)
+}
+
+func (c *ingestCheckpoint) has(chunk text.TextChunk) bool {
+	return c.keys[checkpointKey(chunk)]
+}
+
+// add appends the keys of a batch ingested
+func (c *ingestCheckpoint) add(batch []text.TextChunk) error {
+	var lines strings.Builder
+	for _, chunk := range batch {
+		lines.WriteString(checkpointKey(chunk) + "\n")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.WriteString(lines.String()); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (c *ingestCheckpoint) close() {
+	c.file.Close()
+}
+
+// remove deletes the checkpoint file, once all the chunks are ingested
+func (c *ingestCheckpoint) remove() {
+	c.file.Close()
+	os.Remove(c.path)
+}
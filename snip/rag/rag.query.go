@@ -0,0 +1,133 @@
+package rag
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// QueryKind is the way a query searched was generated from the query of the user
+type QueryKind string
+
+const (
+	// QueryOriginal is the query of the user
+	QueryOriginal QueryKind = "original"
+	// QueryMultiQuery is a paraphrase of the query, the results of all the paraphrases are merged
+	QueryMultiQuery QueryKind = "multi-query"
+	// QueryHyDE is a hypothetical answer to the query (Hypothetical Document Embeddings):
+	// an answer is closer to the chunks than a short question
+	QueryHyDE QueryKind = "hyde"
+	// QueryStepBack is a more generic question about the concepts behind the query
+	QueryStepBack QueryKind = "step-back"
+)
+
+// TransformedQuery is a query searched for the query of the user
+type TransformedQuery struct {
+	Text string    `json:"text"`
+	Kind QueryKind `json:"kind"`
+}
+
+// QueryTransformer rewrites the query of the user into the queries searched
+// The implementation with a chat model is LLMQueryTransformer
+type QueryTransformer interface {
+	// Transform returns the queries to search for the query of the user
+	Transform(ctx context.Context, query string) ([]TransformedQuery, error)
+}
+
+// WithQueryTransformer rewrites the queries of the searches with the transformers
+// (e.g. a multi-query and a step-back transformer): the results of all the queries are merged,
+// and each result records the query that found it (see TransformQuery to debug the queries)
+func WithQueryTransformer(transformers ...QueryTransformer) RagAgentOption {
+	return func(a *RagAgent) {
+		a.transformers = append(a.transformers, transformers...)
+	}
+}
+
+// WithoutQueryTransform skips the query transformers of the agent for a search
+func WithoutQueryTransform() SearchOption {
+	return func(o *SearchOptions) {
+		o.NoTransform = true
+	}
+}
+
+// TransformQuery returns the queries searched for the query of the user: the queries of the
+// transformers of the agent (without duplicates), or the query itself if the agent has none
+func (agent *RagAgent) TransformQuery(query string) ([]TransformedQuery, error) {
+	if len(agent.transformers) == 0 {
+		return []TransformedQuery{{Text: query, Kind: QueryOriginal}}, nil
+	}
+	queries := []TransformedQuery{}
+	seen := map[string]bool{}
+	for _, transformer := range agent.transformers {
+		transformed, err := transformer.Transform(agent.ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("error transforming query: %w", err)
+		}
+		for _, q := range transformed {
+			key := strings.ToLower(strings.TrimSpace(q.Text))
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			queries = append(queries, q)
+		}
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("error transforming query: no query generated")
+	}
+	for _, q := range queries {
+		agent.logger.Debug("🔀 %s query: %s", q.Kind, q.Text)
+	}
+	return queries, nil
+}
+
+// retrieveQueries returns the chunks of the queries of the transformers, merged: each chunk keeps
+// its best score, and the query that gave it
+func (agent *RagAgent) retrieveQueries(query string, options SearchOptions) ([]SearchResult, error) {
+	if len(agent.transformers) == 0 || options.NoTransform {
+		return agent.retrieve(query, options)
+	}
+	queries, err := agent.TransformQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	rankings := make([][]SearchResult, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for idx, q := range queries {
+		wg.Go(func() {
+			rankings[idx], errs[idx] = agent.retrieve(q.Text, options)
+		})
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	best := map[string]int{}
+	results := []SearchResult{}
+	for idx, ranking := range rankings {
+		for _, result := range ranking {
+			result.Query = queries[idx].Text
+			if i, ok := best[result.ID]; !ok {
+				best[result.ID] = len(results)
+				results = append(results, result)
+			} else if result.Score > results[i].Score {
+				results[i] = result
+			}
+		}
+	}
+	// The stable sort keeps the order of the queries for the same scores
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(results) > options.K {
+		results = results[:options.K]
+	}
+	return results, nil
+}
@@ -0,0 +1,183 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// DefaultQueryCount is the default number of paraphrases of a multi-query transformer
+const DefaultQueryCount = 3
+
+// Default system instructions of the LLMQueryTransformer, used when the AgentConfig has none
+// The multi-query instructions are formatted with the number of paraphrases
+var (
+	MultiQueryInstructions = `You rewrite the questions of the users into search queries for a documentation search engine.
+Write %d different versions of the question, using other words and the technical terms of the domain.
+Answer with one query per line, without numbering nor explanation.`
+
+	HyDEInstructions = `You write passages of technical documentation.
+Write a short passage (one paragraph) that answers the question, as it could appear in the documentation.
+Answer with the passage only, even if you are not sure of the answer.`
+
+	StepBackInstructions = `You help a search engine by asking step-back questions.
+Rewrite the question into a more generic question about the concepts or the principles behind it.
+Answer with the question only.`
+)
+
+// listMarkerPattern matches the numbering or the bullet at the start of a line
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// TextGenerator answers a prompt with a chat model and the system instructions system,
+// without conversation history: *chat.ChatAgent is a text generator (see chat.ChatAgent.GenerateText)
+type TextGenerator interface {
+	GetName() string
+	GenerateText(ctx context.Context, system, prompt string) (string, error)
+}
+
+// LLMQueryTransformer is a QueryTransformer generating the queries with a chat agent
+// The original query is searched too, unless disabled with WithOriginalQuery(false)
+type LLMQueryTransformer struct {
+	Name string
+	// SystemInstructions replace the default instructions of the kind (see WithTransformerInstructions)
+	SystemInstructions string
+
+	kind     QueryKind
+	count    int
+	original bool
+	logger   logger.Logger
+
+	// generate returns the answer of the model to the prompt
+	generate func(ctx context.Context, system, prompt string) (string, error)
+}
+
+// LLMQueryTransformerOption defines a functional option for configuring an LLMQueryTransformer
+type LLMQueryTransformerOption func(*LLMQueryTransformer)
+
+// WithQueryKind sets the queries generated: QueryMultiQuery (default), QueryHyDE or QueryStepBack
+func WithQueryKind(kind QueryKind) LLMQueryTransformerOption {
+	return func(t *LLMQueryTransformer) {
+		t.kind = kind
+	}
+}
+
+// WithQueryCount sets the number of paraphrases of a multi-query transformer (DefaultQueryCount by default)
+func WithQueryCount(count int) LLMQueryTransformerOption {
+	return func(t *LLMQueryTransformer) {
+		if count > 0 {
+			t.count = count
+		}
+	}
+}
+
+// WithOriginalQuery sets whether the original query is searched with the generated ones (true by default)
+// e.g. WithOriginalQuery(false) to only search the hypothetical answer of HyDE
+func WithOriginalQuery(original bool) LLMQueryTransformerOption {
+	return func(t *LLMQueryTransformer) {
+		t.original = original
+	}
+}
+
+// WithTransformerInstructions replaces the default system instructions of the kind
+func WithTransformerInstructions(instructions string) LLMQueryTransformerOption {
+	return func(t *LLMQueryTransformer) {
+		t.SystemInstructions = strings.TrimSpace(instructions)
+	}
+}
+
+// WithTransformerLogger sets a custom logger for the transformer
+func WithTransformerLogger(log logger.Logger) LLMQueryTransformerOption {
+	return func(t *LLMQueryTransformer) {
+		t.logger = log
+	}
+}
+
+// NewLLMQueryTransformer creates a query transformer generating the queries with the chat agent
+//
+//	chatAgent, err := chat.NewChatAgent(ctx, agents.AgentConfig{Name: "queries", ModelID: "ai/qwen2.5:1.5B-F16", EngineURL: engineURL}, models.ModelConfig{Temperature: 0.5})
+//	transformer, err := rag.NewLLMQueryTransformer(chatAgent, rag.WithQueryKind(rag.QueryHyDE))
+func NewLLMQueryTransformer(generator TextGenerator, opts ...LLMQueryTransformerOption) (*LLMQueryTransformer, error) {
+	if generator == nil {
+		return nil, fmt.Errorf("no chat agent to generate the queries")
+	}
+	transformer := &LLMQueryTransformer{
+		Name:     generator.GetName(),
+		kind:     QueryMultiQuery,
+		count:    DefaultQueryCount,
+		original: true,
+		logger:   logger.GetLoggerFromEnvWithPrefix(generator.GetName()),
+		generate: generator.GenerateText,
+	}
+	for _, opt := range opts {
+		opt(transformer)
+	}
+	switch transformer.kind {
+	case QueryMultiQuery, QueryHyDE, QueryStepBack:
+	default:
+		return nil, fmt.Errorf("unknown query kind %q", transformer.kind)
+	}
+	return transformer, nil
+}
+
+// Transform returns the original query (unless disabled) and the queries generated by the model
+func (t *LLMQueryTransformer) Transform(ctx context.Context, query string) ([]TransformedQuery, error) {
+	queries := []TransformedQuery{}
+	if t.original {
+		queries = append(queries, TransformedQuery{Text: query, Kind: QueryOriginal})
+	}
+
+	answer, err := t.generate(ctx, t.instructions(), "Question: "+query)
+	if err != nil {
+		return nil, fmt.Errorf("error generating %s queries: %w", t.kind, err)
+	}
+	generated := t.parse(answer)
+	if len(generated) == 0 {
+		return nil, fmt.Errorf("error generating %s queries: empty answer", t.kind)
+	}
+	for _, text := range generated {
+		t.logger.Debug("🔀 %s: %s", t.kind, text)
+		queries = append(queries, TransformedQuery{Text: text, Kind: t.kind})
+	}
+	return queries, nil
+}
+
+// instructions returns the system instructions of the transformer, or the default ones of its kind
+func (t *LLMQueryTransformer) instructions() string {
+	if t.SystemInstructions != "" {
+		return t.SystemInstructions
+	}
+	switch t.kind {
+	case QueryHyDE:
+		return HyDEInstructions
+	case QueryStepBack:
+		return StepBackInstructions
+	default:
+		return fmt.Sprintf(MultiQueryInstructions, t.count)
+	}
+}
+
+// parse returns the queries of the answer of the model: one per line for the multi-query
+// transformer (without their numbering), the whole answer for the others
+func (t *LLMQueryTransformer) parse(answer string) []string {
+	answer = strings.TrimSpace(answer)
+	if t.kind != QueryMultiQuery {
+		if answer == "" {
+			return nil
+		}
+		return []string{answer}
+	}
+	queries := []string{}
+	for line := range strings.Lines(answer) {
+		line = strings.Trim(listMarkerPattern.ReplaceAllString(line, ""), " \t\r\n\"'")
+		if line != "" {
+			queries = append(queries, line)
+		}
+		if len(queries) == t.count {
+			break
+		}
+	}
+	return queries
+}
@@ -0,0 +1,65 @@
+package rag
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// DefaultRerankFactor is the default number of candidates reranked per result
+const DefaultRerankFactor = 4
+
+// Reranker scores the relevance of documents to a query
+// The implementations are an LLM (see NewLLMReranker), or a dedicated rerank endpoint such as llama.cpp /rerank
+type Reranker interface {
+	// Rerank returns the relevance score of each document to the query, in the order of the documents
+	// (the higher the more relevant)
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// WithoutRerank skips the reranker of the agent for a search
+func WithoutRerank() SearchOption {
+	return func(o *SearchOptions) {
+		o.NoRerank = true
+	}
+}
+
+// WithRerankCandidates sets the number of candidates fetched and reranked for a search
+// (K * DefaultRerankFactor by default)
+func WithRerankCandidates(candidates int) SearchOption {
+	return func(o *SearchOptions) {
+		o.RerankCandidates = candidates
+	}
+}
+
+// rerank reranks the candidates of a search with the reranker of the agent, and keeps the k best
+func (agent *RagAgent) rerank(query string, candidates []SearchResult, k int) ([]SearchResult, error) {
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+	documents := make([]string, len(candidates))
+	for idx, candidate := range candidates {
+		documents[idx] = candidate.Content
+	}
+
+	scores, err := agent.reranker.Rerank(agent.ctx, query, documents)
+	if err != nil {
+		return nil, fmt.Errorf("error reranking results: %w", err)
+	}
+	if len(scores) != len(candidates) {
+		return nil, fmt.Errorf("error reranking results: %d scores for %d results", len(scores), len(candidates))
+	}
+	for idx := range candidates {
+		candidates[idx].RerankScore = scores[idx]
+	}
+
+	// The stable sort keeps the retrieval order of the candidates with the same rerank score
+	slices.SortStableFunc(candidates, func(a, b SearchResult) int {
+		return cmp.Compare(b.RerankScore, a.RerankScore)
+	})
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	return candidates, nil
+}
@@ -0,0 +1,194 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chat"
+	"github.com/snipwise/snip-sdk/snip/models"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// RerankMode is the way an LLMReranker scores the documents
+type RerankMode string
+
+const (
+	// RerankPointwise asks the model the relevance of each document to the query, one request per document
+	RerankPointwise RerankMode = "pointwise"
+	// RerankListwise asks the model to order all the documents, in a single request
+	RerankListwise RerankMode = "listwise"
+)
+
+// DefaultRerankWorkers is the default number of pointwise requests sent in parallel
+const DefaultRerankWorkers = 4
+
+// Default system instructions of the LLMReranker, used when the AgentConfig has none
+var (
+	PointwiseRerankInstructions = `You are a search relevance judge.
+Rate how relevant the document is to the query, from 0 (irrelevant) to 10 (it fully answers the query).
+Answer with the number only.`
+
+	ListwiseRerankInstructions = `You are a search relevance judge.
+Order the documents from the most to the least relevant to the query.
+Answer with the numbers of the documents only, separated by commas, e.g.: 3, 1, 2`
+)
+
+// numberPattern matches the numbers of the answers of the model
+var numberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// LLMReranker is a Reranker scoring the documents with a chat model
+// The scores are between 0 and 1: the pointwise rating divided by 10, or 1 - rank / number of documents
+type LLMReranker struct {
+	Name               string
+	ModelID            string
+	SystemInstructions string
+
+	mode    RerankMode
+	workers int
+	logger  logger.Logger
+
+	// generate returns the answer of the model to the prompt
+	generate func(ctx context.Context, system, prompt string) (string, error)
+}
+
+// LLMRerankerOption defines a functional option for configuring an LLMReranker
+type LLMRerankerOption func(*LLMReranker)
+
+// WithRerankMode sets the way the documents are scored (RerankPointwise by default)
+func WithRerankMode(mode RerankMode) LLMRerankerOption {
+	return func(r *LLMReranker) {
+		r.mode = mode
+	}
+}
+
+// WithRerankWorkers sets the number of pointwise requests sent in parallel (DefaultRerankWorkers by default)
+func WithRerankWorkers(workers int) LLMRerankerOption {
+	return func(r *LLMReranker) {
+		if workers > 0 {
+			r.workers = workers
+		}
+	}
+}
+
+// WithRerankerLogger sets a custom logger for the reranker
+func WithRerankerLogger(log logger.Logger) LLMRerankerOption {
+	return func(r *LLMReranker) {
+		r.logger = log
+	}
+}
+
+// NewLLMReranker creates a reranker using the chat model of the config
+// The SystemInstructions of the config replace the default instructions of the mode
+func NewLLMReranker(ctx context.Context, rerankerConfig agents.AgentConfig, modelConfig models.ModelConfig, opts ...LLMRerankerOption) (*LLMReranker, error) {
+	// The chat agent runs the model, its instructions and its history are not used (see chat.ChatAgent.GenerateText)
+	chatAgent, err := chat.NewChatAgent(ctx, rerankerConfig, modelConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	reranker := &LLMReranker{
+		Name:               rerankerConfig.Name,
+		ModelID:            rerankerConfig.ModelID,
+		SystemInstructions: rerankerConfig.SystemInstructions,
+		mode:               RerankPointwise,
+		workers:            DefaultRerankWorkers,
+		logger:             logger.GetLoggerFromEnvWithPrefix(rerankerConfig.Name),
+		generate:           chatAgent.GenerateText,
+	}
+	for _, opt := range opts {
+		opt(reranker)
+	}
+	if reranker.mode != RerankPointwise && reranker.mode != RerankListwise {
+		return nil, fmt.Errorf("unknown rerank mode %q", reranker.mode)
+	}
+	return reranker, nil
+}
+
+// Rerank returns the relevance score of each document to the query, between 0 and 1
+func (r *LLMReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if r.mode == RerankListwise {
+		return r.rerankListwise(ctx, query, documents)
+	}
+	return r.rerankPointwise(ctx, query, documents)
+}
+
+// instructions returns the system instructions of the reranker, or the default ones
+func (r *LLMReranker) instructions(defaultInstructions string) string {
+	if r.SystemInstructions != "" {
+		return r.SystemInstructions
+	}
+	return defaultInstructions
+}
+
+// rerankPointwise rates each document from 0 to 10
+// An answer without a rating gives a score of 0 rather than failing the search
+func (r *LLMReranker) rerankPointwise(ctx context.Context, query string, documents []string) ([]float64, error) {
+	system := r.instructions(PointwiseRerankInstructions)
+	scores := make([]float64, len(documents))
+	errs := make([]error, len(documents))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, r.workers)
+	for idx, document := range documents {
+		slots <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-slots }()
+
+			prompt := fmt.Sprintf("Query: %s\n\nDocument:\n%s\n\nRelevance (0-10):", query, document)
+			answer, err := r.generate(ctx, system, prompt)
+			if err != nil {
+				errs[idx] = err
+				return
+			}
+			rating, err := strconv.ParseFloat(numberPattern.FindString(answer), 64)
+			if err != nil {
+				r.logger.Warn("⚠️ No rating in the answer %q", answer)
+				return
+			}
+			scores[idx] = min(rating, 10) / 10
+		})
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("error rating document: %w", err)
+		}
+	}
+	return scores, nil
+}
+
+// rerankListwise asks the order of the documents, the documents missing from the answer score 0
+func (r *LLMReranker) rerankListwise(ctx context.Context, query string, documents []string) ([]float64, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Query: %s\n\n", query)
+	for idx, document := range documents {
+		fmt.Fprintf(&prompt, "[%d] %s\n\n", idx+1, document)
+	}
+	prompt.WriteString("Ranking:")
+
+	answer, err := r.generate(ctx, r.instructions(ListwiseRerankInstructions), prompt.String())
+	if err != nil {
+		return nil, fmt.Errorf("error ranking documents: %w", err)
+	}
+
+	scores := make([]float64, len(documents))
+	ranked := map[int]bool{}
+	for _, match := range numberPattern.FindAllString(answer, -1) {
+		number, err := strconv.Atoi(match)
+		if err != nil || number < 1 || number > len(documents) || ranked[number] {
+			continue
+		}
+		scores[number-1] = 1 - float64(len(ranked))/float64(len(documents))
+		ranked[number] = true
+	}
+	if len(ranked) < len(documents) {
+		r.logger.Warn("⚠️ The ranking %q misses %d documents", answer, len(documents)-len(ranked))
+	}
+	return scores, nil
+}
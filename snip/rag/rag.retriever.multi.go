@@ -0,0 +1,321 @@
+package rag
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// Searcher is a source of a MultiRetriever: RagAgent, remote.RemoteRagAgent and every snip.AIRagAgent
+// are searchers, and NewStoreSearcher makes a searcher of a vector store
+type Searcher interface {
+	GetName() string
+	Search(query string, opts ...SearchOption) ([]SearchResult, error)
+}
+
+// ContextSearcher is a searcher whose searches can be cancelled: a MultiRetriever cancels the search
+// of such a source when it times out (remote.RemoteRagAgent and the searchers of NewStoreSearcher are context searchers)
+type ContextSearcher interface {
+	Searcher
+	SearchContext(ctx context.Context, query string, opts ...SearchOption) ([]SearchResult, error)
+}
+
+// ScoreNormalization is the way the scores of each source are put on the same scale before being merged
+type ScoreNormalization string
+
+const (
+	// NormalizeMax divides the scores by the best score of the source
+	NormalizeMax ScoreNormalization = "max"
+	// NormalizeMinMax maps the scores of the source from [worst, best] to [0, 1]
+	NormalizeMinMax ScoreNormalization = "minmax"
+	// NormalizeRank only uses the ranks, as the reciprocal rank fusion: (RRFK + 1) / (RRFK + rank)
+	NormalizeRank ScoreNormalization = "rank"
+)
+
+// RetrieverSource is a searcher queried by a MultiRetriever
+type RetrieverSource struct {
+	Searcher Searcher
+	// Name identifies the source in the results (the name of the searcher if empty)
+	Name string
+	// Weight multiplies the normalized scores of the source (1 if zero)
+	Weight float64
+	// Timeout drops the results of the source if it takes longer (the timeout of the retriever if zero)
+	// The search of a ContextSearcher is cancelled at the timeout; the search of another searcher (e.g. a RagAgent)
+	// cannot be cancelled and runs to its end in the background, so that a slow source queried
+	// again and again piles up searches
+	Timeout time.Duration
+}
+
+// MultiSearchResult is a chunk found by one or several sources of a MultiRetriever
+type MultiSearchResult struct {
+	SearchResult
+	// Source is the source with the best normalized score for the chunk
+	Source string `json:"source"`
+	// SourceScores are the scores of the chunk in each source that found it, by source name
+	SourceScores map[string]float64 `json:"source_scores"`
+}
+
+// MultiRetriever searches several RAG agents or stores in parallel, and merges their results
+// The scores of each source are normalized and weighted, then the scores of a chunk found by several sources
+// (same content) are added: a chunk first in all the sources scores 1
+type MultiRetriever struct {
+	sources       []RetrieverSource
+	timeout       time.Duration
+	normalization ScoreNormalization
+	logger        logger.Logger
+}
+
+// MultiRetrieverOption configures a MultiRetriever
+type MultiRetrieverOption func(*MultiRetriever)
+
+// WithRetrieverTimeout sets the timeout of the sources without their own timeout (no timeout by default)
+func WithRetrieverTimeout(timeout time.Duration) MultiRetrieverOption {
+	return func(r *MultiRetriever) {
+		r.timeout = timeout
+	}
+}
+
+// WithScoreNormalization sets how the scores of the sources are normalized (NormalizeMax by default)
+func WithScoreNormalization(normalization ScoreNormalization) MultiRetrieverOption {
+	return func(r *MultiRetriever) {
+		r.normalization = normalization
+	}
+}
+
+// WithRetrieverLogger sets the logger of the retriever, which warns about the failed sources
+func WithRetrieverLogger(log logger.Logger) MultiRetrieverOption {
+	return func(r *MultiRetriever) {
+		r.logger = log
+	}
+}
+
+// NewMultiRetriever returns a retriever of the sources
+//
+//	retriever, err := rag.NewMultiRetriever([]rag.RetrieverSource{
+//		{Searcher: docsAgent, Weight: 2},
+//		{Searcher: remoteAgent, Timeout: time.Second},
+//	})
+//	results, err := retriever.Search("how to install?", rag.WithTopK(5))
+func NewMultiRetriever(sources []RetrieverSource, opts ...MultiRetrieverOption) (*MultiRetriever, error) {
+	retriever := &MultiRetriever{
+		normalization: NormalizeMax,
+		logger:        &logger.NoOpLogger{},
+	}
+	for _, opt := range opts {
+		opt(retriever)
+	}
+	switch retriever.normalization {
+	case NormalizeMax, NormalizeMinMax, NormalizeRank:
+	default:
+		return nil, fmt.Errorf("unknown score normalization %q", retriever.normalization)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no source to retrieve from")
+	}
+
+	names := map[string]bool{}
+	for _, source := range sources {
+		if source.Searcher == nil {
+			return nil, fmt.Errorf("source %q has no searcher", source.Name)
+		}
+		if source.Name == "" {
+			source.Name = source.Searcher.GetName()
+		}
+		if names[source.Name] {
+			return nil, fmt.Errorf("duplicate source name %q", source.Name)
+		}
+		names[source.Name] = true
+		if source.Weight < 0 {
+			return nil, fmt.Errorf("negative weight for the source %q", source.Name)
+		}
+		if source.Weight == 0 {
+			source.Weight = 1
+		}
+		if source.Timeout == 0 {
+			source.Timeout = retriever.timeout
+		}
+		retriever.sources = append(retriever.sources, source)
+	}
+	return retriever, nil
+}
+
+// Search queries all the sources in parallel with the options, and returns the K best merged results
+// MinScore applies to the scores of each source, before normalization
+// The sources that fail or time out are skipped: an error is only returned if all the sources fail
+func (r *MultiRetriever) Search(query string, opts ...SearchOption) ([]MultiSearchResult, error) {
+	options := NewSearchOptions(opts...)
+
+	rankings := make([][]SearchResult, len(r.sources))
+	errs := make([]error, len(r.sources))
+	var wg sync.WaitGroup
+	for idx, source := range r.sources {
+		wg.Go(func() {
+			rankings[idx], errs[idx] = searchSource(source, query, options)
+			if errs[idx] != nil {
+				r.logger.Warn("⚠️ Source %s skipped: %v", source.Name, errs[idx])
+			}
+		})
+	}
+	wg.Wait()
+
+	merged := map[string]*MultiSearchResult{}
+	order := []string{}
+	bestScores := map[string]float64{}
+	var totalWeight float64
+	for idx, source := range r.sources {
+		if errs[idx] != nil {
+			continue
+		}
+		totalWeight += source.Weight
+		// sourceScores are the best normalized scores of the chunks in the source:
+		// a chunk found twice by the same source only counts once
+		sourceScores := map[string]float64{}
+		for rank, result := range rankings[idx] {
+			score := source.Weight * r.normalize(rankings[idx], rank)
+			previous, found := sourceScores[result.Content]
+			if found && previous >= score {
+				continue
+			}
+			sourceScores[result.Content] = score
+			// The chunks of several sources are the same chunk if they have the same content
+			m, ok := merged[result.Content]
+			if !ok {
+				m = &MultiSearchResult{SearchResult: result, SourceScores: map[string]float64{}}
+				m.Score = 0
+				merged[result.Content] = m
+				order = append(order, result.Content)
+			}
+			m.Score += score - previous
+			m.SourceScores[source.Name] = result.Score
+			if !ok || score > bestScores[result.Content] {
+				m.Source = source.Name
+				bestScores[result.Content] = score
+			}
+		}
+	}
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("error searching the sources: %w", errors.Join(errs...))
+	}
+
+	results := make([]MultiSearchResult, 0, len(merged))
+	for _, content := range order {
+		m := merged[content]
+		m.Score /= totalWeight
+		results = append(results, *m)
+	}
+	// The stable sort keeps the order of the sources for the same scores
+	slices.SortStableFunc(results, func(a, b MultiSearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(results) > options.K {
+		results = results[:options.K]
+	}
+	return results, nil
+}
+
+// searchSource searches a source, dropping its results if it takes longer than its timeout
+// The search of a ContextSearcher is cancelled at the timeout
+func searchSource(source RetrieverSource, query string, options SearchOptions) ([]SearchResult, error) {
+	if source.Timeout <= 0 {
+		return source.Searcher.Search(query, WithSearchOptions(options))
+	}
+	if searcher, ok := source.Searcher.(ContextSearcher); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), source.Timeout)
+		defer cancel()
+		results, err := searcher.SearchContext(ctx, query, WithSearchOptions(options))
+		if err != nil && ctx.Err() != nil {
+			return nil, fmt.Errorf("no response after %s: %w", source.Timeout, err)
+		}
+		return results, err
+	}
+	type response struct {
+		results []SearchResult
+		err     error
+	}
+	done := make(chan response, 1)
+	go func() {
+		results, err := source.Searcher.Search(query, WithSearchOptions(options))
+		done <- response{results, err}
+	}()
+	select {
+	case res := <-done:
+		return res.results, res.err
+	case <-time.After(source.Timeout):
+		return nil, fmt.Errorf("no response after %s", source.Timeout)
+	}
+}
+
+// normalize returns the score of the result at rank in the ranking of a source, between 0 and 1
+func (r *MultiRetriever) normalize(ranking []SearchResult, rank int) float64 {
+	switch r.normalization {
+	case NormalizeRank:
+		return float64(RRFK+1) / float64(RRFK+rank+1)
+	case NormalizeMinMax:
+		best, worst := ranking[0].Score, ranking[len(ranking)-1].Score
+		if best == worst {
+			return 1
+		}
+		return (ranking[rank].Score - worst) / (best - worst)
+	default:
+		if best := ranking[0].Score; best > 0 {
+			return max(ranking[rank].Score, 0) / best
+		}
+		return 0
+	}
+}
+
+// storeSearcher searches a vector store with the embeddings of an embedder
+type storeSearcher struct {
+	ctx      context.Context
+	name     string
+	store    vectorstore.VectorStore
+	embedder ai.Embedder
+}
+
+// NewStoreSearcher returns a searcher of a store, the queries being embedded with the embedder of the store
+func NewStoreSearcher(ctx context.Context, name string, store vectorstore.VectorStore, embedder ai.Embedder) Searcher {
+	return &storeSearcher{ctx: ctx, name: name, store: store, embedder: embedder}
+}
+
+func (s *storeSearcher) GetName() string {
+	return s.name
+}
+
+// Search returns the chunks of the store most similar to the query (vector search only)
+func (s *storeSearcher) Search(query string, opts ...SearchOption) ([]SearchResult, error) {
+	return s.SearchContext(s.ctx, query, opts...)
+}
+
+// SearchContext is Search, cancelled when ctx is done
+func (s *storeSearcher) SearchContext(ctx context.Context, query string, opts ...SearchOption) ([]SearchResult, error) {
+	options := NewSearchOptions(opts...)
+	response, err := s.embedder.Embed(ctx, &ai.EmbedRequest{Input: []*ai.Document{ai.DocumentFromText(query, nil)}})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	if len(response.Embeddings) == 0 {
+		return nil, fmt.Errorf("error embedding query: no embedding returned")
+	}
+	matches, err := s.store.Search(ctx, vectorstore.Query{
+		Vector:   response.Embeddings[0].Embedding,
+		K:        options.K,
+		MinScore: options.MinScore,
+		Filter:   options.Filter,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving documents: %w", err)
+	}
+	results := make([]SearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, SearchResult{ID: match.ID, Content: match.Content, Metadata: match.Metadata, Score: match.Score})
+	}
+	return results, nil
+}
@@ -0,0 +1,211 @@
+package rag
+
+import (
+	"fmt"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+)
+
+// DefaultSearchK is the default number of results of a search (the default of the localvec retriever)
+const DefaultSearchK = 3
+
+// SearchResult is a chunk of the store matching a query
+type SearchResult struct {
+	ID       string         `json:"id"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// Score is the cosine similarity between the query and the chunk (1 is the best),
+	// the BM25 score for a lexical search, or the fused score for a hybrid search
+	Score float64 `json:"score"`
+	// VectorScore and LexicalScore are the scores of the chunk in the two rankings of a hybrid search
+	// (zero if the chunk is not in the ranking)
+	VectorScore  float64 `json:"vector_score,omitempty"`
+	LexicalScore float64 `json:"lexical_score,omitempty"`
+	// RerankScore is the relevance score given by the reranker of the agent, if any (see WithReranker)
+	// The results are then sorted by rerank score, Score is still the score of the retrieval
+	RerankScore float64 `json:"rerank_score,omitempty"`
+	// Query is the query that found the chunk, when the query of the user is transformed (see WithQueryTransformer)
+	Query string `json:"query,omitempty"`
+}
+
+// SearchOptions are the settings of a search, set with the SearchOption functions
+// It is exported so that the options can be sent to a remote RAG agent
+type SearchOptions struct {
+	// K is the maximum number of results (DefaultSearchK if zero or negative)
+	K int `json:"k,omitempty"`
+	// MinScore drops the results with a lower score
+	MinScore float64 `json:"min_score,omitempty"`
+	// Filter keeps the chunks whose metadata have all these values
+	Filter map[string]any `json:"filter,omitempty"`
+	// Mode is the kind of search (SearchVector by default)
+	Mode SearchMode `json:"mode,omitempty"`
+	// Fusion merges the rankings of a hybrid search (FusionRRF by default)
+	Fusion FusionMethod `json:"fusion,omitempty"`
+	// VectorWeight and LexicalWeight are the weights of the rankings of a hybrid search (1 and 1 if both are zero)
+	VectorWeight  float64 `json:"vector_weight,omitempty"`
+	LexicalWeight float64 `json:"lexical_weight,omitempty"`
+	// NoRerank skips the reranker of the agent
+	NoRerank bool `json:"no_rerank,omitempty"`
+	// RerankCandidates is the number of candidates fetched and reranked (K * DefaultRerankFactor by default)
+	RerankCandidates int `json:"rerank_candidates,omitempty"`
+	// NoTransform searches the query of the user only, without the query transformers of the agent
+	NoTransform bool `json:"no_transform,omitempty"`
+}
+
+// SearchOption defines a functional option for a search
+type SearchOption func(*SearchOptions)
+
+// WithTopK sets the maximum number of results
+func WithTopK(k int) SearchOption {
+	return func(o *SearchOptions) {
+		o.K = k
+	}
+}
+
+// WithMinScore drops the results with a score lower than minScore
+func WithMinScore(minScore float64) SearchOption {
+	return func(o *SearchOptions) {
+		o.MinScore = minScore
+	}
+}
+
+// WithMetadataFilter only searches the chunks whose metadata have all the values of filter
+// e.g. map[string]any{"category": "marine"}
+func WithMetadataFilter(filter map[string]any) SearchOption {
+	return func(o *SearchOptions) {
+		o.Filter = filter
+	}
+}
+
+// WithSearchOptions sets all the settings of a search at once (e.g. the options received by a server)
+func WithSearchOptions(options SearchOptions) SearchOption {
+	return func(o *SearchOptions) {
+		*o = options
+	}
+}
+
+// WithSearchMode sets the kind of search (the lexical and hybrid searches need WithLexicalIndex)
+func WithSearchMode(mode SearchMode) SearchOption {
+	return func(o *SearchOptions) {
+		o.Mode = mode
+	}
+}
+
+// WithHybridSearch merges the vector and the lexical (BM25) rankings, with these weights
+// e.g. WithHybridSearch(1, 2) to favor the exact identifiers and error codes
+func WithHybridSearch(vectorWeight, lexicalWeight float64) SearchOption {
+	return func(o *SearchOptions) {
+		o.Mode = SearchHybrid
+		o.VectorWeight = vectorWeight
+		o.LexicalWeight = lexicalWeight
+	}
+}
+
+// WithFusion sets how the rankings of a hybrid search are merged
+func WithFusion(fusion FusionMethod) SearchOption {
+	return func(o *SearchOptions) {
+		o.Fusion = fusion
+	}
+}
+
+// NewSearchOptions applies the options to the default settings
+func NewSearchOptions(opts ...SearchOption) SearchOptions {
+	options := SearchOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.K <= 0 {
+		options.K = DefaultSearchK
+	}
+	if options.Mode == "" {
+		options.Mode = SearchVector
+	}
+	if options.Fusion == "" {
+		options.Fusion = FusionRRF
+	}
+	if options.VectorWeight == 0 && options.LexicalWeight == 0 {
+		options.VectorWeight, options.LexicalWeight = 1, 1
+	}
+	return options
+}
+
+// MatchMetadata reports whether metadata has all the values of filter
+// Numbers are compared by value, so that a filter decoded from JSON (float64) matches an int
+func MatchMetadata(metadata, filter map[string]any) bool {
+	return vectorstore.MatchMetadata(metadata, filter)
+}
+
+// Search returns the chunks of the store most similar to the query, with their score and metadata
+// The results are sorted by descending score
+func (agent *RagAgent) Search(query string, opts ...SearchOption) ([]SearchResult, error) {
+	if !agent.IsStoreInitialized() {
+		return nil, fmt.Errorf("document store is not initialized")
+	}
+	options := NewSearchOptions(opts...)
+
+	if agent.reranker == nil || options.NoRerank {
+		return agent.retrieveQueries(query, options)
+	}
+	// Over-fetch the candidates, then keep the K best for the reranker
+	k := options.K
+	options.K = options.RerankCandidates
+	if options.K <= 0 {
+		options.K = k * DefaultRerankFactor
+	}
+	candidates, err := agent.retrieveQueries(query, options)
+	if err != nil {
+		return nil, err
+	}
+	return agent.rerank(query, candidates, k)
+}
+
+// retrieve returns the chunks of the search mode of the options
+func (agent *RagAgent) retrieve(query string, options SearchOptions) ([]SearchResult, error) {
+	switch options.Mode {
+	case SearchVector:
+		return agent.vectorSearch(query, options)
+	case SearchLexical:
+		return agent.lexicalSearch(query, options)
+	case SearchHybrid:
+		return agent.hybridSearch(query, options)
+	}
+	return nil, fmt.Errorf("unknown search mode %q", options.Mode)
+}
+
+// vectorSearch returns the chunks whose embedding is the most similar to the embedding of the query
+func (agent *RagAgent) vectorSearch(query string, options SearchOptions) ([]SearchResult, error) {
+	queryEmbedding, err := agent.embed(query)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := agent.store.Search(agent.ctx, vectorstore.Query{
+		Vector:   queryEmbedding,
+		K:        options.K,
+		MinScore: options.MinScore,
+		Filter:   options.Filter,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error searching the store: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, SearchResult{
+			ID:       match.ID,
+			Content:  match.Content,
+			Metadata: match.Metadata,
+			Score:    match.Score,
+		})
+	}
+	return results, nil
+}
+
+// embed computes the embedding of a text with the embedder of the store
+func (agent *RagAgent) embed(content string) ([]float32, error) {
+	embeddings, err := agent.embedTexts([]string{content})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	return embeddings[0], nil
+}
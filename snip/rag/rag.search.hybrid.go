@@ -0,0 +1,164 @@
+package rag
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// SearchMode is the kind of search
+type SearchMode string
+
+const (
+	// SearchVector ranks the chunks by the cosine similarity of their embedding with the embedding of the query
+	SearchVector SearchMode = "vector"
+	// SearchLexical ranks the chunks by their BM25 score for the terms of the query
+	SearchLexical SearchMode = "lexical"
+	// SearchHybrid merges the vector and the lexical rankings
+	SearchHybrid SearchMode = "hybrid"
+)
+
+// FusionMethod is the way the rankings of a hybrid search are merged
+type FusionMethod string
+
+const (
+	// FusionRRF is the reciprocal rank fusion: each ranking adds weight / (RRFK + rank) to the score of a chunk
+	// It only uses the ranks, so it does not depend on the scales of the scores
+	FusionRRF FusionMethod = "rrf"
+	// FusionWeighted adds the weighted scores: the cosine similarity, and the BM25 score divided by the best one
+	FusionWeighted FusionMethod = "weighted"
+)
+
+const (
+	// RRFK is the rank constant of the reciprocal rank fusion, it lowers the weight of the first ranks
+	RRFK = 60
+	// hybridCandidates is the number of chunks of each ranking merged by a hybrid search, per result
+	hybridCandidates = 4
+)
+
+// errNoLexicalIndex is returned by the lexical and hybrid searches of an agent without lexical index
+var errNoLexicalIndex = fmt.Errorf("the agent has no lexical index (see WithLexicalIndex)")
+
+// lexicalSearch returns the chunks with the best BM25 score for the query
+func (agent *RagAgent) lexicalSearch(query string, options SearchOptions) ([]SearchResult, error) {
+	if agent.lexical == nil {
+		return nil, errNoLexicalIndex
+	}
+	hits := agent.lexical.Search(query, options.K, options.Filter)
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if hit.Score < options.MinScore {
+			break
+		}
+		results = append(results, SearchResult{ID: hit.ID, Score: hit.Score, LexicalScore: hit.Score})
+	}
+	if err := agent.fillResults(results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// hybridSearch merges the vector and the lexical rankings of the query
+// The fused scores are normalized so that a chunk first in both rankings (RRF),
+// or with the best scores in both (weighted), scores 1. MinScore applies to the fused score
+func (agent *RagAgent) hybridSearch(query string, options SearchOptions) ([]SearchResult, error) {
+	if agent.lexical == nil {
+		return nil, errNoLexicalIndex
+	}
+	if options.VectorWeight < 0 || options.LexicalWeight < 0 {
+		return nil, fmt.Errorf("negative hybrid search weight")
+	}
+	candidates := options
+	candidates.K = options.K * hybridCandidates
+	candidates.MinScore = 0
+
+	vectorResults, err := agent.vectorSearch(query, candidates)
+	if err != nil {
+		return nil, err
+	}
+	hits := agent.lexical.Search(query, candidates.K, options.Filter)
+
+	fused := map[string]*SearchResult{}
+	result := func(id string) *SearchResult {
+		if _, ok := fused[id]; !ok {
+			fused[id] = &SearchResult{ID: id}
+		}
+		return fused[id]
+	}
+	for rank, vectorResult := range vectorResults {
+		r := result(vectorResult.ID)
+		r.Content, r.Metadata, r.VectorScore = vectorResult.Content, vectorResult.Metadata, vectorResult.Score
+		if options.Fusion == FusionRRF {
+			r.Score += options.VectorWeight / float64(RRFK+rank+1)
+		} else {
+			r.Score += options.VectorWeight * max(vectorResult.Score, 0)
+		}
+	}
+	for rank, hit := range hits {
+		r := result(hit.ID)
+		r.LexicalScore = hit.Score
+		if options.Fusion == FusionRRF {
+			r.Score += options.LexicalWeight / float64(RRFK+rank+1)
+		} else {
+			r.Score += options.LexicalWeight * hit.Score / hits[0].Score
+		}
+	}
+
+	var norm float64
+	switch options.Fusion {
+	case FusionRRF:
+		norm = float64(RRFK+1) / (options.VectorWeight + options.LexicalWeight)
+	case FusionWeighted:
+		norm = 1 / (options.VectorWeight + options.LexicalWeight)
+	default:
+		return nil, fmt.Errorf("unknown fusion method %q", options.Fusion)
+	}
+
+	results := make([]SearchResult, 0, len(fused))
+	for _, r := range fused {
+		r.Score *= norm
+		if r.Score >= options.MinScore {
+			results = append(results, *r)
+		}
+	}
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(results) > options.K {
+		results = results[:options.K]
+	}
+	if err := agent.fillResults(results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// fillResults reads the content and the metadata of the results found by the lexical index only
+func (agent *RagAgent) fillResults(results []SearchResult) error {
+	ids := []string{}
+	for _, r := range results {
+		if r.Content == "" {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	records, err := agent.store.Get(agent.ctx, ids)
+	if err != nil {
+		return fmt.Errorf("error reading the store: %w", err)
+	}
+	for _, record := range records {
+		for idx := range results {
+			if results[idx].ID == record.ID {
+				results[idx].Content = record.Content
+				results[idx].Metadata = record.Metadata
+			}
+		}
+	}
+	return nil
+}
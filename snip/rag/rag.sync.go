@@ -0,0 +1,363 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/loaders"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/files"
+)
+
+// SyncReport is the result of a synchronization of a directory
+type SyncReport struct {
+	// Added, Changed and Removed are the files (relative to the directory) added, changed and removed since the last synchronization
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+	// Failed are the files that could not be loaded or ingested, synchronized again the next time
+	Failed []string `json:"failed,omitempty"`
+	// Unchanged is the number of files unchanged
+	Unchanged int `json:"unchanged"`
+	// Written is the number of chunks embedded and written to the store
+	Written int `json:"written"`
+	// Deleted is the number of chunks removed from the store
+	Deleted int `json:"deleted"`
+}
+
+// HasChanges reports whether files were added, changed, removed or failed
+func (r SyncReport) HasChanges() bool {
+	return len(r.Added)+len(r.Changed)+len(r.Removed)+len(r.Failed) > 0
+}
+
+// String returns a one-line summary of the synchronization
+func (r SyncReport) String() string {
+	summary := fmt.Sprintf("%d added, %d changed, %d removed, %d unchanged files (%d chunks written, %d deleted)",
+		len(r.Added), len(r.Changed), len(r.Removed), r.Unchanged, r.Written, r.Deleted)
+	if len(r.Failed) > 0 {
+		summary += fmt.Sprintf(", %d failed", len(r.Failed))
+	}
+	return summary
+}
+
+// syncConfig is the configuration of a synchronization
+type syncConfig struct {
+	ext           string
+	manifest      string
+	splitter      func(path string) text.Splitter
+	ingestOptions []IngestOption
+	interval      time.Duration
+	polling       bool
+	onSync        func(SyncReport, error)
+	// ignored tells if a path is a file of the agent, never synchronized (see agentFiles)
+	ignored func(path string) bool
+}
+
+// SyncOption configures SyncDirectory and WatchDirectory
+type SyncOption func(*syncConfig)
+
+// WithSyncExtension only synchronizes the files with this extension (".*" by default: all the files with a loader)
+func WithSyncExtension(ext string) SyncOption {
+	return func(c *syncConfig) {
+		c.ext = ext
+	}
+}
+
+// WithSyncManifest sets the manifest file of the synchronization
+// (<store path>/<store name>.sync.json by default)
+func WithSyncManifest(path string) SyncOption {
+	return func(c *syncConfig) {
+		c.manifest = path
+	}
+}
+
+// WithSyncSplitter splits all the documents with the splitter (see DefaultSyncSplitter), nil keeps one chunk per document
+func WithSyncSplitter(splitter text.Splitter) SyncOption {
+	return func(c *syncConfig) {
+		c.splitter = func(string) text.Splitter { return splitter }
+	}
+}
+
+// WithSyncIngestOptions sets the options of the ingestion of the chunks of each file (see IngestTextChunks)
+func WithSyncIngestOptions(opts ...IngestOption) SyncOption {
+	return func(c *syncConfig) {
+		c.ingestOptions = append(c.ingestOptions, opts...)
+	}
+}
+
+// DefaultSyncSplitter returns the splitter of a file: by sections for Markdown and HTML, by declarations for Go,
+// none for the records of CSV, JSON and JSONL files, and text.RecursiveSplitter for the other files
+func DefaultSyncSplitter(path string) text.Splitter {
+	loader, _ := loaders.LoaderFor(path)
+	switch loader.(type) {
+	case loaders.MarkdownLoader, loaders.HTMLLoader:
+		return text.NewMarkdownSplitter()
+	case loaders.GoLoader:
+		splitter, _ := text.NewCodeSplitter("go")
+		return splitter
+	case loaders.CSVLoader, loaders.JSONLoader, loaders.JSONLinesLoader:
+		return nil
+	default:
+		return text.NewRecursiveSplitter()
+	}
+}
+
+// newSyncConfig returns the configuration of the options
+func (agent *RagAgent) newSyncConfig(opts []SyncOption) syncConfig {
+	config := syncConfig{
+		ext:      ".*",
+		manifest: filepath.Join(agent.storePath, agent.storeName+".sync.json"),
+		splitter: DefaultSyncSplitter,
+		interval: DefaultWatchInterval,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	config.ignored = agent.agentFiles(config.manifest)
+	return config
+}
+
+// agentFiles returns a function telling if a path is a file written by the agent: the manifest,
+// the store (see vectorstore.FileStore), the embedding model of the store, or one of their temporary files
+// They are skipped when they are kept in the synchronized directory, so that the agent does not ingest
+// its own files, nor watch its own writes
+func (agent *RagAgent) agentFiles(manifest string) func(path string) bool {
+	paths := []string{manifest, agent.embeddingInfoPath()}
+	if store, ok := agent.store.(vectorstore.FileStore); ok {
+		paths = append(paths, store.Path())
+	}
+	absPaths := []string{}
+	for _, path := range paths {
+		if absPath, err := filepath.Abs(path); path != "" && err == nil {
+			absPaths = append(absPaths, absPath)
+		}
+	}
+	return func(path string) bool {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return false
+		}
+		for _, absPath := range absPaths {
+			if path == absPath || path == absPath+".tmp" || strings.HasPrefix(path, absPath+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SyncDirectory synchronizes the store with the files of a directory and its subdirectories
+// (the files without loader are skipped, see loaders.Loaders)
+// The manifest records the hash and the chunks of each file: only the files added or changed since
+// the last synchronization are loaded, chunked and embedded, and the chunks of the removed files are deleted
+func (agent *RagAgent) SyncDirectory(dirPath string, opts ...SyncOption) (SyncReport, error) {
+	return agent.syncDirectory(agent.ctx, dirPath, agent.newSyncConfig(opts))
+}
+
+// syncDirectory synchronizes the directory, and stops between two files when ctx is done
+func (agent *RagAgent) syncDirectory(ctx context.Context, dirPath string, config syncConfig) (SyncReport, error) {
+	report := SyncReport{Added: []string{}, Changed: []string{}, Removed: []string{}}
+	if !agent.IsStoreInitialized() {
+		return report, fmt.Errorf("document store is not initialized")
+	}
+	manifest, err := loadSyncManifest(config.manifest)
+	if err != nil {
+		return report, err
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	// dirty is set when the manifest changes, the manifest file is only written then
+	dirty := false
+	_, err = files.ForEachFile(dirPath, config.ext, func(path string) error {
+		if config.ignored(path) {
+			return nil
+		}
+		if _, ok := loaders.LoaderFor(path); !ok {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		entry, known := manifest.Files[rel]
+		updated, err := agent.syncFile(path, entry, config)
+		switch {
+		case err != nil:
+			agent.logger.Warn("⚠️ Error synchronizing %s: %v", rel, err)
+			report.Failed = append(report.Failed, rel)
+			errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+			return nil
+		case !known:
+			report.Added = append(report.Added, rel)
+		case updated.Hash != entry.Hash:
+			report.Changed = append(report.Changed, rel)
+		default:
+			report.Unchanged++
+		}
+		report.Written += updated.written
+		report.Deleted += updated.deleted
+		if !known || updated.Hash != entry.Hash || updated.Size != entry.Size || !updated.Modified.Equal(entry.Modified) {
+			dirty = true
+		}
+		manifest.Files[rel] = updated
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// The chunks of the files removed are deleted, unless the walk stopped before seeing all the files
+	if err == nil {
+		for _, rel := range slices.Sorted(maps.Keys(manifest.Files)) {
+			if seen[rel] {
+				continue
+			}
+			deleted, err := agent.DeleteByID(manifest.Files[rel].Chunks...)
+			if err != nil {
+				report.Failed = append(report.Failed, rel)
+				errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+				continue
+			}
+			report.Removed = append(report.Removed, rel)
+			report.Deleted += deleted
+			delete(manifest.Files, rel)
+			dirty = true
+		}
+	}
+
+	if dirty {
+		if err := manifest.save(config.manifest); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return report, fmt.Errorf("error synchronizing %s: %w", dirPath, errors.Join(errs...))
+	}
+	agent.logger.Info("🔄 %s synchronized: %s", dirPath, report)
+	return report, nil
+}
+
+// syncFile ingests a file if it changed since its entry in the manifest, and returns its new entry
+func (agent *RagAgent) syncFile(path string, entry syncManifestFile, config syncConfig) (syncManifestFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return entry, err
+	}
+	// Same size and modification time: the file is not read again
+	if entry.Hash != "" && entry.Size == info.Size() && entry.Modified.Equal(info.ModTime()) {
+		return entry, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	hash := sha256.Sum256(content)
+	updated := syncManifestFile{
+		Hash:     hex.EncodeToString(hash[:]),
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		Chunks:   entry.Chunks,
+	}
+	if updated.Hash == entry.Hash {
+		return updated, nil
+	}
+
+	docs, err := loaders.Load(path)
+	if err != nil {
+		return entry, err
+	}
+	chunks := loaders.Split(docs, config.splitter(path))
+	progress, err := agent.IngestTextChunks(chunks, config.ingestOptions...)
+	if err != nil {
+		return entry, err
+	}
+	updated.written = progress.Written
+
+	updated.Chunks = make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		updated.Chunks = append(updated.Chunks, ChunkID(chunk))
+	}
+	stale := slices.DeleteFunc(slices.Clone(entry.Chunks), func(id string) bool { return slices.Contains(updated.Chunks, id) })
+	if len(stale) > 0 {
+		if updated.deleted, err = agent.DeleteByID(stale...); err != nil {
+			return entry, err
+		}
+	}
+	return updated, nil
+}
+
+// syncManifest is the state of the files of a directory at the last synchronization
+type syncManifest struct {
+	// Files are the files by path relative to the directory (with slashes)
+	Files map[string]syncManifestFile `json:"files"`
+}
+
+type syncManifestFile struct {
+	// Hash is the SHA-256 of the content of the file
+	Hash     string    `json:"hash"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// Chunks are the IDs of the chunks of the file in the store
+	Chunks []string `json:"chunks"`
+
+	written, deleted int
+}
+
+// loadSyncManifest reads the manifest file, empty if it does not exist
+func loadSyncManifest(path string) (*syncManifest, error) {
+	manifest := &syncManifest{Files: map[string]syncManifestFile{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading sync manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid sync manifest %s: %w", path, err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]syncManifestFile{}
+	}
+	return manifest, nil
+}
+
+// save writes the manifest to a temporary file renamed over the manifest file,
+// so that an interrupted write does not lose the previous manifest
+func (m *syncManifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error writing sync manifest: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing sync manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing sync manifest: %w", err)
+	}
+	return nil
+}
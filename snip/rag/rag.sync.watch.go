@@ -0,0 +1,96 @@
+package rag
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWatchInterval is the default delay between two scans of the directory when polling,
+// and the delay to wait for the end of a burst of changes when notified by the system
+const DefaultWatchInterval = 2 * time.Second
+
+// WithWatchInterval sets the interval of WatchDirectory (DefaultWatchInterval by default)
+func WithWatchInterval(interval time.Duration) SyncOption {
+	return func(c *syncConfig) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// WithPolling makes WatchDirectory scan the directory at each interval, even where
+// the changes can be notified by the system (inotify on Linux)
+func WithPolling() SyncOption {
+	return func(c *syncConfig) {
+		c.polling = true
+	}
+}
+
+// WithSyncCallback sets a function called by WatchDirectory after each synchronization
+// with changes or errors, e.g. to log the report
+func WithSyncCallback(onSync func(SyncReport, error)) SyncOption {
+	return func(c *syncConfig) {
+		c.onSync = onSync
+	}
+}
+
+// directoryWatcher waits for the changes of the files of a directory
+type directoryWatcher interface {
+	// wait returns when files may have changed, or with an error when ctx is done
+	wait(ctx context.Context) error
+	close()
+}
+
+// pollingWatcher reports a possible change at each interval
+type pollingWatcher struct {
+	interval time.Duration
+}
+
+func (w pollingWatcher) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(w.interval):
+		return nil
+	}
+}
+
+func (pollingWatcher) close() {}
+
+// WatchDirectory synchronizes the directory (see SyncDirectory), then synchronizes it again
+// after each change until ctx is done, and returns the error of ctx
+// The changes are notified by inotify on Linux, and detected by scanning the directory
+// at each interval on the other systems (see WithPolling): the scans only read the files
+// whose size or modification time changed
+// A synchronization in progress stops between two files when ctx is done
+func (agent *RagAgent) WatchDirectory(ctx context.Context, dirPath string, opts ...SyncOption) error {
+	config := agent.newSyncConfig(opts)
+	synchronize := func() {
+		report, err := agent.syncDirectory(ctx, dirPath, config)
+		if err != nil {
+			agent.logger.Warn("⚠️ %v", err)
+		}
+		if config.onSync != nil && (err != nil || report.HasChanges()) {
+			config.onSync(report, err)
+		}
+	}
+
+	var watcher directoryWatcher = pollingWatcher{interval: config.interval}
+	if !config.polling {
+		notifier, err := newNotifyWatcher(dirPath, config.interval, config.ignored)
+		if err != nil {
+			agent.logger.Debug("🔄 Polling %s every %s: %v", dirPath, config.interval, err)
+		} else {
+			watcher = notifier
+		}
+	}
+	defer watcher.close()
+
+	synchronize()
+	for {
+		if err := watcher.wait(ctx); err != nil {
+			return err
+		}
+		synchronize()
+	}
+}
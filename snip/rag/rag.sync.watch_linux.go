@@ -0,0 +1,124 @@
+//go:build linux
+
+package rag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// inotifyMask are the events of the files that change the documents of a directory
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// notifyWatcher waits for the inotify events of a directory and its subdirectories
+type notifyWatcher struct {
+	// fd is the inotify descriptor, read through file (file.Fd() would make it blocking)
+	fd       int
+	file     *os.File
+	dirs     map[int32]string
+	events   chan struct{}
+	interval time.Duration
+	// ignored tells if the events of a path are ignored (e.g. the writes of the manifest)
+	ignored func(path string) bool
+}
+
+// newNotifyWatcher watches the directory and its subdirectories with inotify
+// A change is reported once no other event happened during interval, so that a burst of writes
+// (e.g. a git checkout) only triggers one synchronization
+// The events of the paths ignored are skipped, so that the writes of the agent do not trigger a synchronization
+func newNotifyWatcher(dirPath string, interval time.Duration, ignored func(path string) bool) (directoryWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify is not available: %w", err)
+	}
+	// A non-blocking file is read through the runtime poller: closing it stops the reads
+	w := &notifyWatcher{
+		fd:       fd,
+		file:     os.NewFile(uintptr(fd), "inotify"),
+		dirs:     map[int32]string{},
+		events:   make(chan struct{}, 1),
+		interval: interval,
+		ignored:  ignored,
+	}
+	if err := w.addTree(dirPath); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	go w.read()
+	return w, nil
+}
+
+// addTree watches a directory and its subdirectories
+func (w *notifyWatcher) addTree(dirPath string) error {
+	return filepath.WalkDir(dirPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		if w.ignored(path) {
+			return filepath.SkipDir
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("error watching %s: %w", path, err)
+		}
+		w.dirs[int32(wd)] = path
+		return nil
+	})
+}
+
+// read signals the events until the file is closed, watching the new subdirectories
+func (w *notifyWatcher) read() {
+	buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buffer)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buffer[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			dir, ok := w.dirs[event.Wd]
+			if ok && name != "" && w.ignored(filepath.Join(dir, name)) {
+				continue
+			}
+			if ok && event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				// A directory that disappears before being watched is only seen at the next synchronization
+				_ = w.addTree(filepath.Join(dir, name))
+			}
+			select {
+			case w.events <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (w *notifyWatcher) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.events:
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.events:
+		case <-time.After(w.interval):
+			return nil
+		}
+	}
+}
+
+func (w *notifyWatcher) close() {
+	w.file.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package rag
+
+import (
+	"errors"
+	"time"
+)
+
+// newNotifyWatcher is only available on Linux, WatchDirectory polls the directory on the other systems
+func newNotifyWatcher(dirPath string, interval time.Duration, ignored func(path string) bool) (directoryWatcher, error) {
+	return nil, errors.New("file notifications are only supported on Linux")
+}
@@ -43,11 +43,11 @@ func TestRagAgentKind(t *testing.T) {
 func TestRagAgentIsStoreInitialized(t *testing.T) {
 	t.Run("uninitialized store", func(t *testing.T) {
 		ragAgent := &RagAgent{
-			docStore: nil,
+			store: nil,
 		}
 
 		if ragAgent.IsStoreInitialized() {
-			t.Error("IsStoreInitialized() = true, want false for nil docStore")
+			t.Error("IsStoreInitialized() = true, want false for nil store")
 		}
 	})
 
@@ -60,14 +60,14 @@ func TestRagAgentIsStoreInitialized(t *testing.T) {
 // ============================================================================
 
 func TestRagAgentGetNumberOfDocuments(t *testing.T) {
-	t.Run("nil docStore", func(t *testing.T) {
+	t.Run("nil store", func(t *testing.T) {
 		ragAgent := &RagAgent{
-			docStore: nil,
+			store: nil,
 		}
 
 		count := ragAgent.GetNumberOfDocuments()
 		if count != 0 {
-			t.Errorf("GetNumberOfDocuments() = %d, want 0 for nil docStore", count)
+			t.Errorf("GetNumberOfDocuments() = %d, want 0 for nil store", count)
 		}
 	})
 
@@ -79,14 +79,14 @@ func TestRagAgentGetNumberOfDocuments(t *testing.T) {
 // ============================================================================
 
 func TestRagAgentGetInfo(t *testing.T) {
-	t.Run("basic info without docStore", func(t *testing.T) {
+	t.Run("basic info without store", func(t *testing.T) {
 		ragAgent := &RagAgent{
 			Name:               "info-test-agent",
 			ModelID:            "test-embedding-model",
 			embeddingDimension: 384,
 			storeName:          "test-store",
 			storePath:          "./test-data",
-			docStore:           nil, // No documents
+			store:              nil, // No documents
 		}
 
 		info, err := ragAgent.GetInfo()
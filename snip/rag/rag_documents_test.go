@@ -0,0 +1,124 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// newDocumentsTestAgent returns an agent with an empty store, and the list of the texts embedded
+func newDocumentsTestAgent() (*RagAgent, *[]string) {
+	embedded := []string{}
+	embedder := ai.NewEmbedder("counting-embedder", nil, func(ctx context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		response := &ai.EmbedResponse{}
+		for _, doc := range req.Input {
+			embedded = append(embedded, doc.Content[0].Text)
+			response.Embeddings = append(response.Embeddings, &ai.Embedding{Embedding: []float32{float32(len(doc.Content[0].Text)), 1}})
+		}
+		return response, nil
+	})
+	return &RagAgent{
+		ctx:      context.Background(),
+		store:    vectorstore.NewMemoryStore(),
+		logger:   &logger.NoOpLogger{},
+		embedder: embedder,
+	}, &embedded
+}
+
+func TestRagAgentUpsertTextChunks(t *testing.T) {
+	agent, embedded := newDocumentsTestAgent()
+
+	chunks := []text.TextChunk{
+		{ID: "intro-1", Content: "Snip agents", Metadata: map[string]any{"source": "intro.md"}},
+		{ID: "intro-2", Content: "RAG agents", Metadata: map[string]any{"source": "intro.md"}},
+		{Content: "Tools agents", Metadata: map[string]any{"source": "tools.md"}},
+	}
+	written, err := agent.UpsertTextChunks(chunks)
+	if err != nil || written != 3 {
+		t.Fatalf("UpsertTextChunks() = %d, %v, want 3", written, err)
+	}
+
+	t.Run("unchanged chunks are skipped", func(t *testing.T) {
+		*embedded = nil
+		written, err := agent.UpsertTextChunks(chunks)
+		if err != nil || written != 0 {
+			t.Errorf("UpsertTextChunks() = %d, %v, want 0", written, err)
+		}
+		if len(*embedded) != 0 {
+			t.Errorf("embedded %v, want no embedding", *embedded)
+		}
+	})
+
+	t.Run("changed chunks are replaced", func(t *testing.T) {
+		*embedded = nil
+		changed := []text.TextChunk{chunks[0], {ID: "intro-2", Content: "RAG agents search documents", Metadata: map[string]any{"source": "intro.md"}}}
+		written, err := agent.UpsertTextChunks(changed)
+		if err != nil || written != 1 || len(*embedded) != 1 {
+			t.Errorf("UpsertTextChunks() = %d, %v, embedded %v, want 1", written, err, *embedded)
+		}
+		records, _ := agent.store.Get(context.Background(), []string{"intro-2"})
+		if len(records) != 1 || records[0].Content != "RAG agents search documents" {
+			t.Errorf("Get() = %+v, want the new content", records)
+		}
+		if n := agent.GetNumberOfDocuments(); n != 3 {
+			t.Errorf("GetNumberOfDocuments() = %d, want 3", n)
+		}
+	})
+
+	t.Run("content hash ID", func(t *testing.T) {
+		id := ChunkID(chunks[2])
+		if id != vectorstore.DocumentID("Tools agents", map[string]any{"source": "tools.md"}) {
+			t.Errorf("ChunkID() = %s, want the hash of the content and metadata", id)
+		}
+		if records, _ := agent.store.Get(context.Background(), []string{id}); len(records) != 1 {
+			t.Errorf("Get(%s) = %+v, want the chunk without ID", id, records)
+		}
+	})
+}
+
+func TestRagAgentAddTextChunksSkipsExisting(t *testing.T) {
+	agent, embedded := newDocumentsTestAgent()
+
+	chunk := text.TextChunk{ID: "a", Content: "Snip agents"}
+	agent.AddTextChunksToStore([]text.TextChunk{chunk, chunk})
+	count, err := agent.AddTextChunksToStore([]text.TextChunk{{ID: "a", Content: "Changed"}, {ID: "b", Content: "RAG agents"}})
+	if err != nil || count != 2 {
+		t.Fatalf("AddTextChunksToStore() = %d, %v, want 2", count, err)
+	}
+	// The existing chunk is neither embedded again nor replaced
+	if len(*embedded) != 2 || (*embedded)[1] != "RAG agents" {
+		t.Errorf("embedded %v, want Snip agents and RAG agents", *embedded)
+	}
+	records, _ := agent.store.Get(context.Background(), []string{"a"})
+	if len(records) != 1 || records[0].Content != "Snip agents" {
+		t.Errorf("Get() = %+v, want the first content", records)
+	}
+}
+
+func TestRagAgentDelete(t *testing.T) {
+	agent, _ := newDocumentsTestAgent()
+	agent.UpsertTextChunks([]text.TextChunk{
+		{ID: "intro-1", Content: "Snip agents", Metadata: map[string]any{"source": "intro.md"}},
+		{ID: "intro-2", Content: "RAG agents", Metadata: map[string]any{"source": "intro.md"}},
+		{ID: "tools-1", Content: "Tools agents", Metadata: map[string]any{"source": "tools.md"}},
+	})
+
+	deleted, err := agent.DeleteByMetadata(map[string]any{"source": "intro.md"})
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteByMetadata() = %d, %v, want 2", deleted, err)
+	}
+	deleted, err = agent.DeleteByID("tools-1", "unknown")
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteByID() = %d, %v, want 1", deleted, err)
+	}
+	if n := agent.GetNumberOfDocuments(); n != 0 {
+		t.Errorf("GetNumberOfDocuments() = %d, want 0", n)
+	}
+	if _, err := agent.DeleteByMetadata(nil); err == nil {
+		t.Error("DeleteByMetadata() with an empty filter: expected an error")
+	}
+}
@@ -0,0 +1,217 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+func TestEmbeddingCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewEmbeddingCache(dir)
+	if err != nil {
+		t.Fatalf("NewEmbeddingCache() unexpected error: %v", err)
+	}
+	if err := cache.Put("model-a", "hello", []float32{0.1, 0.2}); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	cache.Put("model-b", "hello", []float32{0.3, 0.4})
+	cache.Put("model-a", "hello", []float32{9, 9})
+	cache.Close()
+
+	reopened, err := NewEmbeddingCache(dir)
+	if err != nil {
+		t.Fatalf("NewEmbeddingCache() unexpected error: %v", err)
+	}
+	defer reopened.Close()
+	if embedding, ok := reopened.Get("model-a", "hello"); !ok || !slices.Equal(embedding, []float32{0.1, 0.2}) {
+		t.Errorf("Get(model-a) = %v, %v, want the first embedding of model-a", embedding, ok)
+	}
+	if embedding, _ := reopened.Get("model-b", "hello"); !slices.Equal(embedding, []float32{0.3, 0.4}) {
+		t.Errorf("Get(model-b) = %v, want the embedding of model-b", embedding)
+	}
+	if _, ok := reopened.Get("model-a", "other"); ok || reopened.Len() != 2 {
+		t.Errorf("Get(other) = %v with %d embeddings, want a miss and 2 embeddings", ok, reopened.Len())
+	}
+}
+
+func TestRagAgentEmbeddingCache(t *testing.T) {
+	cache, err := NewEmbeddingCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	agent, embedded := newDocumentsTestAgent()
+	agent.ModelID = "embedder"
+	agent.embeddingCache = cache
+	chunks := []text.TextChunk{{Content: "Snip agents"}, {Content: "RAG agents"}}
+	if _, err := agent.AddTextChunksToStore(chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another agent and store sharing the cache: only the new text is embedded
+	other, otherEmbedded := newDocumentsTestAgent()
+	other.ModelID = "embedder"
+	other.embeddingCache = cache
+	if _, err := other.AddTextChunksToStore(append(chunks, text.TextChunk{Content: "Tools agents"})); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(*otherEmbedded, []string{"Tools agents"}) {
+		t.Errorf("embedded %q, want only the text not cached", *otherEmbedded)
+	}
+	if _, err := other.Search("Snip agents"); err != nil || len(*otherEmbedded) != 1 {
+		t.Errorf("Search() = %v, embedded %q, want the query from the cache", err, *otherEmbedded)
+	}
+
+	// Another model does not use the embeddings of the first one
+	*embedded = nil
+	agent.ModelID = "other-embedder"
+	agent.embedTexts([]string{"Snip agents"})
+	if len(*embedded) != 1 {
+		t.Errorf("embedded %q, want the text embedded by the other model", *embedded)
+	}
+}
+
+func TestRagAgentCheckEmbeddingModel(t *testing.T) {
+	store, storePath := vectorstore.NewMemoryStore(), t.TempDir()
+	open := func(modelID string) (*RagAgent, *[]string, error) {
+		agent, embedded := newDocumentsTestAgent()
+		agent.store, agent.ModelID = store, modelID
+		agent.storeName, agent.storePath = "docs", storePath
+		return agent, embedded, agent.initEmbeddingModel()
+	}
+	agent, embedded, err := open("embedder")
+	if err != nil || agent.embeddingDimension != 2 || !slices.Equal(*embedded, []string{embeddingProbe}) {
+		t.Fatalf("initEmbeddingModel() of a new store = %v, dimension %d, embedded %q, want the embedder probed", err, agent.embeddingDimension, *embedded)
+	}
+	agent.AddTextChunksToStore([]text.TextChunk{{Content: "Snip agents"}})
+
+	// Reopening the store uses the saved model, checked with the first embeddings
+	agent, embedded, err = open("embedder")
+	if err != nil || agent.embeddingDimension != 2 || len(*embedded) != 0 {
+		t.Fatalf("initEmbeddingModel() of the store = %v, dimension %d, embedded %q, want the saved model without embedding", err, agent.embeddingDimension, *embedded)
+	}
+	if _, err := agent.AddTextChunksToStore([]text.TextChunk{{Content: "RAG agents"}}); err != nil || !slices.Equal(*embedded, []string{embeddingProbe, "RAG agents"}) {
+		t.Errorf("AddTextChunksToStore() = %v, embedded %q, want the probe checked with the chunk", err, *embedded)
+	}
+	*embedded = nil
+	agent.AddTextChunksToStore([]text.TextChunk{{Content: "Tools agents"}})
+	if !slices.Equal(*embedded, []string{"Tools agents"}) {
+		t.Errorf("embedded %q, want the model checked once", *embedded)
+	}
+
+	if _, _, err := open("other-embedder"); !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Errorf("initEmbeddingModel() with another model = %v, want ErrEmbeddingModelMismatch", err)
+	}
+
+	// Another model served under the same ID
+	agent, _, err = open("embedder")
+	if err != nil {
+		t.Fatalf("initEmbeddingModel() unexpected error: %v", err)
+	}
+	agent.embedder = fakeEmbedder(map[string][]float32{embeddingProbe: {-11, 1}, "Eval agents": {1, 1}})
+	if _, err := agent.AddTextChunksToStore([]text.TextChunk{{Content: "Eval agents"}}); !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Errorf("AddTextChunksToStore() with another fingerprint = %v, want ErrEmbeddingModelMismatch", err)
+	}
+	if err := agent.CheckEmbeddingModel(); !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Errorf("CheckEmbeddingModel() with another fingerprint = %v, want ErrEmbeddingModelMismatch", err)
+	}
+	agent.embedder = fakeEmbedder(map[string][]float32{embeddingProbe: {1, 0, 0}})
+	if err := agent.CheckEmbeddingModel(); !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Errorf("CheckEmbeddingModel() with another dimension = %v, want ErrEmbeddingModelMismatch", err)
+	}
+
+	// An empty store takes the model of the agent
+	empty, _ := newDocumentsTestAgent()
+	empty.ModelID, empty.storeName, empty.storePath = "other-embedder", "docs", t.TempDir()
+	if err := empty.initEmbeddingModel(); err != nil {
+		t.Errorf("initEmbeddingModel() of an empty store: unexpected error: %v", err)
+	}
+}
+
+func TestRagAgentEmbeddingModelWithoutFile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("store built before the model was saved", func(t *testing.T) {
+		for _, tc := range []struct {
+			name      string
+			dimension int
+			wantErr   bool
+		}{
+			{"same dimension", 2, false},
+			{"another dimension", 3, true},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				agent, _ := newDocumentsTestAgent()
+				agent.ModelID, agent.storeName, agent.storePath = "embedder", "docs", t.TempDir()
+				if _, err := agent.store.Add(ctx, []vectorstore.Record{{ID: "a", Content: "Snip agents", Embedding: make([]float32, tc.dimension)}}); err != nil {
+					t.Fatal(err)
+				}
+
+				err := agent.initEmbeddingModel()
+				if got := errors.Is(err, ErrEmbeddingModelMismatch); got != tc.wantErr {
+					t.Fatalf("initEmbeddingModel() = %v, want mismatch %v", err, tc.wantErr)
+				}
+				if _, statErr := os.Stat(agent.embeddingInfoPath()); (statErr == nil) == tc.wantErr {
+					t.Errorf("embedding model file saved = %v, want %v", statErr == nil, !tc.wantErr)
+				}
+			})
+		}
+	})
+
+	t.Run("store set with WithVectorStore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "index.json")
+		store, err := vectorstore.NewHNSWStore(vectorstore.WithHNSWFile(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		agent, _ := newDocumentsTestAgent()
+		agent.store, agent.ModelID = store, "embedder"
+		if err := agent.initEmbeddingModel(); err != nil {
+			t.Fatalf("initEmbeddingModel() unexpected error: %v", err)
+		}
+		if agent.embeddingInfoPath() != path+".embedding.json" {
+			t.Errorf("embeddingInfoPath() = %q, want the file next to the store", agent.embeddingInfoPath())
+		}
+		if _, err := agent.AddTextChunksToStore([]text.TextChunk{{Content: "Snip agents"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		other, _ := newDocumentsTestAgent()
+		other.store, other.ModelID = store, "other-embedder"
+		if err := other.initEmbeddingModel(); !errors.Is(err, ErrEmbeddingModelMismatch) {
+			t.Errorf("initEmbeddingModel() with another model = %v, want ErrEmbeddingModelMismatch", err)
+		}
+	})
+}
+
+func TestRagAgentEmbeddingCacheReplacedModel(t *testing.T) {
+	cache, err := NewEmbeddingCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	agent, _ := newDocumentsTestAgent()
+	agent.ModelID, agent.embeddingCache = "embedder", cache
+	agent.initEmbeddingModel()
+	agent.embedTexts([]string{"Snip agents"})
+
+	// Another model under the same ID, for a new store: the probe does not come from the cache
+	other, _ := newDocumentsTestAgent()
+	other.ModelID, other.embeddingCache = "embedder", cache
+	other.embedder = fakeEmbedder(map[string][]float32{embeddingProbe: {-11, 1}, "Snip agents": {0, 1}})
+	if err := other.initEmbeddingModel(); err != nil || other.embeddingInfo.Fingerprint[0] != -11 {
+		t.Fatalf("initEmbeddingModel() = %v with %v, want the fingerprint of the embedder", err, other.embeddingInfo.Fingerprint)
+	}
+	if embeddings, err := other.embedTexts([]string{"Snip agents"}); err != nil || !slices.Equal(embeddings[0], []float32{0, 1}) {
+		t.Errorf("embedTexts() = %v, %v, want the embedding of the new model", embeddings, err)
+	}
+}
@@ -0,0 +1,128 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// newIngestTestAgent returns an agent with an empty store, whose embedder calls fail while fail returns true
+func newIngestTestAgent(fail func(texts []string) bool) (*RagAgent, *int) {
+	var mu sync.Mutex
+	calls := 0
+	embedder := ai.NewEmbedder("flaky-embedder", nil, func(ctx context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		texts := []string{}
+		for _, doc := range req.Input {
+			texts = append(texts, doc.Content[0].Text)
+		}
+		mu.Lock()
+		calls++
+		failed := fail(texts)
+		mu.Unlock()
+		if failed {
+			return nil, fmt.Errorf("embedding engine unavailable")
+		}
+		response := &ai.EmbedResponse{}
+		for range texts {
+			response.Embeddings = append(response.Embeddings, &ai.Embedding{Embedding: []float32{1, 0}})
+		}
+		return response, nil
+	})
+	return &RagAgent{
+		ctx:      context.Background(),
+		store:    vectorstore.NewMemoryStore(),
+		logger:   &logger.NoOpLogger{},
+		embedder: embedder,
+	}, &calls
+}
+
+func ingestTestChunks(n int) []text.TextChunk {
+	chunks := make([]text.TextChunk, n)
+	for i := range chunks {
+		chunks[i] = text.TextChunk{Content: fmt.Sprintf("chunk %d", i), Metadata: map[string]any{"source": "test.md"}}
+	}
+	return chunks
+}
+
+func TestRagAgentIngestTextChunks(t *testing.T) {
+	agent, calls := newIngestTestAgent(func([]string) bool { return false })
+
+	var reports []IngestProgress
+	progress, err := agent.IngestTextChunks(ingestTestChunks(25),
+		WithBatchSize(10), WithWorkers(3),
+		WithProgress(func(p IngestProgress) { reports = append(reports, p) }),
+	)
+	if err != nil {
+		t.Fatalf("IngestTextChunks() unexpected error: %v", err)
+	}
+	if progress != (IngestProgress{Total: 25, Processed: 25, Written: 25}) {
+		t.Errorf("IngestTextChunks() = %+v, want 25 chunks written", progress)
+	}
+	if *calls != 3 {
+		t.Errorf("%d embedding requests, want 3 batches", *calls)
+	}
+	if len(reports) != 3 || reports[2].Processed != 25 {
+		t.Errorf("progress reports = %+v, want 3 reports ending with 25 chunks", reports)
+	}
+	if n := agent.GetNumberOfDocuments(); n != 25 {
+		t.Errorf("GetNumberOfDocuments() = %d, want 25", n)
+	}
+
+	// A second ingestion skips the unchanged chunks
+	progress, _ = agent.IngestTextChunks(ingestTestChunks(25), WithBatchSize(10))
+	if progress.Skipped != 25 || progress.Written != 0 || *calls != 3 {
+		t.Errorf("IngestTextChunks() again = %+v after %d requests, want 25 skipped without embedding", progress, *calls)
+	}
+}
+
+func TestRagAgentIngestTextChunksRetries(t *testing.T) {
+	failures := 2
+	agent, calls := newIngestTestAgent(func([]string) bool {
+		failures--
+		return failures >= 0
+	})
+
+	progress, err := agent.IngestTextChunks(ingestTestChunks(5), WithRetries(2, 0))
+	if err != nil || progress.Written != 5 {
+		t.Fatalf("IngestTextChunks() = %+v, %v, want 5 chunks written after the retries", progress, err)
+	}
+	if *calls != 3 {
+		t.Errorf("%d embedding requests, want 3", *calls)
+	}
+}
+
+func TestRagAgentIngestTextChunksCheckpoint(t *testing.T) {
+	checkpoint := filepath.Join(t.TempDir(), "ingest.checkpoint")
+	chunks := ingestTestChunks(6)
+
+	// The batch of chunk 4 fails, the other batches are ingested and recorded in the checkpoint
+	agent, _ := newIngestTestAgent(func(texts []string) bool { return texts[0] == "chunk 4" })
+	progress, err := agent.IngestTextChunks(chunks, WithBatchSize(2), WithWorkers(2), WithRetries(1, 0), WithCheckpoint(checkpoint))
+	if err == nil {
+		t.Fatal("IngestTextChunks() expected the error of the failed batch")
+	}
+	if progress.Written != 4 || progress.Failed != 2 || progress.Processed != 6 {
+		t.Errorf("IngestTextChunks() = %+v, want 4 written and 2 failed", progress)
+	}
+
+	// After a crash, the store is lost but the checkpoint lets the ingestion continue
+	agent, calls := newIngestTestAgent(func([]string) bool { return false })
+	progress, err = agent.IngestTextChunks(chunks, WithBatchSize(2), WithCheckpoint(checkpoint))
+	if err != nil {
+		t.Fatalf("IngestTextChunks() unexpected error: %v", err)
+	}
+	if progress.Skipped != 4 || progress.Written != 2 || *calls != 1 {
+		t.Errorf("IngestTextChunks() = %+v after %d requests, want the failed batch only", progress, *calls)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint not removed after a complete ingestion: %v", err)
+	}
+}
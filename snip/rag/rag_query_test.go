@@ -0,0 +1,138 @@
+package rag
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// fakeTransformer returns the same queries for all the queries
+type fakeTransformer struct {
+	queries []TransformedQuery
+}
+
+func (t fakeTransformer) Transform(ctx context.Context, query string) ([]TransformedQuery, error) {
+	return append([]TransformedQuery{{Text: query, Kind: QueryOriginal}}, t.queries...), nil
+}
+
+func TestRagAgentSearchWithQueryTransformer(t *testing.T) {
+	agent := newSearchTestAgent(t)
+	agent.embedder = fakeEmbedder(map[string][]float32{
+		"Which animals swim?": {1, 0, 0},
+		"What flies?":         {0, 0, 1},
+	})
+	agent.transformers = []QueryTransformer{
+		fakeTransformer{queries: []TransformedQuery{{Text: "What flies?", Kind: QueryMultiQuery}}},
+		fakeTransformer{queries: []TransformedQuery{{Text: "what flies? ", Kind: QueryStepBack}}},
+	}
+
+	queries, err := agent.TransformQuery("Which animals swim?")
+	if err != nil || len(queries) != 2 || queries[1].Kind != QueryMultiQuery {
+		t.Fatalf("TransformQuery() = %+v, %v, want the original and the paraphrase without duplicates", queries, err)
+	}
+
+	results, err := agent.Search("Which animals swim?")
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	ids := []string{}
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	if !slices.Equal(ids, []string{"dolphins", "eagles", "whales"}) {
+		t.Errorf("Search() = %v, want the best results of both queries", ids)
+	}
+	if results[1].Query != "What flies?" || results[1].Score != 1 || results[0].Query != "Which animals swim?" {
+		t.Errorf("Search() = %+v, want the query that found each chunk", results)
+	}
+
+	results, _ = agent.Search("Which animals swim?", WithTopK(2), WithoutQueryTransform())
+	if len(results) != 2 || results[1].ID != "whales" || results[1].Query != "" {
+		t.Errorf("Search() without transform = %+v, want dolphins and whales", results)
+	}
+}
+
+// fakeGenerator is a TextGenerator answering all the prompts with answer
+type fakeGenerator struct {
+	answer  string
+	prompts *[]string
+}
+
+func (g fakeGenerator) GetName() string { return "queries" }
+
+func (g fakeGenerator) GenerateText(ctx context.Context, system, prompt string) (string, error) {
+	*g.prompts = append(*g.prompts, system, prompt)
+	return g.answer, nil
+}
+
+// newTestLLMQueryTransformer returns a transformer whose model answers with answer
+func newTestLLMQueryTransformer(kind QueryKind, answer string, prompts *[]string) *LLMQueryTransformer {
+	transformer, err := NewLLMQueryTransformer(fakeGenerator{answer: answer, prompts: prompts},
+		WithQueryKind(kind), WithQueryCount(2), WithTransformerLogger(&logger.NoOpLogger{}))
+	if err != nil {
+		panic(err)
+	}
+	return transformer
+}
+
+func TestLLMQueryTransformer(t *testing.T) {
+	var prompts []string
+	transformer := newTestLLMQueryTransformer(QueryMultiQuery, "1. How do animals swim?\n\n- \"Swimming animals\"\n3) Marine animals", &prompts)
+	queries, err := transformer.Transform(context.Background(), "Which animals swim?")
+	if err != nil {
+		t.Fatalf("Transform() unexpected error: %v", err)
+	}
+	texts := []string{}
+	for _, q := range queries {
+		texts = append(texts, q.Text)
+	}
+	if !slices.Equal(texts, []string{"Which animals swim?", "How do animals swim?", "Swimming animals"}) {
+		t.Errorf("Transform() = %q, want the original and 2 paraphrases without numbering", texts)
+	}
+	if !strings.Contains(prompts[0], "Write 2 different versions") || prompts[1] != "Question: Which animals swim?" {
+		t.Errorf("prompts = %q, want the multi-query instructions and the question", prompts)
+	}
+
+	t.Run("HyDE", func(t *testing.T) {
+		var prompts []string
+		transformer := newTestLLMQueryTransformer(QueryHyDE, "Dolphins and whales swim.\nFishes too.", &prompts)
+		transformer.original = false
+		queries, _ := transformer.Transform(context.Background(), "Which animals swim?")
+		if len(queries) != 1 || queries[0].Kind != QueryHyDE || queries[0].Text != "Dolphins and whales swim.\nFishes too." {
+			t.Errorf("Transform() = %+v, want the whole hypothetical answer only", queries)
+		}
+		if prompts[0] != HyDEInstructions {
+			t.Errorf("system = %q, want the HyDE instructions", prompts[0])
+		}
+	})
+
+	t.Run("empty answer", func(t *testing.T) {
+		var prompts []string
+		if _, err := newTestLLMQueryTransformer(QueryStepBack, " \n", &prompts).Transform(context.Background(), "Which animals swim?"); err == nil {
+			t.Error("Transform() of an empty answer: expected an error")
+		}
+	})
+
+	t.Run("chat agent options", func(t *testing.T) {
+		var prompts []string
+		transformer, err := NewLLMQueryTransformer(fakeGenerator{answer: "Swimming animals", prompts: &prompts},
+			WithTransformerInstructions(" Rewrite the question. "))
+		if err != nil {
+			t.Fatalf("NewLLMQueryTransformer() unexpected error: %v", err)
+		}
+		transformer.Transform(context.Background(), "Which animals swim?")
+		if transformer.Name != "queries" || prompts[0] != "Rewrite the question." {
+			t.Errorf("transformer %q with system %q, want the name of the chat agent and the instructions of the option", transformer.Name, prompts[0])
+		}
+
+		if _, err := NewLLMQueryTransformer(nil); err == nil {
+			t.Error("NewLLMQueryTransformer() without chat agent: expected an error")
+		}
+		if _, err := NewLLMQueryTransformer(fakeGenerator{prompts: &prompts}, WithQueryKind("unknown")); err == nil {
+			t.Error("NewLLMQueryTransformer() with an unknown kind: expected an error")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// fakeReranker scores the documents by the table, and records the documents reranked
+type fakeReranker struct {
+	scores   map[string]float64
+	reranked []string
+}
+
+func (r *fakeReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	r.reranked = documents
+	scores := make([]float64, len(documents))
+	for idx, document := range documents {
+		scores[idx] = r.scores[document]
+	}
+	return scores, nil
+}
+
+func TestRagAgentSearchWithReranker(t *testing.T) {
+	agent := newSearchTestAgent(t)
+	reranker := &fakeReranker{scores: map[string]float64{"Eagles fly in the sky": 0.9, "Whales also swim in the ocean": 0.5}}
+	agent.reranker = reranker
+
+	results, err := agent.Search("Which animals swim?", WithTopK(2))
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	if len(reranker.reranked) != 3 {
+		t.Errorf("reranked %d candidates, want all the 3 chunks for K * DefaultRerankFactor", len(reranker.reranked))
+	}
+	if len(results) != 2 || results[0].ID != "eagles" || results[1].ID != "whales" {
+		t.Fatalf("Search() = %+v, want eagles then whales", results)
+	}
+	if results[0].RerankScore != 0.9 || results[0].Score != 0 || math.Abs(results[1].Score-0.8) > 1e-6 {
+		t.Errorf("Search() = %+v, want the rerank and the retrieval scores", results)
+	}
+
+	if _, err := agent.Search("Which animals swim?", WithRerankCandidates(1)); err != nil || len(reranker.reranked) != 1 {
+		t.Errorf("Search() with 1 candidate reranked %d candidates, %v", len(reranker.reranked), err)
+	}
+	results, _ = agent.Search("Which animals swim?", WithTopK(1), WithoutRerank())
+	if len(results) != 1 || results[0].ID != "dolphins" || results[0].RerankScore != 0 {
+		t.Errorf("Search() without rerank = %+v, want dolphins", results)
+	}
+}
+
+// newTestLLMReranker returns a reranker whose model answers with answer
+func newTestLLMReranker(mode RerankMode, answer func(prompt string) string) *LLMReranker {
+	return &LLMReranker{
+		mode:    mode,
+		workers: 2,
+		logger:  &logger.NoOpLogger{},
+		generate: func(ctx context.Context, system, prompt string) (string, error) {
+			if strings.Contains(prompt, "failing") {
+				return "", fmt.Errorf("model unavailable")
+			}
+			return answer(prompt), nil
+		},
+	}
+}
+
+func TestLLMReranker(t *testing.T) {
+	documents := []string{"Eagles fly", "Dolphins swim", "Sharks swim and hunt"}
+
+	t.Run("pointwise", func(t *testing.T) {
+		ratings := map[string]string{"Eagles fly": "0", "Dolphins swim": "Relevance: 7", "Sharks swim and hunt": "I don't know"}
+		reranker := newTestLLMReranker(RerankPointwise, func(prompt string) string {
+			for document, rating := range ratings {
+				if strings.Contains(prompt, "Document:\n"+document+"\n") {
+					return rating
+				}
+			}
+			return ""
+		})
+		scores, err := reranker.Rerank(context.Background(), "Which animals swim?", documents)
+		if err != nil || !slices.Equal(scores, []float64{0, 0.7, 0}) {
+			t.Errorf("Rerank() = %v, %v, want [0 0.7 0]", scores, err)
+		}
+		if _, err := reranker.Rerank(context.Background(), "query", []string{"failing"}); err == nil {
+			t.Error("Rerank() expected the error of the model")
+		}
+	})
+
+	t.Run("listwise", func(t *testing.T) {
+		var prompts []string
+		reranker := newTestLLMReranker(RerankListwise, func(prompt string) string {
+			prompts = append(prompts, prompt)
+			return "3, 2, 3, 7"
+		})
+		scores, err := reranker.Rerank(context.Background(), "Which animals swim?", documents)
+		if err != nil {
+			t.Fatalf("Rerank() unexpected error: %v", err)
+		}
+		// 3 is first, 2 second, the duplicate and unknown numbers are ignored, 1 is missing
+		rank := 1.0
+		if want := []float64{0, 1 - rank/3, 1}; !slices.Equal(scores, want) {
+			t.Errorf("Rerank() = %v, want %v", scores, want)
+		}
+		if len(prompts) != 1 || !strings.Contains(prompts[0], "[3] Sharks swim and hunt") {
+			t.Errorf("prompts = %q, want a single prompt with the numbered documents", prompts)
+		}
+	})
+}
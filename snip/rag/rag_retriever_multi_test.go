@@ -0,0 +1,164 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+// fakeSearcher returns the same results for all the queries
+type fakeSearcher struct {
+	name    string
+	results []SearchResult
+	err     error
+	delay   time.Duration
+	options SearchOptions
+}
+
+func (s *fakeSearcher) GetName() string { return s.name }
+
+func (s *fakeSearcher) Search(query string, opts ...SearchOption) ([]SearchResult, error) {
+	time.Sleep(s.delay)
+	s.options = NewSearchOptions(opts...)
+	return s.results, s.err
+}
+
+// blockingSearcher is a context searcher answering when its context is done
+type blockingSearcher struct {
+	cancelled chan struct{}
+}
+
+func (s *blockingSearcher) GetName() string { return "blocking" }
+
+func (s *blockingSearcher) Search(query string, opts ...SearchOption) ([]SearchResult, error) {
+	return s.SearchContext(context.Background(), query, opts...)
+}
+
+func (s *blockingSearcher) SearchContext(ctx context.Context, query string, opts ...SearchOption) ([]SearchResult, error) {
+	<-ctx.Done()
+	close(s.cancelled)
+	return nil, ctx.Err()
+}
+
+// multiContents returns the contents and the sources of the results
+func multiContents(results []MultiSearchResult) ([]string, []string) {
+	contents, sources := []string{}, []string{}
+	for _, result := range results {
+		contents = append(contents, result.Content)
+		sources = append(sources, result.Source)
+	}
+	return contents, sources
+}
+
+func TestMultiRetriever(t *testing.T) {
+	// The scores of the second source are on another scale, its best result is worth the best of the first one
+	first := &fakeSearcher{name: "first", results: []SearchResult{
+		{ID: "1", Content: "Dolphins swim", Score: 0.8},
+		{ID: "2", Content: "Frogs swim", Score: 0.4},
+	}}
+	second := &fakeSearcher{name: "second", results: []SearchResult{
+		{ID: "a", Content: "Fishes swim", Score: 0.3},
+		{ID: "b", Content: "Dolphins swim", Score: 0.15},
+	}}
+	retriever, err := NewMultiRetriever([]RetrieverSource{{Searcher: first}, {Searcher: second, Name: "other"}})
+	if err != nil {
+		t.Fatalf("NewMultiRetriever() unexpected error: %v", err)
+	}
+
+	results, err := retriever.Search("Which animals swim?", WithTopK(3), WithMetadataFilter(map[string]any{"kind": "animal"}))
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	contents, sources := multiContents(results)
+	if want := []string{"Dolphins swim", "Fishes swim", "Frogs swim"}; !slices.Equal(contents, want) {
+		t.Errorf("Search() = %q, want %q", contents, want)
+	}
+	if want := []string{"first", "other", "first"}; !slices.Equal(sources, want) {
+		t.Errorf("sources = %q, want %q", sources, want)
+	}
+	// (1 + 0.5) / 2 for the dolphins found by both sources
+	if math.Abs(results[0].Score-0.75) > 1e-9 || results[0].SourceScores["other"] != 0.15 || results[0].SourceScores["first"] != 0.8 {
+		t.Errorf("dolphins = %+v, want the score 0.75 and the scores of both sources", results[0])
+	}
+	if first.options.K != 3 || first.options.Filter["kind"] != "animal" {
+		t.Errorf("source options = %+v, want the options of the search", first.options)
+	}
+
+	t.Run("weights", func(t *testing.T) {
+		retriever, _ := NewMultiRetriever([]RetrieverSource{{Searcher: first}, {Searcher: second, Weight: 3}}, WithScoreNormalization(NormalizeRank))
+		results, _ := retriever.Search("Which animals swim?", WithTopK(2))
+		if contents, _ := multiContents(results); !slices.Equal(contents, []string{"Dolphins swim", "Fishes swim"}) {
+			t.Errorf("Search() = %q, want the results of the weighted source first", contents)
+		}
+	})
+
+	t.Run("failed and slow sources are skipped", func(t *testing.T) {
+		failing := &fakeSearcher{name: "failing", err: errors.New("engine down")}
+		slow := &fakeSearcher{name: "slow", delay: time.Second, results: []SearchResult{{Content: "Too late", Score: 1}}}
+		retriever, _ := NewMultiRetriever([]RetrieverSource{{Searcher: failing}, {Searcher: slow, Timeout: 10 * time.Millisecond}, {Searcher: first}})
+		results, err := retriever.Search("Which animals swim?")
+		if contents, _ := multiContents(results); err != nil || !slices.Equal(contents, []string{"Dolphins swim", "Frogs swim"}) {
+			t.Errorf("Search() = %q, %v, want the results of the first source", contents, err)
+		}
+
+		retriever, _ = NewMultiRetriever([]RetrieverSource{{Searcher: failing}})
+		if _, err := retriever.Search("Which animals swim?"); err == nil {
+			t.Error("Search() with all the sources failing: expected an error")
+		}
+	})
+
+	t.Run("slow context sources are cancelled", func(t *testing.T) {
+		blocking := &blockingSearcher{cancelled: make(chan struct{})}
+		retriever, _ := NewMultiRetriever([]RetrieverSource{{Searcher: blocking}, {Searcher: first}}, WithRetrieverTimeout(10*time.Millisecond))
+		if _, err := retriever.Search("Which animals swim?"); err != nil {
+			t.Errorf("Search() unexpected error: %v", err)
+		}
+		select {
+		case <-blocking.cancelled:
+		default:
+			t.Error("the search of the slow source was not cancelled")
+		}
+	})
+
+	t.Run("chunk found twice by a source", func(t *testing.T) {
+		twice := &fakeSearcher{name: "twice", results: []SearchResult{
+			{ID: "1", Content: "Dolphins swim", Score: 0.8},
+			{ID: "2", Content: "Dolphins swim", Score: 0.6},
+		}}
+		retriever, _ := NewMultiRetriever([]RetrieverSource{{Searcher: twice}})
+		results, err := retriever.Search("Which animals swim?")
+		if err != nil || len(results) != 1 || results[0].Score != 1 || results[0].SourceScores["twice"] != 0.8 {
+			t.Errorf("Search() = %+v, %v, want the chunk once with the score 1 and its best source score", results, err)
+		}
+	})
+
+	t.Run("invalid sources", func(t *testing.T) {
+		if _, err := NewMultiRetriever([]RetrieverSource{{Searcher: first}, {Searcher: first}}); err == nil {
+			t.Error("NewMultiRetriever() with duplicate names: expected an error")
+		}
+		if _, err := NewMultiRetriever(nil); err == nil {
+			t.Error("NewMultiRetriever() without source: expected an error")
+		}
+	})
+}
+
+func TestStoreSearcher(t *testing.T) {
+	agent, _ := newDocumentsTestAgent()
+	agent.AddTextChunksToStore([]text.TextChunk{{Content: "four"}, {Content: "a longer text"}})
+
+	var store vectorstore.VectorStore = agent.store
+	retriever, err := NewMultiRetriever([]RetrieverSource{{Searcher: NewStoreSearcher(agent.ctx, "store", store, agent.embedder)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := retriever.Search("five", WithTopK(1))
+	if err != nil || len(results) != 1 || results[0].Content != "four" || results[0].Source != "store" {
+		t.Errorf("Search() = %+v, %v, want the chunk with the closest length", results, err)
+	}
+}
@@ -0,0 +1,119 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// newHybridTestAgent returns an agent with a lexical index, whose store holds three chunks
+// The vector search of the query ranks the chunk with the error code last
+func newHybridTestAgent(t *testing.T) *RagAgent {
+	t.Helper()
+	store := vectorstore.NewMemoryStore()
+	store.Upsert(context.Background(), []vectorstore.Record{
+		{ID: "retry", Content: "The client retries on ERR_CONN_RESET", Metadata: map[string]any{"source": "client.md"}, Embedding: []float32{0, 1, 0}},
+		{ID: "network", Content: "Network errors are retried by the client", Metadata: map[string]any{"source": "client.md"}, Embedding: []float32{1, 0, 0}},
+		{ID: "server", Content: "The server closes idle connections", Metadata: map[string]any{"source": "server.md"}, Embedding: []float32{0.9, 0.1, 0}},
+	})
+	agent := &RagAgent{
+		ctx:    context.Background(),
+		store:  store,
+		logger: &logger.NoOpLogger{},
+		embedder: fakeEmbedder(map[string][]float32{
+			"What happens on ERR_CONN_RESET?": {1, 0, 0},
+			"Idle connections":                {0.9, 0.1, 0},
+		}),
+	}
+	if err := agent.rebuildLexicalIndex(); err != nil {
+		t.Fatalf("rebuildLexicalIndex() unexpected error: %v", err)
+	}
+	return agent
+}
+
+func TestRagAgentHybridSearch(t *testing.T) {
+	agent := newHybridTestAgent(t)
+	query := "What happens on ERR_CONN_RESET?"
+
+	t.Run("vector", func(t *testing.T) {
+		results, _ := agent.Search(query)
+		if len(results) != 3 || results[2].ID != "retry" {
+			t.Errorf("Search() = %+v, want the error code last", results)
+		}
+	})
+
+	t.Run("lexical", func(t *testing.T) {
+		results, err := agent.Search(query, WithSearchMode(SearchLexical))
+		if err != nil {
+			t.Fatalf("Search() unexpected error: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "retry" || results[0].Content != "The client retries on ERR_CONN_RESET" {
+			t.Errorf("Search() = %+v, want the chunk with the error code and its content", results)
+		}
+		if results[0].Metadata["source"] != "client.md" || results[0].LexicalScore != results[0].Score {
+			t.Errorf("Search() = %+v, want the metadata and the BM25 score", results[0])
+		}
+	})
+
+	t.Run("reciprocal rank fusion", func(t *testing.T) {
+		results, err := agent.Search(query, WithHybridSearch(1, 1))
+		if err != nil {
+			t.Fatalf("Search() unexpected error: %v", err)
+		}
+		if len(results) != 3 || results[0].ID != "retry" || results[1].ID != "network" {
+			t.Errorf("Search() = %+v, want retry then network", results)
+		}
+		if results[0].VectorScore != 0 || results[0].LexicalScore == 0 || results[1].VectorScore != 1 {
+			t.Errorf("Search() = %+v, want the scores of both rankings", results)
+		}
+		if results[0].Score > 1 || results[0].Score <= results[1].Score {
+			t.Errorf("Search() scores = %v and %v, want normalized descending scores", results[0].Score, results[1].Score)
+		}
+	})
+
+	t.Run("weights per query", func(t *testing.T) {
+		results, _ := agent.Search(query, WithHybridSearch(2, 1), WithFusion(FusionWeighted))
+		if len(results) != 3 || results[0].ID != "network" {
+			t.Errorf("Search() favoring the vectors = %+v, want network first", results)
+		}
+		results, _ = agent.Search(query, WithHybridSearch(1, 2), WithFusion(FusionWeighted), WithTopK(1))
+		if len(results) != 1 || results[0].ID != "retry" {
+			t.Errorf("Search() favoring the terms = %+v, want retry first", results)
+		}
+	})
+
+	t.Run("filter and min score", func(t *testing.T) {
+		results, _ := agent.Search("Idle connections", WithHybridSearch(1, 1), WithMetadataFilter(map[string]any{"source": "server.md"}))
+		if len(results) != 1 || results[0].ID != "server" || results[0].Score != 1 {
+			t.Errorf("Search() with filter = %+v, want server with a score of 1", results)
+		}
+		// retry is first in the lexical ranking only: (1/61 + 1/63) * 61/2 < 0.99
+		results, _ = agent.Search(query, WithHybridSearch(1, 1), WithMinScore(0.99))
+		if len(results) != 0 {
+			t.Errorf("Search() with min score = %+v, want no result", results)
+		}
+	})
+
+	t.Run("index follows the writes", func(t *testing.T) {
+		agent.embedder = fakeEmbedder(map[string][]float32{"Timeouts raise ERR_TIMEOUT": {0, 0, 1}})
+		agent.UpsertTextChunks([]text.TextChunk{{ID: "timeout", Content: "Timeouts raise ERR_TIMEOUT"}})
+		if results, _ := agent.Search("ERR_TIMEOUT", WithSearchMode(SearchLexical)); len(results) == 0 || results[0].ID != "timeout" {
+			t.Errorf("Search() after UpsertTextChunks() = %+v, want timeout", results)
+		}
+		agent.DeleteByMetadata(map[string]any{"source": "client.md"})
+		if results, _ := agent.Search("ERR_CONN_RESET", WithSearchMode(SearchLexical)); len(results) != 1 || results[0].ID != "timeout" {
+			t.Errorf("Search() after DeleteByMetadata() = %+v, want timeout only (by the err term)", results)
+		}
+	})
+
+	t.Run("no lexical index", func(t *testing.T) {
+		agent := newSearchTestAgent(t)
+		if _, err := agent.Search("Which animals swim?", WithHybridSearch(1, 1)); !errors.Is(err, errNoLexicalIndex) {
+			t.Errorf("Search() error = %v, want errNoLexicalIndex", err)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// fakeEmbedder returns the vectors of a table, by text
+func fakeEmbedder(vectors map[string][]float32) ai.Embedder {
+	return ai.NewEmbedder("fake-embedder", nil, func(ctx context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		response := &ai.EmbedResponse{}
+		for _, doc := range req.Input {
+			vector, ok := vectors[doc.Content[0].Text]
+			if !ok {
+				return nil, fmt.Errorf("no vector for %q", doc.Content[0].Text)
+			}
+			response.Embeddings = append(response.Embeddings, &ai.Embedding{Embedding: vector})
+		}
+		return response, nil
+	})
+}
+
+// newSearchTestAgent returns an agent whose store holds three chunks
+func newSearchTestAgent(t *testing.T) *RagAgent {
+	t.Helper()
+	store := vectorstore.NewMemoryStore()
+	err := store.Upsert(context.Background(), []vectorstore.Record{
+		{
+			ID:        "dolphins",
+			Content:   "Dolphins swim in the ocean",
+			Metadata:  map[string]any{"category": "marine", "year": 2024},
+			Embedding: []float32{1, 0, 0},
+		},
+		{
+			ID:        "whales",
+			Content:   "Whales also swim in the ocean",
+			Metadata:  map[string]any{"category": "marine", "year": 2025},
+			Embedding: []float32{0.8, 0.6, 0},
+		},
+		{
+			ID:        "eagles",
+			Content:   "Eagles fly in the sky",
+			Metadata:  map[string]any{"category": "birds"},
+			Embedding: []float32{0, 0, 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Upsert() unexpected error: %v", err)
+	}
+
+	return &RagAgent{
+		ctx:    context.Background(),
+		store:  store,
+		logger: &logger.NoOpLogger{},
+		embedder: fakeEmbedder(map[string][]float32{
+			"Which animals swim?": {1, 0, 0},
+			"Sharks hunt fish":    {0.6, 0.8, 0},
+		}),
+	}
+}
+
+func TestRagAgentSearch(t *testing.T) {
+	agent := newSearchTestAgent(t)
+
+	t.Run("scores and metadata", func(t *testing.T) {
+		results, err := agent.Search("Which animals swim?")
+		if err != nil {
+			t.Fatalf("Search() unexpected error: %v", err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("Search() returned %d results, want 3", len(results))
+		}
+
+		wantIDs := []string{"dolphins", "whales", "eagles"}
+		wantScores := []float64{1, 0.8, 0}
+		for i, result := range results {
+			if result.ID != wantIDs[i] || math.Abs(result.Score-wantScores[i]) > 1e-6 {
+				t.Errorf("result %d = %s (%f), want %s (%f)", i, result.ID, result.Score, wantIDs[i], wantScores[i])
+			}
+		}
+		if results[0].Content != "Dolphins swim in the ocean" || results[0].Metadata["category"] != "marine" {
+			t.Errorf("result 0 = %+v, want the content and metadata of the chunk", results[0])
+		}
+	})
+
+	t.Run("top K", func(t *testing.T) {
+		results, _ := agent.Search("Which animals swim?", WithTopK(1))
+		if len(results) != 1 || results[0].ID != "dolphins" {
+			t.Errorf("Search() = %+v, want the best chunk only", results)
+		}
+	})
+
+	t.Run("min score", func(t *testing.T) {
+		results, _ := agent.Search("Which animals swim?", WithMinScore(0.5))
+		if len(results) != 2 {
+			t.Errorf("Search() returned %d results, want 2", len(results))
+		}
+	})
+
+	t.Run("metadata filter", func(t *testing.T) {
+		// A filter decoded from JSON holds float64 numbers
+		results, _ := agent.Search("Which animals swim?", WithMetadataFilter(map[string]any{"category": "marine", "year": float64(2025)}))
+		if len(results) != 1 || results[0].ID != "whales" {
+			t.Errorf("Search() = %+v, want the whales chunk", results)
+		}
+	})
+
+	t.Run("search similarities", func(t *testing.T) {
+		contents, err := agent.SearchSimilarities("Which animals swim?")
+		if err != nil || len(contents) != DefaultSearchK || contents[0] != "Dolphins swim in the ocean" {
+			t.Errorf("SearchSimilarities() = %v, %v, want the contents of the best chunks", contents, err)
+		}
+	})
+
+	t.Run("embedding error", func(t *testing.T) {
+		if _, err := agent.Search("unknown query"); err == nil {
+			t.Error("Search() should return the embedding error")
+		}
+	})
+}
+
+func TestRagAgentAddTextChunksToStore(t *testing.T) {
+	agent := newSearchTestAgent(t)
+
+	chunk := text.TextChunk{Content: "Sharks hunt fish", Metadata: map[string]any{"category": "marine"}}
+	count, err := agent.AddTextChunksToStore([]text.TextChunk{chunk})
+	if err != nil || count != 1 {
+		t.Fatalf("AddTextChunksToStore() = %d, %v, want 1", count, err)
+	}
+	// The same chunk is not added twice
+	agent.AddTextChunksToStore([]text.TextChunk{chunk})
+
+	if n := agent.GetNumberOfDocuments(); n != 4 {
+		t.Errorf("GetNumberOfDocuments() = %d, want 4", n)
+	}
+
+	results, _ := agent.Search("Sharks hunt fish", WithTopK(1))
+	if len(results) != 1 || results[0].ID != vectorstore.DocumentID(chunk.Content, chunk.Metadata) {
+		t.Errorf("Search() = %+v, want the added chunk with its localvec ID", results)
+	}
+}
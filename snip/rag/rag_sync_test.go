@@ -0,0 +1,214 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+)
+
+// writeSyncFile writes a file of the synchronized directory, with a modification time in the past
+// so that a rewrite within the same clock tick is still seen as a change
+func writeSyncFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-time.Hour).Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRagAgentSyncDirectory(t *testing.T) {
+	agent, embedded := newDocumentsTestAgent()
+	dir := t.TempDir()
+	manifest := filepath.Join(t.TempDir(), "docs.sync.json")
+	writeSyncFile(t, dir, "intro.md", "# Intro\nSnip agents.")
+	writeSyncFile(t, dir, "guides/rag.md", "# RAG\nRAG agents search documents.")
+	writeSyncFile(t, dir, "animals.csv", "name\nDolphin\nEagle\n")
+	writeSyncFile(t, dir, "logo.png", "not a document")
+
+	report, err := agent.SyncDirectory(dir, WithSyncManifest(manifest))
+	if err != nil {
+		t.Fatalf("SyncDirectory() unexpected error: %v", err)
+	}
+	if want := []string{"animals.csv", "guides/rag.md", "intro.md"}; !slices.Equal(report.Added, want) || report.Written != 4 {
+		t.Fatalf("SyncDirectory() = %+v, want %v added with 4 chunks", report, want)
+	}
+	if count := agent.GetNumberOfDocuments(); count != 4 {
+		t.Errorf("store has %d chunks, want 4", count)
+	}
+
+	t.Run("unchanged files are not embedded again", func(t *testing.T) {
+		*embedded = nil
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		os.Chtimes(manifest, past, past)
+		report, err := agent.SyncDirectory(dir, WithSyncManifest(manifest))
+		if err != nil || report.HasChanges() || report.Unchanged != 3 || len(*embedded) != 0 {
+			t.Errorf("SyncDirectory() = %+v, %v, embedded %v, want no change", report, err, *embedded)
+		}
+		if info, err := os.Stat(manifest); err != nil || !info.ModTime().Equal(past) {
+			t.Errorf("manifest modified without change")
+		}
+	})
+
+	t.Run("changed and removed files", func(t *testing.T) {
+		*embedded = nil
+		writeSyncFile(t, dir, "intro.md", "# Intro\nSnip agents and tools.")
+		os.Remove(filepath.Join(dir, "animals.csv"))
+		writeSyncFile(t, dir, "guides/new.txt", "A new guide")
+
+		report, err := agent.SyncDirectory(dir, WithSyncManifest(manifest))
+		if err != nil {
+			t.Fatalf("SyncDirectory() unexpected error: %v", err)
+		}
+		if !slices.Equal(report.Changed, []string{"intro.md"}) || !slices.Equal(report.Removed, []string{"animals.csv"}) ||
+			!slices.Equal(report.Added, []string{"guides/new.txt"}) || report.Unchanged != 1 {
+			t.Errorf("SyncDirectory() = %+v, want intro.md changed, animals.csv removed and guides/new.txt added", report)
+		}
+		if len(*embedded) != 2 || report.Deleted != 3 {
+			t.Errorf("embedded %q and deleted %d chunks, want the new chunks of intro.md and new.txt, and 3 deleted", *embedded, report.Deleted)
+		}
+		if count := agent.GetNumberOfDocuments(); count != 3 {
+			t.Errorf("store has %d chunks, want 3", count)
+		}
+	})
+
+	t.Run("touched file with the same content", func(t *testing.T) {
+		*embedded = nil
+		now := time.Now()
+		os.Chtimes(filepath.Join(dir, "guides/rag.md"), now, now)
+		report, err := agent.SyncDirectory(dir, WithSyncManifest(manifest))
+		if err != nil || report.HasChanges() || len(*embedded) != 0 {
+			t.Errorf("SyncDirectory() = %+v, %v, want no change", report, err)
+		}
+	})
+}
+
+func TestRagAgentSyncDirectorySkipsAgentFiles(t *testing.T) {
+	agent, _ := newDocumentsTestAgent()
+	dir := t.TempDir()
+	store, err := vectorstore.NewHNSWStore(vectorstore.WithHNSWFile(filepath.Join(dir, "index.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.store = store
+	writeSyncFile(t, dir, "intro.md", "# Intro\nSnip agents.")
+	opts := []SyncOption{WithSyncManifest(filepath.Join(dir, "docs.sync.json"))}
+
+	if _, err := agent.SyncDirectory(dir, opts...); err != nil {
+		t.Fatalf("SyncDirectory() unexpected error: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := agent.SyncDirectory(dir, opts...)
+	if err != nil || report.HasChanges() || report.Unchanged != 1 {
+		t.Errorf("SyncDirectory() = %+v, %v, want the manifest and the store skipped", report, err)
+	}
+}
+
+func TestRagAgentSyncDirectoryCancelled(t *testing.T) {
+	agent, embedded := newDocumentsTestAgent()
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "intro.md", "# Intro\nSnip agents.")
+
+	// The context of the watch, not the one of the agent, stops the synchronization
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := agent.syncDirectory(ctx, dir, agent.newSyncConfig([]SyncOption{WithSyncManifest(filepath.Join(t.TempDir(), "sync.json"))}))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("syncDirectory() error = %v, want context.Canceled", err)
+	}
+	if report.HasChanges() || len(*embedded) != 0 {
+		t.Errorf("syncDirectory() = %+v, embedded %v, want nothing synchronized", report, *embedded)
+	}
+}
+
+func TestNotifyWatcherIgnoresAgentFiles(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "docs.sync.json")
+	watcher, err := newNotifyWatcher(dir, 20*time.Millisecond, func(path string) bool {
+		return path == manifest || path == manifest+".tmp"
+	})
+	if err != nil {
+		t.Skipf("no file notifications: %v", err)
+	}
+	defer watcher.close()
+
+	(&syncManifest{Files: map[string]syncManifestFile{}}).save(manifest)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := watcher.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wait() = %v after writing the manifest, want no change", err)
+	}
+
+	writeSyncFile(t, dir, "intro.md", "# Intro")
+	if err := watcher.wait(context.Background()); err != nil {
+		t.Errorf("wait() = %v after writing a document", err)
+	}
+}
+
+func TestRagAgentWatchDirectory(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		t.Run(map[bool]string{false: "notifications", true: "polling"}[polling], func(t *testing.T) {
+			agent, _ := newDocumentsTestAgent()
+			dir := t.TempDir()
+			writeSyncFile(t, dir, "intro.md", "# Intro\nSnip agents.")
+
+			reports := make(chan SyncReport, 10)
+			opts := []SyncOption{
+				WithSyncManifest(filepath.Join(t.TempDir(), "sync.json")),
+				WithWatchInterval(20 * time.Millisecond),
+				WithSyncCallback(func(report SyncReport, err error) {
+					if err != nil {
+						t.Errorf("synchronization error: %v", err)
+					}
+					reports <- report
+				}),
+			}
+			if polling {
+				opts = append(opts, WithPolling())
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- agent.WatchDirectory(ctx, dir, opts...) }()
+
+			next := func() SyncReport {
+				select {
+				case report := <-reports:
+					return report
+				case <-time.After(5 * time.Second):
+					t.Fatal("no synchronization after the change")
+					return SyncReport{}
+				}
+			}
+			if report := next(); !slices.Equal(report.Added, []string{"intro.md"}) {
+				t.Errorf("first synchronization = %+v, want intro.md added", report)
+			}
+			writeSyncFile(t, dir, "sub/rag.md", "# RAG\nRAG agents.")
+			if report := next(); !slices.Equal(report.Added, []string{"sub/rag.md"}) {
+				t.Errorf("synchronization = %+v, want sub/rag.md added", report)
+			}
+			os.Remove(filepath.Join(dir, "intro.md"))
+			if report := next(); !slices.Equal(report.Removed, []string{"intro.md"}) {
+				t.Errorf("synchronization = %+v, want intro.md removed", report)
+			}
+
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("WatchDirectory() = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// vectorstore-migrate copies the records of a vector store to another one
+//
+// Usage:
+//
+//	go run github.com/snipwise/snip-sdk/snip/rag/vectorstore/cmd/vectorstore-migrate \
+//		-from localvec:./store/docs -to segmented:./store/docs-segments
+//
+// A store is "localvec:<dir>/<name>" (the file <dir>/__db_<name>.json), "segmented:<dir>"
+// or "hnsw:<file>" (an HNSW index saved in a file)
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip/rag/vectorstore"
+)
+
+func main() {
+	from := flag.String("from", "", "source store (localvec:<dir>/<name>, segmented:<dir> or hnsw:<file>)")
+	to := flag.String("to", "", "destination store (localvec:<dir>/<name>, segmented:<dir> or hnsw:<file>)")
+	batchSize := flag.Int("batch", vectorstore.DefaultMigrationBatchSize, "number of records written at once")
+	compact := flag.Bool("compact", true, "compact the destination when it is a segmented store")
+	flag.Parse()
+
+	if *from == "" || *to == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*from, *to, *batchSize, *compact); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(1)
+	}
+}
+
+func run(from, to string, batchSize int, compact bool) error {
+	src, err := openStore(from)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := openStore(to)
+	if err != nil {
+		return fmt.Errorf("destination: %w", err)
+	}
+
+	count, err := vectorstore.Migrate(context.Background(), src, dst, batchSize)
+	if segmented, ok := dst.(*vectorstore.SegmentedStore); ok && compact && err == nil {
+		err = segmented.Compact()
+	}
+	// Closing the hnsw store writes its file, and closing the segmented store flushes its active segment
+	// (the localvec store writes its file when the records are added)
+	if closeErr := dst.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("destination: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ %d records copied from %s to %s\n", count, from, to)
+	return nil
+}
+
+// openStore opens a store from its description
+func openStore(spec string) (vectorstore.VectorStore, error) {
+	kind, path, found := strings.Cut(spec, ":")
+	if !found || path == "" {
+		return nil, fmt.Errorf("invalid store %q, expected localvec:<dir>/<name>, segmented:<dir> or hnsw:<file>", spec)
+	}
+
+	switch kind {
+	case "localvec":
+		return vectorstore.OpenLocalvecStore(filepath.Dir(path), filepath.Base(path))
+	case "segmented":
+		return vectorstore.NewSegmentedStore(path)
+	case "hnsw":
+		return vectorstore.NewHNSWStore(vectorstore.WithHNSWFile(path))
+	}
+	return nil, fmt.Errorf("unknown store kind %q", kind)
+}
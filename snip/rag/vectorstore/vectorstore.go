@@ -0,0 +1,212 @@
+// Package vectorstore defines the storage of the embeddings of the RAG agents, and its built-in backends:
+// an in-memory store, a segmented on-disk store, and an adapter for the Genkit localvec stores
+package vectorstore
+
+import (
+	"cmp"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+var (
+	// ErrClosed is returned by the methods of a closed store
+	ErrClosed = errors.New("vector store is closed")
+
+	// ErrDimensionMismatch is returned when the embedding of a record or a query
+	// does not have the dimension of the embeddings of the store
+	ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+)
+
+// Record is a chunk of text with its embedding
+type Record struct {
+	ID        string         `json:"id"`
+	Content   string         `json:"content"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Embedding []float32      `json:"embedding"`
+}
+
+// Match is a record returned by a search, with its score
+type Match struct {
+	Record
+	// Score is the cosine similarity between the query and the record (1 is the best)
+	Score float64 `json:"score"`
+}
+
+// Query is a similarity search
+type Query struct {
+	// Vector is the embedding of the query
+	Vector []float32
+	// K is the maximum number of matches (all the matches if zero or negative)
+	K int
+	// MinScore drops the matches with a lower score
+	MinScore float64
+	// Filter keeps the records whose metadata have all these values (see MatchMetadata)
+	Filter map[string]any
+}
+
+// VectorStore stores the records of a RAG agent and searches them by similarity
+// The implementations are safe for concurrent use
+type VectorStore interface {
+	// Add adds the records whose ID is not in the store yet, and returns the number of records added
+	Add(ctx context.Context, records []Record) (int, error)
+	// Upsert adds the records, replacing the ones with the same ID
+	Upsert(ctx context.Context, records []Record) error
+	// Delete removes the records with these IDs, and returns the number of records removed
+	Delete(ctx context.Context, ids []string) (int, error)
+	// Get returns the records with these IDs, in the order of the IDs, skipping the IDs not in the store
+	Get(ctx context.Context, ids []string) ([]Record, error)
+	// Search returns the records most similar to the query vector, sorted by descending score
+	Search(ctx context.Context, query Query) ([]Match, error)
+	// Count returns the number of records
+	Count(ctx context.Context) (int, error)
+	// Scan calls fn with each record, in no particular order, until fn returns an error
+	Scan(ctx context.Context, fn func(Record) error) error
+	// Close releases the resources of the store
+	Close() error
+}
+
+// FileStore is implemented by the stores kept on disk
+type FileStore interface {
+	// Path returns the file or the directory of the store (empty if not saved)
+	Path() string
+}
+
+// DocumentID returns the ID of a chunk computed as the localvec plugin does (MD5 of the JSON document),
+// so that the records added by a RagAgent keep the same IDs whatever the store
+func DocumentID(content string, metadata map[string]any) string {
+	data, err := json.Marshal(ai.DocumentFromText(content, metadata))
+	if err != nil {
+		// Metadata that cannot be encoded: fall back to the content only
+		data, _ = json.Marshal(ai.DocumentFromText(content, nil))
+	}
+	return fmt.Sprintf("%02x", md5.Sum(data))
+}
+
+// DeleteByMetadata removes the records whose metadata have all the values of filter
+// (for example all the chunks of a source file), and returns the number of records removed
+// An empty filter is an error, use Delete with the IDs of a Scan to empty a store
+func DeleteByMetadata(ctx context.Context, store VectorStore, filter map[string]any) (int, error) {
+	if len(filter) == 0 {
+		return 0, fmt.Errorf("empty metadata filter")
+	}
+	ids := []string{}
+	err := store.Scan(ctx, func(record Record) error {
+		if MatchMetadata(record.Metadata, filter) {
+			ids = append(ids, record.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return store.Delete(ctx, ids)
+}
+
+// MatchMetadata reports whether metadata has all the values of filter
+// Numbers are compared by value, so that a filter decoded from JSON (float64) matches an int
+func MatchMetadata(metadata, filter map[string]any) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok || !equalValues(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalValues compares two metadata values
+func equalValues(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(value any) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors (0 if their dimensions differ or one is null)
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// sortMatches sorts the matches by descending score (then by ID for a stable order) and keeps the k best
+func sortMatches(matches []Match, k int) []Match {
+	slices.SortFunc(matches, func(a, b Match) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// scoreRecord returns the match of a record for the query, ok is false if the record is filtered out
+func scoreRecord(record Record, query Query) (match Match, ok bool) {
+	if !MatchMetadata(record.Metadata, query.Filter) {
+		return Match{}, false
+	}
+	score := CosineSimilarity(query.Vector, record.Embedding)
+	if score < query.MinScore {
+		return Match{}, false
+	}
+	return Match{Record: record, Score: score}, true
+}
+
+// validateRecords checks that the records have an ID and an embedding of the given dimension
+// (any dimension if 0, then the dimension of the first record), and returns the dimension
+func validateRecords(records []Record, dimension int) (int, error) {
+	for _, record := range records {
+		if record.ID == "" {
+			return dimension, fmt.Errorf("record without ID")
+		}
+		if len(record.Embedding) == 0 {
+			return dimension, fmt.Errorf("record %s has no embedding", record.ID)
+		}
+		if dimension == 0 {
+			dimension = len(record.Embedding)
+		}
+		if len(record.Embedding) != dimension {
+			return dimension, fmt.Errorf("%w: record %s has dimension %d, want %d", ErrDimensionMismatch, record.ID, len(record.Embedding), dimension)
+		}
+	}
+	return dimension, nil
+}
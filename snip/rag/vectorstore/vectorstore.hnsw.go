@@ -0,0 +1,687 @@
+package vectorstore
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+)
+
+const (
+	// DefaultHNSWM is the default number of neighbours of a node (twice as many on the bottom layer)
+	DefaultHNSWM = 16
+	// DefaultHNSWEfConstruction is the default size of the candidate list when a record is inserted
+	DefaultHNSWEfConstruction = 200
+	// DefaultHNSWEfSearch is the default size of the candidate list of a search
+	DefaultHNSWEfSearch = 64
+)
+
+// HNSWStore is an in-memory vector store indexed with a Hierarchical Navigable Small World graph
+// (https://arxiv.org/abs/1603.09320): a search visits a small part of the records instead of all of them,
+// at the price of approximate results. Raise efSearch for a better recall, lower it for faster searches
+// Deleted and replaced records stay in the graph as tombstones until Compact rebuilds it
+// A search without K (all the matches) is done by brute force
+type HNSWStore struct {
+	mu sync.RWMutex
+
+	m              int
+	mMax0          int
+	efConstruction int
+	efSearch       int
+	levelMult      float64
+	rng            *rand.Rand
+	path           string
+
+	nodes     []*hnswNode
+	ids       map[string]int
+	entry     int
+	maxLevel  int
+	dimension int
+	deleted   int
+	closed    bool
+}
+
+// hnswNode is a record in the graph
+type hnswNode struct {
+	record Record
+	// vector is the normalized embedding, so that the cosine distance is 1 - dot product
+	vector    []float32
+	level     int
+	neighbors [][]int
+	deleted   bool
+}
+
+// HNSWOption defines a functional option for configuring an HNSWStore
+type HNSWOption func(*HNSWStore)
+
+// WithM sets the number of neighbours of a node (DefaultHNSWM by default)
+// A higher M gives a better recall on high-dimensional embeddings, with more memory and slower inserts
+func WithM(m int) HNSWOption {
+	return func(s *HNSWStore) {
+		if m > 1 {
+			s.m = m
+		}
+	}
+}
+
+// WithEfConstruction sets the size of the candidate list when a record is inserted (DefaultHNSWEfConstruction by default)
+func WithEfConstruction(ef int) HNSWOption {
+	return func(s *HNSWStore) {
+		if ef > 0 {
+			s.efConstruction = ef
+		}
+	}
+}
+
+// WithEfSearch sets the size of the candidate list of a search (DefaultHNSWEfSearch by default, at least K)
+func WithEfSearch(ef int) HNSWOption {
+	return func(s *HNSWStore) {
+		if ef > 0 {
+			s.efSearch = ef
+		}
+	}
+}
+
+// WithHNSWFile persists the index in a file: it is loaded when the store is created (if the file exists),
+// and saved by Save and Close
+func WithHNSWFile(path string) HNSWOption {
+	return func(s *HNSWStore) {
+		s.path = path
+	}
+}
+
+// NewHNSWStore creates an HNSW store, loading its file if WithHNSWFile is used
+// The M, efConstruction and efSearch of a loaded file are kept unless they are set with the options
+func NewHNSWStore(opts ...HNSWOption) (*HNSWStore, error) {
+	s := &HNSWStore{
+		ids:   map[string]int{},
+		entry: -1,
+		rng:   rand.New(rand.NewPCG(1, 2)),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.path != "" {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.m == 0 {
+		s.m = DefaultHNSWM
+	}
+	if s.efConstruction == 0 {
+		s.efConstruction = DefaultHNSWEfConstruction
+	}
+	if s.efSearch == 0 {
+		s.efSearch = DefaultHNSWEfSearch
+	}
+	s.mMax0 = 2 * s.m
+	s.levelMult = 1 / math.Log(float64(s.m))
+	return s, nil
+}
+
+// SetEfSearch changes the size of the candidate list of the next searches
+func (s *HNSWStore) SetEfSearch(ef int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ef > 0 {
+		s.efSearch = ef
+	}
+}
+
+func (s *HNSWStore) Add(ctx context.Context, records []Record) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added []Record
+	for _, record := range records {
+		if _, exists := s.ids[record.ID]; !exists {
+			added = append(added, record)
+		}
+	}
+	return len(added), s.put(ctx, added)
+}
+
+func (s *HNSWStore) Upsert(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.put(ctx, records)
+}
+
+// put inserts the records, the records with the same ID become tombstones
+// The caller must hold the lock
+func (s *HNSWStore) put(ctx context.Context, records []Record) error {
+	if s.closed {
+		return ErrClosed
+	}
+	dimension, err := validateRecords(records, s.dimension)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if old, exists := s.ids[record.ID]; exists {
+			s.nodes[old].deleted = true
+			s.deleted++
+		}
+		s.insert(record)
+		s.dimension = dimension
+	}
+	return nil
+}
+
+func (s *HNSWStore) Delete(ctx context.Context, ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+	deleted := 0
+	for _, id := range ids {
+		if index, exists := s.ids[id]; exists {
+			s.nodes[index].deleted = true
+			delete(s.ids, id)
+			s.deleted++
+			deleted++
+		}
+	}
+	if len(s.ids) == 0 {
+		// the tombstones would keep the old dimension in the graph
+		s.reset()
+	}
+	return deleted, nil
+}
+
+// reset empties the graph
+// The caller must hold the lock
+func (s *HNSWStore) reset() {
+	s.nodes = nil
+	s.ids = map[string]int{}
+	s.entry = -1
+	s.maxLevel = 0
+	s.dimension = 0
+	s.deleted = 0
+}
+
+func (s *HNSWStore) Search(ctx context.Context, query Query) ([]Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.dimension != 0 && len(query.Vector) != s.dimension {
+		return nil, fmt.Errorf("%w: query has dimension %d, want %d", ErrDimensionMismatch, len(query.Vector), s.dimension)
+	}
+	if len(s.ids) == 0 {
+		return []Match{}, nil
+	}
+
+	if query.K <= 0 {
+		return s.bruteForce(query), nil
+	}
+
+	q := normalize(query.Vector)
+	ef := max(s.efSearch, query.K)
+	for {
+		candidates := s.searchFromEntry(q, ef)
+
+		matches := []Match{}
+		for _, candidate := range candidates {
+			node := s.nodes[candidate.id]
+			if node.deleted {
+				continue
+			}
+			if match, ok := scoreRecord(node.record, query); ok {
+				matches = append(matches, match)
+			}
+		}
+
+		// The filters and the tombstones may leave fewer than K matches: widen the search
+		enough := len(matches) >= query.K || ef >= len(s.nodes)
+		belowMinScore := len(query.Filter) == 0 && len(candidates) > 0 && 1-candidates[len(candidates)-1].dist < query.MinScore
+		if enough || belowMinScore {
+			return sortMatches(matches, query.K), nil
+		}
+		ef *= 2
+	}
+}
+
+// bruteForce compares the query with every record
+// The caller must hold the lock
+func (s *HNSWStore) bruteForce(query Query) []Match {
+	matches := []Match{}
+	for _, node := range s.nodes {
+		if node.deleted {
+			continue
+		}
+		if match, ok := scoreRecord(node.record, query); ok {
+			matches = append(matches, match)
+		}
+	}
+	return sortMatches(matches, query.K)
+}
+
+func (s *HNSWStore) Get(ctx context.Context, ids []string) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	records := []Record{}
+	for _, id := range ids {
+		if node, ok := s.ids[id]; ok {
+			records = append(records, s.nodes[node].record)
+		}
+	}
+	return records, nil
+}
+
+func (s *HNSWStore) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+	return len(s.ids), nil
+}
+
+func (s *HNSWStore) Scan(ctx context.Context, fn func(Record) error) error {
+	s.mu.RLock()
+	records := make([]Record, 0, len(s.ids))
+	for _, node := range s.nodes {
+		if !node.deleted {
+			records = append(records, node.record)
+		}
+	}
+	closed := s.closed
+	s.mu.RUnlock()
+
+	if closed {
+		return ErrClosed
+	}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compact rebuilds the graph without the tombstones of the deleted and replaced records
+func (s *HNSWStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.deleted == 0 {
+		return nil
+	}
+
+	nodes, dimension := s.nodes, s.dimension
+	s.reset()
+	s.dimension = dimension
+	for _, node := range nodes {
+		if !node.deleted {
+			s.insert(node.record)
+		}
+	}
+	return nil
+}
+
+// Path returns the file of the index set with WithHNSWFile
+func (s *HNSWStore) Path() string {
+	return s.path
+}
+
+// Save writes the index to the file set with WithHNSWFile
+func (s *HNSWStore) Save() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.path == "" {
+		return fmt.Errorf("no file to save the index, use WithHNSWFile")
+	}
+	return s.save()
+}
+
+// Close saves the index if it has a file
+func (s *HNSWStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.path != "" {
+		return s.save()
+	}
+	return nil
+}
+
+// hnswSnapshot is the content of the file of an index
+type hnswSnapshot struct {
+	Version        int                `json:"version"`
+	M              int                `json:"m"`
+	EfConstruction int                `json:"ef_construction"`
+	EfSearch       int                `json:"ef_search"`
+	Dimension      int                `json:"dimension"`
+	Entry          int                `json:"entry"`
+	MaxLevel       int                `json:"max_level"`
+	Nodes          []hnswNodeSnapshot `json:"nodes"`
+}
+
+type hnswNodeSnapshot struct {
+	Record    Record  `json:"record"`
+	Level     int     `json:"level"`
+	Neighbors [][]int `json:"neighbors"`
+	Deleted   bool    `json:"deleted,omitempty"`
+}
+
+// save writes the index through a temporary file
+// The caller must hold the lock
+func (s *HNSWStore) save() error {
+	snapshot := hnswSnapshot{
+		Version:        1,
+		M:              s.m,
+		EfConstruction: s.efConstruction,
+		EfSearch:       s.efSearch,
+		Dimension:      s.dimension,
+		Entry:          s.entry,
+		MaxLevel:       s.maxLevel,
+		Nodes:          make([]hnswNodeSnapshot, len(s.nodes)),
+	}
+	for i, node := range s.nodes {
+		snapshot.Nodes[i] = hnswNodeSnapshot{Record: node.record, Level: node.level, Neighbors: node.neighbors, Deleted: node.deleted}
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error encoding HNSW index: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// load reads the file of the index, if it exists
+func (s *HNSWStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading HNSW index: %w", err)
+	}
+
+	var snapshot hnswSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("error parsing HNSW index: %w", err)
+	}
+
+	if s.m == 0 {
+		s.m = snapshot.M
+	}
+	if s.efConstruction == 0 {
+		s.efConstruction = snapshot.EfConstruction
+	}
+	if s.efSearch == 0 {
+		s.efSearch = snapshot.EfSearch
+	}
+	s.dimension = snapshot.Dimension
+	s.entry = snapshot.Entry
+	s.maxLevel = snapshot.MaxLevel
+	for i, n := range snapshot.Nodes {
+		s.nodes = append(s.nodes, &hnswNode{
+			record:    n.Record,
+			vector:    normalize(n.Record.Embedding),
+			level:     n.Level,
+			neighbors: n.Neighbors,
+			deleted:   n.Deleted,
+		})
+		if n.Deleted {
+			s.deleted++
+		} else {
+			s.ids[n.Record.ID] = i
+		}
+	}
+	return nil
+}
+
+// insert adds a node to the graph
+// The caller must hold the lock
+func (s *HNSWStore) insert(record Record) {
+	level := int(math.Floor(-math.Log(1-s.rng.Float64()) * s.levelMult))
+	node := &hnswNode{
+		record:    record,
+		vector:    normalize(record.Embedding),
+		level:     level,
+		neighbors: make([][]int, level+1),
+	}
+	id := len(s.nodes)
+	s.nodes = append(s.nodes, node)
+	s.ids[record.ID] = id
+
+	if s.entry < 0 {
+		s.entry = id
+		s.maxLevel = level
+		return
+	}
+
+	entryPoints := []hnswCandidate{{id: s.entry, dist: s.distance(node.vector, s.entry)}}
+	for layer := s.maxLevel; layer > level; layer-- {
+		entryPoints = s.searchLayer(node.vector, entryPoints, 1, layer)
+	}
+
+	for layer := min(level, s.maxLevel); layer >= 0; layer-- {
+		candidates := s.searchLayer(node.vector, entryPoints, s.efConstruction, layer)
+		neighbors := s.selectNeighbors(candidates, s.m)
+		node.neighbors[layer] = make([]int, len(neighbors))
+		for i, neighbor := range neighbors {
+			node.neighbors[layer][i] = neighbor.id
+			s.connect(neighbor.id, id, layer)
+		}
+		entryPoints = candidates
+	}
+
+	if level > s.maxLevel {
+		s.maxLevel = level
+		s.entry = id
+	}
+}
+
+// connect adds the link from a node to another, pruning the links of the node if it has too many
+func (s *HNSWStore) connect(from, to, layer int) {
+	node := s.nodes[from]
+	node.neighbors[layer] = append(node.neighbors[layer], to)
+
+	maxNeighbors := s.m
+	if layer == 0 {
+		maxNeighbors = s.mMax0
+	}
+	if len(node.neighbors[layer]) <= maxNeighbors {
+		return
+	}
+
+	candidates := make([]hnswCandidate, len(node.neighbors[layer]))
+	for i, neighbor := range node.neighbors[layer] {
+		candidates[i] = hnswCandidate{id: neighbor, dist: s.distance(node.vector, neighbor)}
+	}
+	sortCandidates(candidates)
+
+	kept := s.selectNeighbors(candidates, maxNeighbors)
+	node.neighbors[layer] = node.neighbors[layer][:0]
+	for _, candidate := range kept {
+		node.neighbors[layer] = append(node.neighbors[layer], candidate.id)
+	}
+}
+
+// selectNeighbors picks up to m neighbours among candidates sorted by distance, with the heuristic of the paper:
+// a candidate closer to an already selected neighbour than to the node is skipped, so that the links
+// go in different directions; the skipped candidates fill the remaining places
+func (s *HNSWStore) selectNeighbors(candidates []hnswCandidate, m int) []hnswCandidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+
+	selected := make([]hnswCandidate, 0, m)
+	var skipped []hnswCandidate
+	for _, candidate := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		diverse := true
+		for _, neighbor := range selected {
+			if s.distance(s.nodes[candidate.id].vector, neighbor.id) < candidate.dist {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, candidate)
+		} else {
+			skipped = append(skipped, candidate)
+		}
+	}
+	for _, candidate := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, candidate)
+	}
+	return selected
+}
+
+// searchFromEntry goes down the layers from the entry point and returns the ef closest nodes of the bottom layer
+func (s *HNSWStore) searchFromEntry(q []float32, ef int) []hnswCandidate {
+	entryPoints := []hnswCandidate{{id: s.entry, dist: s.distance(q, s.entry)}}
+	for layer := s.maxLevel; layer > 0; layer-- {
+		entryPoints = s.searchLayer(q, entryPoints, 1, layer)
+	}
+	return s.searchLayer(q, entryPoints, ef, 0)
+}
+
+// searchLayer returns the ef nodes of a layer closest to q, sorted by distance
+func (s *HNSWStore) searchLayer(q []float32, entryPoints []hnswCandidate, ef, layer int) []hnswCandidate {
+	visited := make(map[int]struct{}, ef*4)
+	candidates := &candidateHeap{}
+	results := &candidateHeap{farthestFirst: true}
+	for _, entryPoint := range entryPoints {
+		visited[entryPoint.id] = struct{}{}
+		heap.Push(candidates, entryPoint)
+		heap.Push(results, entryPoint)
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		closest := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && closest.dist > results.items[0].dist {
+			break
+		}
+
+		for _, neighbor := range s.nodes[closest.id].neighbors[layer] {
+			if _, seen := visited[neighbor]; seen {
+				continue
+			}
+			visited[neighbor] = struct{}{}
+
+			dist := s.distance(q, neighbor)
+			if results.Len() < ef || dist < results.items[0].dist {
+				candidate := hnswCandidate{id: neighbor, dist: dist}
+				heap.Push(candidates, candidate)
+				heap.Push(results, candidate)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := results.items
+	sortCandidates(sorted)
+	return sorted
+}
+
+// distance returns the cosine distance between a normalized vector and a node
+func (s *HNSWStore) distance(q []float32, id int) float64 {
+	v := s.nodes[id].vector
+	var dot float64
+	for i := range q {
+		dot += float64(q[i]) * float64(v[i])
+	}
+	return 1 - dot
+}
+
+// normalize returns a copy of the vector with a norm of 1 (the zero vector is kept as is)
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	normalized := make([]float32, len(vector))
+	if norm == 0 {
+		return normalized
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// hnswCandidate is a node with its distance to the query
+type hnswCandidate struct {
+	id   int
+	dist float64
+}
+
+// sortCandidates sorts the candidates by ascending distance
+func sortCandidates(candidates []hnswCandidate) {
+	slices.SortFunc(candidates, func(a, b hnswCandidate) int {
+		return cmp.Compare(a.dist, b.dist)
+	})
+}
+
+// candidateHeap is a heap of candidates, closest first (or farthest first)
+type candidateHeap struct {
+	items         []hnswCandidate
+	farthestFirst bool
+}
+
+func (h candidateHeap) Len() int { return len(h.items) }
+func (h candidateHeap) Less(i, j int) bool {
+	if h.farthestFirst {
+		return h.items[i].dist > h.items[j].dist
+	}
+	return h.items[i].dist < h.items[j].dist
+}
+func (h candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x any)   { h.items = append(h.items, x.(hnswCandidate)) }
+func (h *candidateHeap) Pop() any {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
@@ -0,0 +1,240 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/plugins/localvec"
+)
+
+// LocalvecStore adapts a Genkit localvec document store (a single JSON file "__db_<name>.json") to VectorStore
+// It is the default store of the RAG agents, and reads the files written by previous versions
+// NOTE: localvec rewrites the whole file at each write, prefer SegmentedStore for large stores
+type LocalvecStore struct {
+	mu        sync.RWMutex
+	docStore  *localvec.DocStore
+	dimension int
+	closed    bool
+}
+
+// NewLocalvecStore wraps a localvec document store (e.g. the one returned by localvec.DefineRetriever)
+func NewLocalvecStore(docStore *localvec.DocStore) *LocalvecStore {
+	s := &LocalvecStore{docStore: docStore}
+	for _, value := range docStore.Data {
+		s.dimension = len(value.Embedding)
+		break
+	}
+	return s
+}
+
+// OpenLocalvecStore opens the localvec store name in dir without Genkit (e.g. to migrate it)
+// The store is empty if the file does not exist
+func OpenLocalvecStore(dir, name string) (*LocalvecStore, error) {
+	filename := filepath.Join(dir, "__db_"+name+".json")
+
+	var data map[string]localvec.DbValue
+	content, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating store directory: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("error reading localvec store: %w", err)
+	default:
+		if err := json.Unmarshal(content, &data); err != nil {
+			return nil, fmt.Errorf("error parsing localvec store: %w", err)
+		}
+	}
+
+	return NewLocalvecStore(&localvec.DocStore{Filename: filename, Data: data}), nil
+}
+
+// Path returns the file of the localvec store
+func (s *LocalvecStore) Path() string {
+	return s.docStore.Filename
+}
+
+// DocStore returns the wrapped localvec document store
+func (s *LocalvecStore) DocStore() *localvec.DocStore {
+	return s.docStore
+}
+
+func (s *LocalvecStore) Add(ctx context.Context, records []Record) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added []Record
+	for _, record := range records {
+		if _, exists := s.docStore.Data[record.ID]; !exists {
+			added = append(added, record)
+		}
+	}
+	return len(added), s.put(added)
+}
+
+func (s *LocalvecStore) Upsert(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.put(records)
+}
+
+// put writes the records and saves the file
+// The caller must hold the lock
+func (s *LocalvecStore) put(records []Record) error {
+	if s.closed {
+		return ErrClosed
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	dimension, err := validateRecords(records, s.dimension)
+	if err != nil {
+		return err
+	}
+
+	if s.docStore.Data == nil {
+		s.docStore.Data = make(map[string]localvec.DbValue)
+	}
+	for _, record := range records {
+		s.docStore.Data[record.ID] = localvec.DbValue{
+			Doc:       ai.DocumentFromText(record.Content, record.Metadata),
+			Embedding: record.Embedding,
+		}
+	}
+	s.dimension = dimension
+	return s.save()
+}
+
+func (s *LocalvecStore) Delete(ctx context.Context, ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+	deleted := 0
+	for _, id := range ids {
+		if _, exists := s.docStore.Data[id]; exists {
+			delete(s.docStore.Data, id)
+			deleted++
+		}
+	}
+	if deleted == 0 {
+		return 0, nil
+	}
+	if len(s.docStore.Data) == 0 {
+		s.dimension = 0
+	}
+	return deleted, s.save()
+}
+
+func (s *LocalvecStore) Search(ctx context.Context, query Query) ([]Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.dimension != 0 && len(query.Vector) != s.dimension {
+		return nil, fmt.Errorf("%w: query has dimension %d, want %d", ErrDimensionMismatch, len(query.Vector), s.dimension)
+	}
+
+	matches := []Match{}
+	for id, value := range s.docStore.Data {
+		if match, ok := scoreRecord(localvecRecord(id, value), query); ok {
+			matches = append(matches, match)
+		}
+	}
+	return sortMatches(matches, query.K), nil
+}
+
+func (s *LocalvecStore) Get(ctx context.Context, ids []string) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	records := []Record{}
+	for _, id := range ids {
+		if value, ok := s.docStore.Data[id]; ok {
+			records = append(records, localvecRecord(id, value))
+		}
+	}
+	return records, nil
+}
+
+func (s *LocalvecStore) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+	return len(s.docStore.Data), nil
+}
+
+func (s *LocalvecStore) Scan(ctx context.Context, fn func(Record) error) error {
+	s.mu.RLock()
+	records := make([]Record, 0, len(s.docStore.Data))
+	for id, value := range s.docStore.Data {
+		records = append(records, localvecRecord(id, value))
+	}
+	closed := s.closed
+	s.mu.RUnlock()
+
+	if closed {
+		return ErrClosed
+	}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalvecStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+// save writes the store file as localvec does (the whole map, through a temporary file)
+func (s *LocalvecStore) save() error {
+	data, err := json.Marshal(s.docStore.Data)
+	if err != nil {
+		return fmt.Errorf("error encoding localvec store: %w", err)
+	}
+	return writeFileAtomic(s.docStore.Filename, data)
+}
+
+// localvecRecord converts a localvec document to a record
+func localvecRecord(id string, value localvec.DbValue) Record {
+	record := Record{ID: id, Embedding: value.Embedding}
+	if value.Doc != nil {
+		var content strings.Builder
+		for _, part := range value.Doc.Content {
+			if part.IsText() {
+				content.WriteString(part.Text)
+			}
+		}
+		record.Content = content.String()
+		record.Metadata = value.Doc.Metadata
+	}
+	return record
+}
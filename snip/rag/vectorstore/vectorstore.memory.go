@@ -0,0 +1,167 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryStore is a vector store kept in memory, searched by brute force
+// It is lost when the process stops, use SegmentedStore to keep the records on disk
+type MemoryStore struct {
+	mu        sync.RWMutex
+	records   map[string]Record
+	dimension int
+	closed    bool
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func (s *MemoryStore) Add(ctx context.Context, records []Record) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added, err := s.add(records, false)
+	return len(added), err
+}
+
+func (s *MemoryStore) Upsert(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.add(records, true)
+	return err
+}
+
+// add stores the records (all of them if replace, else the new ones) and returns the records stored
+// The caller must hold the lock
+func (s *MemoryStore) add(records []Record, replace bool) ([]Record, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+	dimension, err := validateRecords(records, s.dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []Record
+	for _, record := range records {
+		if _, exists := s.records[record.ID]; exists && !replace {
+			continue
+		}
+		s.records[record.ID] = record
+		stored = append(stored, record)
+	}
+	if len(s.records) > 0 {
+		s.dimension = dimension
+	}
+	return stored, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted, err := s.delete(ids)
+	return len(deleted), err
+}
+
+// delete removes the records and returns the IDs of the records removed
+// The caller must hold the lock
+func (s *MemoryStore) delete(ids []string) ([]string, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+	var deleted []string
+	for _, id := range ids {
+		if _, exists := s.records[id]; exists {
+			delete(s.records, id)
+			deleted = append(deleted, id)
+		}
+	}
+	if len(s.records) == 0 {
+		s.dimension = 0
+	}
+	return deleted, nil
+}
+
+func (s *MemoryStore) Search(ctx context.Context, query Query) ([]Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.dimension != 0 && len(query.Vector) != s.dimension {
+		return nil, fmt.Errorf("%w: query has dimension %d, want %d", ErrDimensionMismatch, len(query.Vector), s.dimension)
+	}
+
+	matches := []Match{}
+	for _, record := range s.records {
+		if match, ok := scoreRecord(record, query); ok {
+			matches = append(matches, match)
+		}
+	}
+	return sortMatches(matches, query.K), nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, ids []string) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	records := []Record{}
+	for _, id := range ids {
+		if record, ok := s.records[id]; ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (s *MemoryStore) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+	return len(s.records), nil
+}
+
+func (s *MemoryStore) Scan(ctx context.Context, fn func(Record) error) error {
+	s.mu.RLock()
+	records := make([]Record, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	closed := s.closed
+	s.mu.RUnlock()
+
+	if closed {
+		return ErrClosed
+	}
+	// fn is called without the lock, so that it can use the store
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return nil
+}
@@ -0,0 +1,48 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultMigrationBatchSize is the default number of records written at once by Migrate
+const DefaultMigrationBatchSize = 500
+
+// Migrate copies the records of src to dst, replacing the records of dst with the same IDs,
+// and returns the number of records copied (batchSize records are written at once, DefaultMigrationBatchSize if zero)
+// e.g. to move the store of a RAG agent from localvec to a SegmentedStore:
+//
+//	src, _ := vectorstore.OpenLocalvecStore("./store", "docs")
+//	dst, _ := vectorstore.NewSegmentedStore("./store/docs")
+//	count, err := vectorstore.Migrate(ctx, src, dst, 0)
+func Migrate(ctx context.Context, src, dst VectorStore, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultMigrationBatchSize
+	}
+
+	copied := 0
+	batch := make([]Record, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := dst.Upsert(ctx, batch); err != nil {
+			return fmt.Errorf("error writing records: %w", err)
+		}
+		copied += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := src.Scan(ctx, func(record Record) error {
+		batch = append(batch, record)
+		if len(batch) == batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	return copied, err
+}
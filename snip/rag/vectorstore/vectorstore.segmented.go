@@ -0,0 +1,439 @@
+package vectorstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultSegmentSize is the default number of entries of a segment of a SegmentedStore
+	DefaultSegmentSize = 10000
+
+	segmentManifestFile = "manifest.json"
+)
+
+// segmentEntry is a line of a segment: a record written, or a record deleted
+type segmentEntry struct {
+	Op     string  `json:"op"`
+	Record *Record `json:"record,omitempty"`
+	ID     string  `json:"id,omitempty"`
+}
+
+// segmentManifest lists the segments of a store, in the order they are replayed
+type segmentManifest struct {
+	Version  int      `json:"version"`
+	Segments []string `json:"segments"`
+	Next     int      `json:"next"`
+}
+
+// SegmentedStore is a vector store kept in memory and persisted in a directory as append-only segments
+// (JSON lines of the records written and deleted). A write only appends the changed records to the current
+// segment, instead of rewriting the whole store as localvec does; a new segment is started every
+// segment size entries. Compact rewrites the segments without the deleted and replaced records
+type SegmentedStore struct {
+	// memory holds the records, its lock also protects the segments
+	memory *MemoryStore
+
+	dir         string
+	segmentSize int
+	sync        bool
+
+	manifest segmentManifest
+	active   *os.File
+	// activeEntries is the number of entries of the active segment
+	activeEntries int
+}
+
+// SegmentedStoreOption defines a functional option for configuring a SegmentedStore
+type SegmentedStoreOption func(*SegmentedStore)
+
+// WithSegmentSize sets the number of entries after which a new segment is started (DefaultSegmentSize by default)
+func WithSegmentSize(entries int) SegmentedStoreOption {
+	return func(s *SegmentedStore) {
+		if entries > 0 {
+			s.segmentSize = entries
+		}
+	}
+}
+
+// WithSync flushes the segment to the disk after each write (slower, but no write is lost on a power failure)
+func WithSync(enabled bool) SegmentedStoreOption {
+	return func(s *SegmentedStore) {
+		s.sync = enabled
+	}
+}
+
+// NewSegmentedStore opens the store in dir, or creates it if dir has no store
+// An entry partially written when the process stopped is dropped
+func NewSegmentedStore(dir string, opts ...SegmentedStoreOption) (*SegmentedStore, error) {
+	s := &SegmentedStore{
+		memory:      NewMemoryStore(),
+		dir:         dir,
+		segmentSize: DefaultSegmentSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating store directory: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, segmentManifestFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.manifest = segmentManifest{Version: 1}
+	case err != nil:
+		return nil, fmt.Errorf("error reading store manifest: %w", err)
+	default:
+		if err := json.Unmarshal(data, &s.manifest); err != nil {
+			return nil, fmt.Errorf("error parsing store manifest: %w", err)
+		}
+	}
+
+	for i, segment := range s.manifest.Segments {
+		last := i == len(s.manifest.Segments)-1
+		entries, err := s.replay(segment, last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			s.activeEntries = entries
+		}
+	}
+
+	if len(s.manifest.Segments) == 0 {
+		if err := s.startSegment(); err != nil {
+			return nil, err
+		}
+	} else if err := s.openActive(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// replay applies the entries of a segment to the memory store and returns the number of entries
+// A truncated last line of the last segment is removed from the file
+func (s *SegmentedStore) replay(segment string, last bool) (int, error) {
+	path := filepath.Join(s.dir, segment)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("error reading segment %s: %w", segment, err)
+	}
+
+	entries := 0
+	offset := 0
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) == 0 {
+				break
+			}
+			// The last line was not completely written
+			if !last {
+				return 0, fmt.Errorf("segment %s is truncated", segment)
+			}
+			if err := os.Truncate(path, int64(offset)); err != nil {
+				return 0, fmt.Errorf("error repairing segment %s: %w", segment, err)
+			}
+			break
+		}
+
+		var entry segmentEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return 0, fmt.Errorf("error parsing segment %s at offset %d: %w", segment, offset, err)
+		}
+		switch {
+		case entry.Op == "put" && entry.Record != nil:
+			if _, err := s.memory.add([]Record{*entry.Record}, true); err != nil {
+				return 0, fmt.Errorf("error loading segment %s: %w", segment, err)
+			}
+		case entry.Op == "delete":
+			s.memory.delete([]string{entry.ID})
+		default:
+			return 0, fmt.Errorf("unknown entry %q in segment %s", entry.Op, segment)
+		}
+		offset += len(line)
+		entries++
+	}
+	return entries, nil
+}
+
+// segmentName returns the file name of the segment number n
+func segmentName(n int) string {
+	return fmt.Sprintf("segment-%06d.jsonl", n)
+}
+
+// startSegment creates a new segment, makes it the active one and saves the manifest
+func (s *SegmentedStore) startSegment() error {
+	s.manifest.Next++
+	name := segmentName(s.manifest.Next)
+	s.manifest.Segments = append(s.manifest.Segments, name)
+
+	if err := s.closeActive(); err != nil {
+		return err
+	}
+	if err := s.openActive(); err != nil {
+		return err
+	}
+	s.activeEntries = 0
+	return s.saveManifest()
+}
+
+// openActive opens the last segment for appending
+func (s *SegmentedStore) openActive() error {
+	name := s.manifest.Segments[len(s.manifest.Segments)-1]
+	file, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening segment %s: %w", name, err)
+	}
+	s.active = file
+	return nil
+}
+
+// closeActive closes the active segment, if any
+func (s *SegmentedStore) closeActive() error {
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+// saveManifest writes the manifest through a temporary file
+func (s *SegmentedStore) saveManifest() error {
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding store manifest: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(s.dir, segmentManifestFile), data)
+}
+
+// appendEntries writes the entries to the active segment, starting a new segment when it is full
+func (s *SegmentedStore) appendEntries(entries []segmentEntry) error {
+	for len(entries) > 0 {
+		if s.activeEntries >= s.segmentSize {
+			if err := s.startSegment(); err != nil {
+				return err
+			}
+		}
+
+		n := min(len(entries), s.segmentSize-s.activeEntries)
+		var buffer bytes.Buffer
+		for _, entry := range entries[:n] {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return fmt.Errorf("error encoding entry: %w", err)
+			}
+			buffer.Write(line)
+			buffer.WriteByte('\n')
+		}
+		if _, err := s.active.Write(buffer.Bytes()); err != nil {
+			return fmt.Errorf("error writing segment: %w", err)
+		}
+		if s.sync {
+			if err := s.active.Sync(); err != nil {
+				return fmt.Errorf("error syncing segment: %w", err)
+			}
+		}
+		s.activeEntries += n
+		entries = entries[n:]
+	}
+	return nil
+}
+
+func (s *SegmentedStore) Add(ctx context.Context, records []Record) (int, error) {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	var added []Record
+	for _, record := range records {
+		if _, exists := s.memory.records[record.ID]; !exists {
+			added = append(added, record)
+		}
+	}
+	return len(added), s.put(added)
+}
+
+func (s *SegmentedStore) Upsert(ctx context.Context, records []Record) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	return s.put(records)
+}
+
+// put writes the records to the segment, then to the memory store
+// The caller must hold the lock
+func (s *SegmentedStore) put(records []Record) error {
+	if s.memory.closed {
+		return ErrClosed
+	}
+	if _, err := validateRecords(records, s.memory.dimension); err != nil {
+		return err
+	}
+
+	entries := make([]segmentEntry, len(records))
+	for i := range records {
+		entries[i] = segmentEntry{Op: "put", Record: &records[i]}
+	}
+	if err := s.appendEntries(entries); err != nil {
+		return err
+	}
+	_, err := s.memory.add(records, true)
+	return err
+}
+
+func (s *SegmentedStore) Delete(ctx context.Context, ids []string) (int, error) {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	if s.memory.closed {
+		return 0, ErrClosed
+	}
+	var entries []segmentEntry
+	var existing []string
+	for _, id := range ids {
+		if _, exists := s.memory.records[id]; exists {
+			entries = append(entries, segmentEntry{Op: "delete", ID: id})
+			existing = append(existing, id)
+		}
+	}
+	if err := s.appendEntries(entries); err != nil {
+		return 0, err
+	}
+	deleted, err := s.memory.delete(existing)
+	return len(deleted), err
+}
+
+// Compact rewrites the segments with the current records only, dropping the deleted and replaced ones
+// The old segments are removed once the manifest points to the new ones, so a failure keeps the store as it was
+func (s *SegmentedStore) Compact() error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	if s.memory.closed {
+		return ErrClosed
+	}
+
+	entries := make([]segmentEntry, 0, len(s.memory.records))
+	for _, record := range s.memory.records {
+		record := record
+		entries = append(entries, segmentEntry{Op: "put", Record: &record})
+	}
+
+	compacted := segmentManifest{Version: s.manifest.Version, Next: s.manifest.Next}
+	lastEntries := 0
+	for first := true; first || len(entries) > 0; first = false {
+		n := min(len(entries), s.segmentSize)
+		compacted.Next++
+		name := segmentName(compacted.Next)
+		if err := writeSegment(filepath.Join(s.dir, name), entries[:n]); err != nil {
+			for _, segment := range compacted.Segments {
+				os.Remove(filepath.Join(s.dir, segment))
+			}
+			return err
+		}
+		compacted.Segments = append(compacted.Segments, name)
+		lastEntries = n
+		entries = entries[n:]
+	}
+
+	oldManifest := s.manifest
+	s.manifest = compacted
+	if err := s.saveManifest(); err != nil {
+		s.manifest = oldManifest
+		return err
+	}
+
+	s.closeActive()
+	for _, segment := range oldManifest.Segments {
+		os.Remove(filepath.Join(s.dir, segment))
+	}
+	s.activeEntries = lastEntries
+	return s.openActive()
+}
+
+// writeSegment writes a complete segment file and flushes it to the disk
+func writeSegment(path string, entries []segmentEntry) error {
+	var buffer bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("error encoding entry: %w", err)
+		}
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error writing segment: %w", err)
+	}
+	if _, err := file.Write(buffer.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing segment: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error syncing segment: %w", err)
+	}
+	return file.Close()
+}
+
+func (s *SegmentedStore) Search(ctx context.Context, query Query) ([]Match, error) {
+	return s.memory.Search(ctx, query)
+}
+
+func (s *SegmentedStore) Get(ctx context.Context, ids []string) ([]Record, error) {
+	return s.memory.Get(ctx, ids)
+}
+
+func (s *SegmentedStore) Count(ctx context.Context) (int, error) {
+	return s.memory.Count(ctx)
+}
+
+func (s *SegmentedStore) Scan(ctx context.Context, fn func(Record) error) error {
+	return s.memory.Scan(ctx, fn)
+}
+
+// Path returns the directory of the segments
+func (s *SegmentedStore) Path() string {
+	return s.dir
+}
+
+// Close closes the active segment
+func (s *SegmentedStore) Close() error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	if s.memory.closed {
+		return nil
+	}
+	s.memory.closed = true
+	if s.active != nil && s.sync {
+		s.active.Sync()
+	}
+	return s.closeActive()
+}
+
+// writeFileAtomic writes a file through a temporary file, so that readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+)
+
+// randomRecords returns n records with random embeddings
+func randomRecords(n, dimension int, seed uint64) []Record {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	records := make([]Record, n)
+	for i := range records {
+		embedding := make([]float32, dimension)
+		for j := range embedding {
+			embedding[j] = rng.Float32()*2 - 1
+		}
+		records[i] = Record{
+			ID:        fmt.Sprintf("record-%d", i),
+			Content:   fmt.Sprintf("chunk %d", i),
+			Metadata:  map[string]any{"even": i%2 == 0},
+			Embedding: embedding,
+		}
+	}
+	return records
+}
+
+// recall returns the fraction of the expected matches found by the approximate search
+func recall(got, want []Match) float64 {
+	expected := map[string]bool{}
+	for _, match := range want {
+		expected[match.ID] = true
+	}
+	found := 0
+	for _, match := range got {
+		if expected[match.ID] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(want))
+}
+
+func TestHNSWStore(t *testing.T) {
+	store, err := NewHNSWStore(WithM(8), WithEfConstruction(64))
+	if err != nil {
+		t.Fatalf("NewHNSWStore() unexpected error: %v", err)
+	}
+	testVectorStore(t, store)
+}
+
+func TestHNSWStoreRecall(t *testing.T) {
+	ctx := context.Background()
+	records := randomRecords(3000, 32, 1)
+	queries := randomRecords(50, 32, 2)
+
+	exact := NewMemoryStore()
+	if _, err := exact.Add(ctx, records); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	store, err := NewHNSWStore()
+	if err != nil {
+		t.Fatalf("NewHNSWStore() unexpected error: %v", err)
+	}
+	if _, err := store.Add(ctx, records); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	total := 0.0
+	for _, query := range queries {
+		want, _ := exact.Search(ctx, Query{Vector: query.Embedding, K: 10})
+		got, _ := store.Search(ctx, Query{Vector: query.Embedding, K: 10})
+		total += recall(got, want)
+	}
+	if average := total / float64(len(queries)); average < 0.9 {
+		t.Errorf("recall@10 = %.3f, want at least 0.9", average)
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		filter := map[string]any{"even": true}
+		for _, query := range queries[:10] {
+			want, _ := exact.Search(ctx, Query{Vector: query.Embedding, K: 10, Filter: filter})
+			got, _ := store.Search(ctx, Query{Vector: query.Embedding, K: 10, Filter: filter})
+			if len(got) != 10 {
+				t.Fatalf("Search() with filter returned %d matches, want 10", len(got))
+			}
+			if r := recall(got, want); r < 0.7 {
+				t.Errorf("recall@10 with filter = %.2f, want at least 0.7", r)
+			}
+		}
+	})
+
+	t.Run("deletes", func(t *testing.T) {
+		query := queries[0].Embedding
+		best, _ := store.Search(ctx, Query{Vector: query, K: 1})
+		store.Delete(ctx, []string{best[0].ID})
+
+		matches, _ := store.Search(ctx, Query{Vector: query, K: 10})
+		for _, match := range matches {
+			if match.ID == best[0].ID {
+				t.Errorf("Search() returned the deleted record %s", best[0].ID)
+			}
+		}
+		if len(matches) != 10 {
+			t.Errorf("Search() returned %d matches, want 10", len(matches))
+		}
+	})
+}
+
+func TestHNSWStorePersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index.json")
+	records := randomRecords(500, 16, 3)
+
+	store, err := NewHNSWStore(WithHNSWFile(path), WithM(12))
+	if err != nil {
+		t.Fatalf("NewHNSWStore() unexpected error: %v", err)
+	}
+	if _, err := store.Add(ctx, records); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if _, err := store.Delete(ctx, []string{"record-0"}); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	before, _ := store.Search(ctx, Query{Vector: records[1].Embedding, K: 5})
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	loaded, err := NewHNSWStore(WithHNSWFile(path))
+	if err != nil {
+		t.Fatalf("NewHNSWStore() loading the file unexpected error: %v", err)
+	}
+	if loaded.m != 12 {
+		t.Errorf("M = %d, want the M of the file", loaded.m)
+	}
+	if count, _ := loaded.Count(ctx); count != 499 {
+		t.Errorf("Count() = %d, want 499", count)
+	}
+	after, _ := loaded.Search(ctx, Query{Vector: records[1].Embedding, K: 5})
+	if recall(after, before) != 1 {
+		t.Errorf("Search() after loading = %v, want %v", after, before)
+	}
+
+	// Incremental inserts on the loaded index
+	loaded.Add(ctx, []Record{{ID: "new", Content: "new", Embedding: records[1].Embedding}})
+	matches, _ := loaded.Search(ctx, Query{Vector: records[1].Embedding, K: 2})
+	if len(matches) != 2 || (matches[0].ID != "new" && matches[1].ID != "new") {
+		t.Errorf("Search() = %+v, want the inserted record", matches)
+	}
+
+	if err := loaded.Compact(); err != nil {
+		t.Fatalf("Compact() unexpected error: %v", err)
+	}
+	if len(loaded.nodes) != 500 {
+		t.Errorf("%d nodes after Compact(), want 500", len(loaded.nodes))
+	}
+}
+
+// BenchmarkSearch compares the HNSW index with the brute force search of MemoryStore
+// The recall@10 of the HNSW index is reported for each efSearch:
+//
+//	go test ./snip/rag/vectorstore -run '^$' -bench Search -benchtime 200x
+func BenchmarkSearch(b *testing.B) {
+	ctx := context.Background()
+	records := randomRecords(10000, 64, 1)
+	queries := randomRecords(100, 64, 2)
+
+	exact := NewMemoryStore()
+	exact.Add(ctx, records)
+	expected := make([][]Match, len(queries))
+	for i, query := range queries {
+		expected[i], _ = exact.Search(ctx, Query{Vector: query.Embedding, K: 10})
+	}
+
+	b.Run("brute-force", func(b *testing.B) {
+		for i := 0; b.Loop(); i++ {
+			exact.Search(ctx, Query{Vector: queries[i%len(queries)].Embedding, K: 10})
+		}
+	})
+
+	index, _ := NewHNSWStore()
+	index.Add(ctx, records)
+	for _, ef := range []int{16, 64, 256} {
+		b.Run(fmt.Sprintf("hnsw-ef%d", ef), func(b *testing.B) {
+			index.SetEfSearch(ef)
+			total := 0.0
+			for i, query := range queries {
+				matches, _ := index.Search(ctx, Query{Vector: query.Embedding, K: 10})
+				total += recall(matches, expected[i])
+			}
+
+			for i := 0; b.Loop(); i++ {
+				index.Search(ctx, Query{Vector: queries[i%len(queries)].Embedding, K: 10})
+			}
+			b.ReportMetric(total/float64(len(queries)), "recall@10")
+		})
+	}
+}
+
+func TestHNSWStoreDeleteAllNewDimension(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewHNSWStore()
+	if err != nil {
+		t.Fatalf("NewHNSWStore() unexpected error: %v", err)
+	}
+	if _, err := store.Add(ctx, []Record{{ID: "a", Content: "a", Embedding: []float32{1, 0}}}); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if _, err := store.Delete(ctx, []string{"a"}); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+
+	if err := store.Upsert(ctx, []Record{{ID: "b", Content: "b", Embedding: []float32{1, 0, 0, 0}}}); err != nil {
+		t.Fatalf("Upsert() with a new dimension after deleting all the records unexpected error: %v", err)
+	}
+	matches, err := store.Search(ctx, Query{Vector: []float32{1, 0, 0, 0}, K: 1})
+	if err != nil || len(matches) != 1 || matches[0].ID != "b" {
+		t.Errorf("Search() = %+v, %v, want b", matches, err)
+	}
+}
@@ -0,0 +1,254 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testRecords = []Record{
+	{ID: "dolphins", Content: "Dolphins swim in the ocean", Metadata: map[string]any{"category": "marine"}, Embedding: []float32{1, 0, 0}},
+	{ID: "whales", Content: "Whales also swim in the ocean", Metadata: map[string]any{"category": "marine"}, Embedding: []float32{0.8, 0.6, 0}},
+	{ID: "eagles", Content: "Eagles fly in the sky", Metadata: map[string]any{"category": "birds"}, Embedding: []float32{0, 0, 1}},
+}
+
+// testVectorStore checks the behavior shared by all the stores
+func testVectorStore(t *testing.T, store VectorStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	added, err := store.Add(ctx, testRecords)
+	if err != nil || added != 3 {
+		t.Fatalf("Add() = %d, %v, want 3", added, err)
+	}
+	if added, _ := store.Add(ctx, testRecords[:1]); added != 0 {
+		t.Errorf("Add() of an existing record = %d, want 0", added)
+	}
+
+	matches, err := store.Search(ctx, Query{Vector: []float32{1, 0, 0}, K: 2})
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != "dolphins" || matches[1].ID != "whales" {
+		t.Errorf("Search() = %+v, want dolphins and whales", matches)
+	}
+	if matches[0].Content != "Dolphins swim in the ocean" || matches[0].Metadata["category"] != "marine" {
+		t.Errorf("Search() match = %+v, want the content and metadata of the record", matches[0])
+	}
+
+	matches, _ = store.Search(ctx, Query{Vector: []float32{1, 0, 0}, Filter: map[string]any{"category": "birds"}})
+	if len(matches) != 1 || matches[0].ID != "eagles" {
+		t.Errorf("Search() with filter = %+v, want eagles", matches)
+	}
+	matches, _ = store.Search(ctx, Query{Vector: []float32{1, 0, 0}, MinScore: 0.5})
+	if len(matches) != 2 {
+		t.Errorf("Search() with min score returned %d matches, want 2", len(matches))
+	}
+
+	if _, err := store.Search(ctx, Query{Vector: []float32{1, 0}}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Search() error = %v, want ErrDimensionMismatch", err)
+	}
+	if err := store.Upsert(ctx, []Record{{ID: "bad", Embedding: []float32{1}}}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Upsert() error = %v, want ErrDimensionMismatch", err)
+	}
+
+	updated := Record{ID: "eagles", Content: "Eagles dive into the sea", Embedding: []float32{1, 0, 0}}
+	if err := store.Upsert(ctx, []Record{updated}); err != nil {
+		t.Fatalf("Upsert() unexpected error: %v", err)
+	}
+	if count, _ := store.Count(ctx); count != 3 {
+		t.Errorf("Count() after Upsert() = %d, want 3", count)
+	}
+
+	records, err := store.Get(ctx, []string{"eagles", "unknown", "dolphins"})
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].Content != "Eagles dive into the sea" || records[1].ID != "dolphins" {
+		t.Errorf("Get() = %+v, want eagles then dolphins", records)
+	}
+	if records[1].Metadata["category"] != "marine" || len(records[1].Embedding) != 3 {
+		t.Errorf("Get() record = %+v, want the metadata and embedding of the record", records[1])
+	}
+
+	deleted, err := store.Delete(ctx, []string{"whales", "unknown"})
+	if err != nil || deleted != 1 {
+		t.Errorf("Delete() = %d, %v, want 1", deleted, err)
+	}
+
+	seen := map[string]string{}
+	store.Scan(ctx, func(record Record) error {
+		seen[record.ID] = record.Content
+		return nil
+	})
+	want := map[string]string{"dolphins": "Dolphins swim in the ocean", "eagles": "Eagles dive into the sea"}
+	if len(seen) != len(want) || seen["dolphins"] != want["dolphins"] || seen["eagles"] != want["eagles"] {
+		t.Errorf("Scan() = %v, want %v", seen, want)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	testVectorStore(t, store)
+
+	store.Close()
+	if _, err := store.Count(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Count() after Close() error = %v, want ErrClosed", err)
+	}
+}
+
+func TestDeleteByMetadata(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.Add(ctx, testRecords)
+
+	if _, err := DeleteByMetadata(ctx, store, nil); err == nil {
+		t.Error("DeleteByMetadata() with an empty filter: expected an error")
+	}
+	deleted, err := DeleteByMetadata(ctx, store, map[string]any{"category": "marine"})
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteByMetadata() = %d, %v, want 2", deleted, err)
+	}
+	if deleted, _ := DeleteByMetadata(ctx, store, map[string]any{"category": "marine"}); deleted != 0 {
+		t.Errorf("DeleteByMetadata() again = %d, want 0", deleted)
+	}
+	if count, _ := store.Count(ctx); count != 1 {
+		t.Errorf("Count() after DeleteByMetadata() = %d, want 1", count)
+	}
+}
+
+func TestSegmentedStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSegmentedStore(dir, WithSegmentSize(2))
+	if err != nil {
+		t.Fatalf("NewSegmentedStore() unexpected error: %v", err)
+	}
+	testVectorStore(t, store)
+	store.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "segment-*.jsonl"))
+	if len(segments) < 2 {
+		t.Errorf("%d segments, want several segments of 2 entries", len(segments))
+	}
+
+	t.Run("reopen", func(t *testing.T) {
+		store, err := NewSegmentedStore(dir, WithSegmentSize(2))
+		if err != nil {
+			t.Fatalf("NewSegmentedStore() unexpected error: %v", err)
+		}
+		defer store.Close()
+
+		if count, _ := store.Count(context.Background()); count != 2 {
+			t.Errorf("Count() after reopening = %d, want 2", count)
+		}
+		// The upserted eagles record has the same vector as dolphins, and comes second by ID
+		matches, _ := store.Search(context.Background(), Query{Vector: []float32{1, 0, 0}})
+		if len(matches) != 2 || matches[1].Content != "Eagles dive into the sea" {
+			t.Errorf("Search() after reopening = %+v, want the upserted record", matches)
+		}
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		store, _ := NewSegmentedStore(dir, WithSegmentSize(2))
+		if err := store.Compact(); err != nil {
+			t.Fatalf("Compact() unexpected error: %v", err)
+		}
+		if err := store.Upsert(context.Background(), []Record{{ID: "sharks", Content: "Sharks", Embedding: []float32{0, 1, 0}}}); err != nil {
+			t.Fatalf("Upsert() after Compact() unexpected error: %v", err)
+		}
+		store.Close()
+
+		segments, _ := filepath.Glob(filepath.Join(dir, "segment-*.jsonl"))
+		if len(segments) != 2 {
+			t.Errorf("%d segments after Compact(), want 2", len(segments))
+		}
+		store, _ = NewSegmentedStore(dir)
+		defer store.Close()
+		if count, _ := store.Count(context.Background()); count != 3 {
+			t.Errorf("Count() after Compact() = %d, want 3", count)
+		}
+	})
+
+	t.Run("truncated entry", func(t *testing.T) {
+		dir := t.TempDir()
+		store, _ := NewSegmentedStore(dir)
+		store.Upsert(context.Background(), testRecords[:1])
+		store.Close()
+
+		// A write interrupted by a crash
+		segment := filepath.Join(dir, segmentName(1))
+		file, _ := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0o644)
+		file.WriteString(`{"op":"put","record":{"id":"half`)
+		file.Close()
+
+		store, err := NewSegmentedStore(dir)
+		if err != nil {
+			t.Fatalf("NewSegmentedStore() unexpected error: %v", err)
+		}
+		if err := store.Upsert(context.Background(), testRecords[1:2]); err != nil {
+			t.Fatalf("Upsert() unexpected error: %v", err)
+		}
+		store.Close()
+
+		store, err = NewSegmentedStore(dir)
+		if err != nil {
+			t.Fatalf("NewSegmentedStore() after repair unexpected error: %v", err)
+		}
+		defer store.Close()
+		if count, _ := store.Count(context.Background()); count != 2 {
+			t.Errorf("Count() = %d, want 2", count)
+		}
+	})
+}
+
+func TestLocalvecStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenLocalvecStore(dir, "docs")
+	if err != nil {
+		t.Fatalf("OpenLocalvecStore() unexpected error: %v", err)
+	}
+	testVectorStore(t, store)
+
+	// The file has the localvec format
+	reopened, err := OpenLocalvecStore(dir, "docs")
+	if err != nil {
+		t.Fatalf("OpenLocalvecStore() unexpected error: %v", err)
+	}
+	if count, _ := reopened.Count(context.Background()); count != 2 {
+		t.Errorf("Count() after reopening = %d, want 2", count)
+	}
+	if value, ok := reopened.DocStore().Data["dolphins"]; !ok || value.Doc.Content[0].Text != "Dolphins swim in the ocean" {
+		t.Errorf("localvec data = %+v, want the dolphins document", reopened.DocStore().Data)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	src, _ := OpenLocalvecStore(t.TempDir(), "docs")
+	src.Add(ctx, testRecords)
+
+	dst, err := NewSegmentedStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSegmentedStore() unexpected error: %v", err)
+	}
+	defer dst.Close()
+
+	count, err := Migrate(ctx, src, dst, 2)
+	if err != nil || count != 3 {
+		t.Fatalf("Migrate() = %d, %v, want 3", count, err)
+	}
+	matches, _ := dst.Search(ctx, Query{Vector: []float32{0, 0, 1}, K: 1})
+	if len(matches) != 1 || matches[0].ID != "eagles" || matches[0].Metadata["category"] != "birds" {
+		t.Errorf("Search() on the destination = %+v, want eagles", matches)
+	}
+}
+
+func TestDocumentID(t *testing.T) {
+	// Same value as the localvec plugin for the same document
+	id := DocumentID("Whales also swim in the ocean", map[string]any{"category": "marine"})
+	if id != "1a8cb2c220728fe80f35d0e636958c09" {
+		t.Errorf("DocumentID() = %s, want the localvec ID", id)
+	}
+}
@@ -0,0 +1,229 @@
+// Package ragchat answers questions with a chat agent from the chunks retrieved by a RAG agent,
+// citing the chunks used
+package ragchat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip"
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// DefaultContextTokens is the default token budget of the chunks of the context
+const DefaultContextTokens = 2048
+
+// Default templates of the RagChatAgent
+var (
+	// PromptTemplate is the prompt sent to the chat agent, {{context}} and {{question}} being replaced
+	PromptTemplate = `Answer the question using only the numbered sources of the context.
+Cite the sources you use with their numbers in brackets, e.g. [1] or [1][3].
+If the context does not contain the answer, say that you don't know.
+
+<context>
+{{context}}
+</context>
+
+Question: {{question}}`
+
+	// SourceTemplate is the template of each chunk of the context,
+	// {{number}}, {{title}}, {{source}} and {{content}} being replaced
+	SourceTemplate = `[{{number}}] {{title}}
+{{content}}`
+)
+
+// RagChatAgent answers the questions with the chat agent, in the context of the chunks retrieved by the RAG agent
+type RagChatAgent struct {
+	Name string
+
+	ragAgent  snip.AIRagAgent
+	chatAgent snip.AIChatAgent
+
+	promptTemplate string
+	sourceTemplate string
+	contextTokens  int
+	lengthFunc     text.LengthFunc
+	searchOptions  []rag.SearchOption
+
+	logger logger.Logger
+}
+
+// Source is a chunk of the context of an answer, with its number in the citations
+type Source struct {
+	Number int `json:"number"`
+	rag.SearchResult
+}
+
+// Title returns the title of the chunk: its title metadata, else its source metadata, else its ID
+func (s Source) Title() string {
+	for _, key := range []string{"title", "source"} {
+		if value, ok := s.Metadata[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return s.ID
+}
+
+// RagChatResponse is the answer of the chat agent with its sources
+type RagChatResponse struct {
+	agents.ChatResponse
+	// Sources are the chunks cited by the answer, in the order of their numbers
+	Sources []Source `json:"sources"`
+	// Context are all the chunks given to the chat agent
+	Context []Source `json:"context"`
+}
+
+func NewRagChatAgent(
+	name string,
+	ragAgent snip.AIRagAgent,
+	chatAgent snip.AIChatAgent,
+	opts ...RagChatAgentOption,
+) (*RagChatAgent, error) {
+	if ragAgent == nil || chatAgent == nil {
+		return nil, fmt.Errorf("a RAG agent and a chat agent are required")
+	}
+
+	ragChatAgent := &RagChatAgent{
+		Name:           name,
+		ragAgent:       ragAgent,
+		chatAgent:      chatAgent,
+		promptTemplate: PromptTemplate,
+		sourceTemplate: SourceTemplate,
+		contextTokens:  DefaultContextTokens,
+		lengthFunc:     text.EstimateTokens,
+		logger:         &logger.NoOpLogger{},
+	}
+
+	for _, opt := range opts {
+		opt(ragChatAgent)
+	}
+
+	return ragChatAgent, nil
+}
+
+func (ragChatAgent *RagChatAgent) GetName() string {
+	return ragChatAgent.Name
+}
+
+func (ragChatAgent *RagChatAgent) Kind() agents.AgentKind {
+	return agents.RagChat
+}
+
+// GetMessages returns the conversation history of the chat agent (the questions without their context)
+func (ragChatAgent *RagChatAgent) GetMessages() []*ai.Message {
+	return ragChatAgent.chatAgent.GetMessages()
+}
+
+// ClearMessages removes the conversation history of the chat agent
+func (ragChatAgent *RagChatAgent) ClearMessages() error {
+	return ragChatAgent.chatAgent.ReplaceMessagesWith([]*ai.Message{})
+}
+
+// Ask answers the question without memory
+func (ragChatAgent *RagChatAgent) Ask(question string) (RagChatResponse, error) {
+	return ragChatAgent.answer(question, func(prompt string) (agents.ChatResponse, error) {
+		return ragChatAgent.chatAgent.Ask(prompt)
+	})
+}
+
+// AskStream answers the question without memory, calling callback with each chunk of the answer
+func (ragChatAgent *RagChatAgent) AskStream(question string, callback func(agents.ChatResponse) error) (RagChatResponse, error) {
+	return ragChatAgent.answer(question, func(prompt string) (agents.ChatResponse, error) {
+		return ragChatAgent.chatAgent.AskStream(prompt, callback)
+	})
+}
+
+// AskWithMemory answers the question with the conversation history of the chat agent
+// The history keeps the question without its context, so that it does not grow with the chunks
+func (ragChatAgent *RagChatAgent) AskWithMemory(question string) (RagChatResponse, error) {
+	return ragChatAgent.answer(question, func(prompt string) (agents.ChatResponse, error) {
+		response, err := ragChatAgent.chatAgent.AskWithMemory(prompt)
+		if err == nil {
+			err = ragChatAgent.forgetContext(prompt, question)
+		}
+		return response, err
+	})
+}
+
+// AskStreamWithMemory answers the question with the conversation history of the chat agent,
+// calling callback with each chunk of the answer
+func (ragChatAgent *RagChatAgent) AskStreamWithMemory(question string, callback func(agents.ChatResponse) error) (RagChatResponse, error) {
+	return ragChatAgent.answer(question, func(prompt string) (agents.ChatResponse, error) {
+		response, err := ragChatAgent.chatAgent.AskStreamWithMemory(prompt, callback)
+		if err == nil {
+			err = ragChatAgent.forgetContext(prompt, question)
+		}
+		return response, err
+	})
+}
+
+// answer retrieves the chunks of the question, asks the prompt with their context, and finds the sources cited
+func (ragChatAgent *RagChatAgent) answer(question string, ask func(prompt string) (agents.ChatResponse, error)) (RagChatResponse, error) {
+	results, err := ragChatAgent.ragAgent.Search(question, ragChatAgent.searchOptions...)
+	if err != nil {
+		return RagChatResponse{}, fmt.Errorf("error retrieving the context: %w", err)
+	}
+	sources, context := ragChatAgent.BuildContext(results)
+	ragChatAgent.logger.Debug("📚 %d sources in the context of %q", len(sources), question)
+
+	prompt := strings.NewReplacer("{{context}}", context, "{{question}}", question).Replace(ragChatAgent.promptTemplate)
+	response, err := ask(prompt)
+	if err != nil {
+		return RagChatResponse{ChatResponse: response, Context: sources}, err
+	}
+	return RagChatResponse{ChatResponse: response, Sources: Cited(response.Text, sources), Context: sources}, nil
+}
+
+// BuildContext numbers the results and formats them with the source template, in the order of the results,
+// as long as they fit in the token budget: the results too long for the remaining budget are skipped
+func (ragChatAgent *RagChatAgent) BuildContext(results []rag.SearchResult) ([]Source, string) {
+	sources := []Source{}
+	blocks := []string{}
+	budget := ragChatAgent.contextTokens
+	for _, result := range results {
+		source := Source{Number: len(sources) + 1, SearchResult: result}
+		block := strings.NewReplacer(
+			"{{number}}", fmt.Sprint(source.Number),
+			"{{title}}", source.Title(),
+			"{{source}}", metadataString(result.Metadata, "source"),
+			"{{content}}", strings.TrimSpace(result.Content),
+		).Replace(ragChatAgent.sourceTemplate)
+
+		length := ragChatAgent.lengthFunc(block)
+		if length > budget {
+			ragChatAgent.logger.Debug("✂️ Chunk %s skipped: %d tokens for a remaining budget of %d", result.ID, length, budget)
+			continue
+		}
+		budget -= length
+		sources = append(sources, source)
+		blocks = append(blocks, block)
+	}
+	return sources, strings.Join(blocks, "\n\n")
+}
+
+// forgetContext replaces the prompt of the last question in the history of the chat agent with the question
+func (ragChatAgent *RagChatAgent) forgetContext(prompt, question string) error {
+	messages := ragChatAgent.chatAgent.GetMessages()
+	prompt = strings.TrimSpace(prompt)
+	for idx := len(messages) - 1; idx >= 0; idx-- {
+		if messages[idx].Role == ai.RoleUser && messages[idx].Text() == prompt {
+			updated := make([]*ai.Message, len(messages))
+			copy(updated, messages)
+			updated[idx] = ai.NewUserTextMessage(strings.TrimSpace(question))
+			return ragChatAgent.chatAgent.ReplaceMessagesWith(updated)
+		}
+	}
+	return nil
+}
+
+// metadataString returns the metadata value of key as a string, empty if missing
+func metadataString(metadata map[string]any, key string) string {
+	if value, ok := metadata[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
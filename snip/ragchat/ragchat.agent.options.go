@@ -0,0 +1,73 @@
+package ragchat
+
+import (
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+type RagChatAgentOption func(*RagChatAgent)
+
+// WithLogger sets a custom logger for the agent
+func WithLogger(log logger.Logger) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		ragChatAgent.logger = log
+	}
+}
+
+// WithVerbose enables verbose logging (INFO level) with agent name prefix
+func WithVerbose(verbose bool) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		if verbose {
+			ragChatAgent.logger = logger.NewConsoleLoggerWithPrefix(logger.LevelInfo, ragChatAgent.Name)
+		} else {
+			ragChatAgent.logger = &logger.NoOpLogger{}
+		}
+	}
+}
+
+// WithLogLevel sets the log level for the agent
+func WithLogLevel(level logger.LogLevel) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		ragChatAgent.logger = logger.NewConsoleLoggerWithPrefix(level, ragChatAgent.Name)
+	}
+}
+
+// WithPromptTemplate replaces PromptTemplate, {{context}} and {{question}} being replaced
+func WithPromptTemplate(template string) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		ragChatAgent.promptTemplate = template
+	}
+}
+
+// WithSourceTemplate replaces SourceTemplate, {{number}}, {{title}}, {{source}} and {{content}} being replaced
+func WithSourceTemplate(template string) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		ragChatAgent.sourceTemplate = template
+	}
+}
+
+// WithContextTokens sets the token budget of the chunks of the context (DefaultContextTokens by default)
+func WithContextTokens(tokens int) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		if tokens > 0 {
+			ragChatAgent.contextTokens = tokens
+		}
+	}
+}
+
+// WithLengthFunction sets how the budget of the context is measured (text.EstimateTokens by default)
+func WithLengthFunction(lengthFunc text.LengthFunc) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		if lengthFunc != nil {
+			ragChatAgent.lengthFunc = lengthFunc
+		}
+	}
+}
+
+// WithSearchOptions sets the options of the searches of the RAG agent, e.g. rag.WithTopK(8)
+func WithSearchOptions(opts ...rag.SearchOption) RagChatAgentOption {
+	return func(ragChatAgent *RagChatAgent) {
+		ragChatAgent.searchOptions = append(ragChatAgent.searchOptions, opts...)
+	}
+}
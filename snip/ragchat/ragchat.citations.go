@@ -0,0 +1,35 @@
+package ragchat
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+var (
+	// citationPattern matches a citation of the answer, e.g. [1] or [1, 3]
+	citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+	numberPattern   = regexp.MustCompile(`\d+`)
+)
+
+// Cited returns the sources cited in the answer with their numbers in brackets, in the order of their numbers
+// The numbers without source are ignored
+func Cited(answer string, sources []Source) []Source {
+	numbers := []int{}
+	for _, citation := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, number := range numberPattern.FindAllString(citation[1], -1) {
+			if n, err := strconv.Atoi(number); err == nil && !slices.Contains(numbers, n) {
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	slices.Sort(numbers)
+
+	cited := []Source{}
+	for _, n := range numbers {
+		if idx := slices.IndexFunc(sources, func(s Source) bool { return s.Number == n }); idx >= 0 {
+			cited = append(cited, sources[idx])
+		}
+	}
+	return cited
+}
@@ -0,0 +1,140 @@
+package ragchat
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip"
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/rag"
+)
+
+// fakeRagAgent returns the same results for all the questions
+type fakeRagAgent struct {
+	snip.AIRagAgent
+	results []rag.SearchResult
+	options rag.SearchOptions
+}
+
+func (agent *fakeRagAgent) Search(query string, opts ...rag.SearchOption) ([]rag.SearchResult, error) {
+	agent.options = rag.NewSearchOptions(opts...)
+	return agent.results, nil
+}
+
+// fakeChatAgent answers with answer, streamed word by word, and keeps the history as the chat agents do
+type fakeChatAgent struct {
+	snip.AIChatAgent
+	answer   string
+	prompts  []string
+	messages []*ai.Message
+}
+
+func (agent *fakeChatAgent) Ask(prompt string) (agents.ChatResponse, error) {
+	agent.prompts = append(agent.prompts, prompt)
+	return agents.ChatResponse{Text: agent.answer}, nil
+}
+
+func (agent *fakeChatAgent) AskStreamWithMemory(prompt string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	agent.prompts = append(agent.prompts, prompt)
+	for _, word := range strings.SplitAfter(agent.answer, " ") {
+		if err := callback(agents.ChatResponse{Text: word}); err != nil {
+			return agents.ChatResponse{}, err
+		}
+	}
+	agent.messages = append(agent.messages, ai.NewUserTextMessage(strings.TrimSpace(prompt)), ai.NewModelTextMessage(agent.answer))
+	return agents.ChatResponse{Text: agent.answer}, nil
+}
+
+func (agent *fakeChatAgent) GetMessages() []*ai.Message {
+	return agent.messages
+}
+
+func (agent *fakeChatAgent) ReplaceMessagesWith(messages []*ai.Message) error {
+	agent.messages = messages
+	return nil
+}
+
+func newTestRagChatAgent(t *testing.T, answer string, opts ...RagChatAgentOption) (*RagChatAgent, *fakeRagAgent, *fakeChatAgent) {
+	t.Helper()
+	ragAgent := &fakeRagAgent{results: []rag.SearchResult{
+		{ID: "install", Content: "Run go get to install the SDK.", Metadata: map[string]any{"source": "docs/install.md", "title": "Install"}, Score: 0.9},
+		{ID: "usage", Content: "Create a chat agent with chat.NewChatAgent.", Metadata: map[string]any{"source": "docs/usage.md"}, Score: 0.7},
+		{ID: "faq", Content: "The SDK needs Go 1.25.", Score: 0.5},
+	}}
+	chatAgent := &fakeChatAgent{answer: answer}
+	agent, err := NewRagChatAgent("docs", ragAgent, chatAgent, opts...)
+	if err != nil {
+		t.Fatalf("NewRagChatAgent() unexpected error: %v", err)
+	}
+	return agent, ragAgent, chatAgent
+}
+
+func TestRagChatAgentAsk(t *testing.T) {
+	agent, ragAgent, chatAgent := newTestRagChatAgent(t, "Run go get [1], then create an agent [2, 1]. [7] does not exist.",
+		WithSearchOptions(rag.WithTopK(5)))
+
+	response, err := agent.Ask("How to start?")
+	if err != nil {
+		t.Fatalf("Ask() unexpected error: %v", err)
+	}
+	if ragAgent.options.K != 5 {
+		t.Errorf("search K = %d, want the search options of the agent", ragAgent.options.K)
+	}
+	prompt := chatAgent.prompts[0]
+	for _, want := range []string{"[1] Install\nRun go get", "[2] docs/usage.md\nCreate a chat agent", "[3] faq\n", "Question: How to start?"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt %q, want %q", prompt, want)
+		}
+	}
+
+	cited := []string{}
+	for _, source := range response.Sources {
+		cited = append(cited, source.ID)
+	}
+	if !slices.Equal(cited, []string{"install", "usage"}) || len(response.Context) != 3 {
+		t.Errorf("Sources = %v with %d chunks in the context, want install and usage of 3", cited, len(response.Context))
+	}
+	if response.Sources[0].Number != 1 || response.Sources[0].Metadata["source"] != "docs/install.md" {
+		t.Errorf("source = %+v, want the number and the metadata of the chunk", response.Sources[0])
+	}
+}
+
+func TestRagChatAgentContextBudget(t *testing.T) {
+	agent, _, _ := newTestRagChatAgent(t, "", WithContextTokens(20), WithSourceTemplate("{{number}}: {{content}}"))
+	results := []rag.SearchResult{
+		{ID: "short", Content: "one two"},
+		{ID: "long", Content: strings.Repeat("word ", 30)},
+		{ID: "other", Content: "three four"},
+	}
+	sources, context := agent.BuildContext(results)
+	if len(sources) != 2 || sources[1].ID != "other" || sources[1].Number != 2 {
+		t.Fatalf("BuildContext() = %+v, want the long chunk skipped and the others numbered", sources)
+	}
+	if context != "1: one two\n\n2: three four" {
+		t.Errorf("context = %q", context)
+	}
+}
+
+func TestRagChatAgentStreamWithMemory(t *testing.T) {
+	agent, _, chatAgent := newTestRagChatAgent(t, "Use go get [1].")
+	chatAgent.messages = []*ai.Message{ai.NewSystemTextMessage("You are a helpful assistant.")}
+
+	streamed := ""
+	response, err := agent.AskStreamWithMemory("How to install?", func(chunk agents.ChatResponse) error {
+		streamed += chunk.Text
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("AskStreamWithMemory() unexpected error: %v", err)
+	}
+	if streamed != "Use go get [1]." || len(response.Sources) != 1 || response.Sources[0].ID != "install" {
+		t.Errorf("streamed %q with the sources %+v, want the answer citing install", streamed, response.Sources)
+	}
+
+	messages := agent.GetMessages()
+	if len(messages) != 3 || messages[1].Text() != "How to install?" || messages[2].Text() != "Use go get [1]." {
+		t.Errorf("history = %d messages, want the question without its context and the answer", len(messages))
+	}
+}
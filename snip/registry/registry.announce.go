@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// Announcement is an instance kept alive in a registry by Announce
+type Announcement struct {
+	Instance Instance
+
+	registry Registry
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Announce registers the instance and sends a heartbeat every interval (DefaultHeartbeatInterval if zero or negative)
+// until Stop is called. An ID is generated if the instance has none
+// If the registry lost the instance (e.g. the registry was restarted, or a heartbeat was missed), it is registered again
+func Announce(registry Registry, instance Instance, interval time.Duration, log logger.Logger) (*Announcement, error) {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+	if log == nil {
+		log = &logger.NoOpLogger{}
+	}
+	if instance.ID == "" {
+		instance.ID = newInstanceID()
+	}
+
+	if err := registry.Register(instance); err != nil {
+		return nil, err
+	}
+
+	announcement := &Announcement{
+		Instance: instance,
+		registry: registry,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(announcement.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-announcement.stop:
+				return
+			case <-ticker.C:
+			}
+
+			err := registry.Heartbeat(instance.ID)
+			if errors.Is(err, ErrNotRegistered) {
+				log.Warn("Instance %s of %s is not registered anymore, registering again", instance.ID, instance.Name)
+				err = registry.Register(instance)
+			}
+			if err != nil {
+				log.Error("Heartbeat of instance %s of %s failed: %v", instance.ID, instance.Name, err)
+			}
+		}
+	}()
+
+	return announcement, nil
+}
+
+// Stop stops the heartbeats and deregisters the instance
+func (a *Announcement) Stop() error {
+	var err error
+	a.once.Do(func() {
+		close(a.stop)
+		<-a.done
+		err = a.registry.Deregister(a.Instance.ID)
+	})
+	return err
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileRegistry is a registry stored in a directory, shared by the processes of a machine (or a shared volume)
+// Each instance is a JSON file, rewritten atomically at each heartbeat
+type FileRegistry struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewFileRegistry creates a registry stored in dir (created if needed)
+// Instances without heartbeat for longer than ttl are ignored (DefaultTTL if zero or negative)
+func NewFileRegistry(dir string, ttl time.Duration) (*FileRegistry, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating registry directory: %w", err)
+	}
+	return &FileRegistry{dir: dir, ttl: ttl}, nil
+}
+
+// path returns the file of an instance
+func (r *FileRegistry) path(id string) string {
+	return filepath.Join(r.dir, id+".json")
+}
+
+func (r *FileRegistry) Register(instance Instance) error {
+	if instance.ID == "" || strings.ContainsAny(instance.ID, `/\`) {
+		return fmt.Errorf("invalid instance id %q", instance.ID)
+	}
+	instance.LastHeartbeat = time.Now()
+	return r.write(instance)
+}
+
+func (r *FileRegistry) Heartbeat(id string) error {
+	instance, err := r.read(r.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotRegistered
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !alive(instance, r.ttl, now) {
+		os.Remove(r.path(id))
+		return ErrNotRegistered
+	}
+	instance.LastHeartbeat = now
+	return r.write(instance)
+}
+
+func (r *FileRegistry) Deregister(id string) error {
+	if err := os.Remove(r.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing instance: %w", err)
+	}
+	return nil
+}
+
+func (r *FileRegistry) Lookup(name string) ([]Instance, error) {
+	paths, err := filepath.Glob(filepath.Join(r.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing instances: %w", err)
+	}
+
+	now := time.Now()
+	var instances []Instance
+	for _, path := range paths {
+		instance, err := r.read(path)
+		if err != nil {
+			// The file may have been removed, or be written by another process
+			continue
+		}
+		if instance.Name == name && alive(instance, r.ttl, now) {
+			instances = append(instances, instance)
+		}
+	}
+	sortInstances(instances)
+	return instances, nil
+}
+
+// read decodes the instance file at path
+func (r *FileRegistry) read(path string) (Instance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Instance{}, err
+	}
+	var instance Instance
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return Instance{}, fmt.Errorf("error parsing instance file %s: %w", path, err)
+	}
+	return instance, nil
+}
+
+// write writes the instance file through a temporary file, so that readers never see a partial file
+func (r *FileRegistry) write(instance Instance) error {
+	data, err := json.MarshalIndent(instance, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding instance: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(r.dir, instance.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing instance: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing instance: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), r.path(instance.ID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing instance: %w", err)
+	}
+	return nil
+}
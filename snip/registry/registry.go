@@ -0,0 +1,60 @@
+// Package registry keeps track of the agent servers running under a name,
+// so that remote agents can be balanced between the replicas of a server
+package registry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+)
+
+const (
+	// DefaultTTL is the default duration after which an instance without heartbeat is considered gone
+	DefaultTTL = 15 * time.Second
+
+	// DefaultHeartbeatInterval is the default interval between the heartbeats of an instance
+	DefaultHeartbeatInterval = 5 * time.Second
+)
+
+// ErrNotRegistered is returned by Heartbeat when the instance is unknown or expired
+var ErrNotRegistered = errors.New("instance not registered")
+
+// Instance is a server registered under an agent name
+type Instance struct {
+	// ID identifies the instance (generated by Announce if empty)
+	ID   string           `json:"id"`
+	Name string           `json:"name"`
+	Kind agents.AgentKind `json:"kind"`
+	// Address is the base URL of the server, e.g. "http://10.0.0.12:8080"
+	Address  string            `json:"address"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// LastHeartbeat is set by the registry
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+// Registry is where the servers register themselves and where the clients look them up
+type Registry interface {
+	// Register adds the instance, or replaces it if an instance with the same ID exists
+	Register(instance Instance) error
+	// Heartbeat keeps the instance alive, it returns ErrNotRegistered if the instance expired
+	Heartbeat(id string) error
+	// Deregister removes the instance
+	Deregister(id string) error
+	// Lookup returns the live instances registered under name
+	Lookup(name string) ([]Instance, error)
+}
+
+// newInstanceID returns a random instance ID
+func newInstanceID() string {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	return hex.EncodeToString(idBytes)
+}
+
+// alive reports whether the last heartbeat of the instance is within the TTL
+func alive(instance Instance, ttl time.Duration, now time.Time) bool {
+	return now.Sub(instance.LastHeartbeat) <= ttl
+}
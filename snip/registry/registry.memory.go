@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRegistry is an in-process registry, for servers and clients running in the same process
+type MemoryRegistry struct {
+	mu        sync.Mutex
+	instances map[string]Instance
+	ttl       time.Duration
+}
+
+// NewMemoryRegistry creates an in-process registry
+// Instances without heartbeat for longer than ttl are ignored (DefaultTTL if zero or negative)
+func NewMemoryRegistry(ttl time.Duration) *MemoryRegistry {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &MemoryRegistry{instances: map[string]Instance{}, ttl: ttl}
+}
+
+func (r *MemoryRegistry) Register(instance Instance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	instance.LastHeartbeat = time.Now()
+	r.instances[instance.ID] = instance
+	return nil
+}
+
+func (r *MemoryRegistry) Heartbeat(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	instance, ok := r.instances[id]
+	now := time.Now()
+	if !ok || !alive(instance, r.ttl, now) {
+		delete(r.instances, id)
+		return ErrNotRegistered
+	}
+	instance.LastHeartbeat = now
+	r.instances[id] = instance
+	return nil
+}
+
+func (r *MemoryRegistry) Deregister(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.instances, id)
+	return nil
+}
+
+func (r *MemoryRegistry) Lookup(name string) ([]Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var instances []Instance
+	for id, instance := range r.instances {
+		if !alive(instance, r.ttl, now) {
+			delete(r.instances, id)
+			continue
+		}
+		if instance.Name == name {
+			instances = append(instances, instance)
+		}
+	}
+	sortInstances(instances)
+	return instances, nil
+}
+
+// sortInstances sorts the instances by ID, so that the balancing order is stable
+func sortInstances(instances []Instance) {
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+}
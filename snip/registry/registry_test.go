@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+)
+
+func testRegistry(t *testing.T, reg Registry) {
+	t.Helper()
+
+	for _, instance := range []Instance{
+		{ID: "b", Name: "bob", Kind: agents.ChatServer, Address: "http://host-b:8080"},
+		{ID: "a", Name: "bob", Kind: agents.ChatServer, Address: "http://host-a:8080"},
+		{ID: "c", Name: "alice", Kind: agents.ChatServer, Address: "http://host-c:8080"},
+	} {
+		if err := reg.Register(instance); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+
+	instances, err := reg.Lookup("bob")
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	if len(instances) != 2 || instances[0].ID != "a" || instances[1].ID != "b" {
+		t.Fatalf("Lookup() = %+v, want the instances a and b", instances)
+	}
+	if instances[0].LastHeartbeat.IsZero() {
+		t.Error("Register() should set LastHeartbeat")
+	}
+
+	if err := reg.Heartbeat("a"); err != nil {
+		t.Errorf("Heartbeat() unexpected error: %v", err)
+	}
+	if err := reg.Heartbeat("unknown"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Heartbeat() error = %v, want ErrNotRegistered", err)
+	}
+
+	if err := reg.Deregister("a"); err != nil {
+		t.Fatalf("Deregister() unexpected error: %v", err)
+	}
+	instances, _ = reg.Lookup("bob")
+	if len(instances) != 1 || instances[0].ID != "b" {
+		t.Errorf("Lookup() after Deregister() = %+v, want the instance b", instances)
+	}
+}
+
+func TestMemoryRegistry(t *testing.T) {
+	testRegistry(t, NewMemoryRegistry(0))
+}
+
+func TestFileRegistry(t *testing.T) {
+	dir := t.TempDir()
+	reg, err := NewFileRegistry(dir, 0)
+	if err != nil {
+		t.Fatalf("NewFileRegistry() unexpected error: %v", err)
+	}
+	testRegistry(t, reg)
+
+	// Another process sees the same instances
+	other, _ := NewFileRegistry(dir, 0)
+	instances, _ := other.Lookup("alice")
+	if len(instances) != 1 || instances[0].Address != "http://host-c:8080" {
+		t.Errorf("Lookup() from another registry = %+v, want the instance c", instances)
+	}
+}
+
+func TestRegistryExpiration(t *testing.T) {
+	reg := NewMemoryRegistry(50 * time.Millisecond)
+	reg.Register(Instance{ID: "a", Name: "bob"})
+
+	time.Sleep(80 * time.Millisecond)
+	if instances, _ := reg.Lookup("bob"); len(instances) != 0 {
+		t.Errorf("Lookup() = %+v, want no live instance", instances)
+	}
+	if err := reg.Heartbeat("a"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Heartbeat() error = %v, want ErrNotRegistered", err)
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	reg := NewMemoryRegistry(100 * time.Millisecond)
+	announcement, err := Announce(reg, Instance{Name: "bob", Address: "http://host:8080"}, 20*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("Announce() unexpected error: %v", err)
+	}
+	if announcement.Instance.ID == "" {
+		t.Error("Announce() should generate an ID")
+	}
+
+	// The heartbeats keep the instance alive after the TTL, and register it again once lost
+	time.Sleep(150 * time.Millisecond)
+	if instances, _ := reg.Lookup("bob"); len(instances) != 1 {
+		t.Errorf("Lookup() = %+v, want the announced instance", instances)
+	}
+	reg.Deregister(announcement.Instance.ID)
+	time.Sleep(60 * time.Millisecond)
+	if instances, _ := reg.Lookup("bob"); len(instances) != 1 {
+		t.Errorf("Lookup() after losing the instance = %+v, want it registered again", instances)
+	}
+
+	if err := announcement.Stop(); err != nil {
+		t.Fatalf("Stop() unexpected error: %v", err)
+	}
+	if instances, _ := reg.Lookup("bob"); len(instances) != 0 {
+		t.Errorf("Lookup() after Stop() = %+v, want no instance", instances)
+	}
+}
@@ -2,20 +2,20 @@ package remote
 
 // Implements AIAgent interface for remote agents accessed via HTTP API
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/core"
 	"github.com/snipwise/snip-sdk/snip/agents"
 	"github.com/snipwise/snip-sdk/snip/chatserver"
 )
 
 // Structure for flow input
+// Same JSON as chatserver.FlowRequest
 type RemoteChatRequest struct {
 	Data struct {
 		Message string `json:"message"`
@@ -25,16 +25,48 @@ type RemoteChatRequest struct {
 type RemoteAgent struct {
 	ChatStreamEndpoint  string
 	ChatEndPoint        string
+	AskStreamEndpoint   string
+	AskEndpoint         string
 	InformationEndpoint string
 	AddContextEndpoint  string
 	GetMessagesEndpoint string
 	Name                string
+
+	ReplaceMessagesEndpoint           string
+	ReplaceWithSystemMessagesEndpoint string
+	ClearMessagesEndpoint             string
+	TruncateMessagesEndpoint          string
+	SystemInstructionsEndpoint        string
+	CompressContextEndpoint           string
+	CompressContextStreamEndpoint     string
+	CancelStreamEndpoint              string
+
+	// currentStream is the last stream started by the agent, cancelled by GetStreamCancel
+	streamMu      sync.Mutex
+	currentStream remoteStream
+
+	remoteClient
 }
 
-func NewRemoteAgent(name string, config chatserver.ConfigHTTP) *RemoteAgent {
-	// Build full URLs from Address and paths
-	baseURL := "http://" + config.Address
+// NewRemoteAgent creates a remote agent for a ChatAgentServer configured with config
+// The base URL is built from config.Address ("localhost:8080" is served over http, "https://host" is kept as is)
+// unless the WithBaseURL option is used
+func NewRemoteAgent(name string, config chatserver.ConfigHTTP, opts ...RemoteAgentOption) *RemoteAgent {
+	agent := newRemoteAgent(name, opts...)
+
+	// Build full URLs from the base URL and paths
+	baseURL := agent.baseURL
+	if baseURL == "" {
+		baseURL = baseURLFromAddress(config.Address)
+	}
+	agent.setEndpoints(strings.TrimRight(baseURL, "/"), config)
+
+	return agent
+}
 
+// setEndpoints sets the endpoints from the base URL and the paths of the server configuration
+// An empty baseURL leaves the paths alone, to be resolved on a replica at each call (see NewRemoteAgentFromRegistry)
+func (agent *RemoteAgent) setEndpoints(baseURL string, config chatserver.ConfigHTTP) {
 	// Set default information path if not provided
 	informationPath := config.InformationPath
 	if informationPath == "" {
@@ -53,16 +85,40 @@ func NewRemoteAgent(name string, config chatserver.ConfigHTTP) *RemoteAgent {
 		getMessagesPath = chatserver.DefaultGetMessagesPath
 	}
 
+	agent.ChatStreamEndpoint = baseURL + config.ChatStreamFlowPath
+	agent.ChatEndPoint = baseURL + config.ChatFlowPath
+	agent.AskStreamEndpoint = baseURL + pathOrDefault(config.AskStreamFlowPath, chatserver.DefaultAskStreamFlowPath)
+	agent.AskEndpoint = baseURL + pathOrDefault(config.AskFlowPath, chatserver.DefaultAskFlowPath)
+	agent.InformationEndpoint = baseURL + informationPath
+	agent.AddContextEndpoint = baseURL + addContextPath
+	agent.GetMessagesEndpoint = baseURL + getMessagesPath
+
+	agent.ReplaceMessagesEndpoint = baseURL + pathOrDefault(config.ReplaceMessagesPath, chatserver.DefaultReplaceMessagesPath)
+	agent.ReplaceWithSystemMessagesEndpoint = baseURL + pathOrDefault(config.ReplaceWithSystemMessagesPath, chatserver.DefaultReplaceWithSystemMessagesPath)
+	agent.ClearMessagesEndpoint = baseURL + pathOrDefault(config.ClearMessagesPath, chatserver.DefaultClearMessagesPath)
+	agent.TruncateMessagesEndpoint = baseURL + pathOrDefault(config.TruncateMessagesPath, chatserver.DefaultTruncateMessagesPath)
+	agent.SystemInstructionsEndpoint = baseURL + pathOrDefault(config.SystemInstructionsPath, chatserver.DefaultSystemInstructionsPath)
+	agent.CompressContextEndpoint = baseURL + pathOrDefault(config.CompressContextPath, chatserver.DefaultCompressContextPath)
+	agent.CompressContextStreamEndpoint = baseURL + pathOrDefault(config.CompressContextStreamPath, chatserver.DefaultCompressContextStreamPath)
+	agent.CancelStreamEndpoint = baseURL + pathOrDefault(config.CancelStreamPath, chatserver.DefaultCancelStreamPath)
+}
+
+// newRemoteAgent creates a remote agent without endpoints and applies the options
+func newRemoteAgent(name string, opts ...RemoteAgentOption) *RemoteAgent {
 	return &RemoteAgent{
-		ChatStreamEndpoint:  baseURL + config.ChatStreamFlowPath,
-		ChatEndPoint:        baseURL + config.ChatFlowPath,
-		InformationEndpoint: baseURL + informationPath,
-		AddContextEndpoint:  baseURL + addContextPath,
-		GetMessagesEndpoint: baseURL + getMessagesPath,
-		Name:                name,
+		Name:         name,
+		remoteClient: newRemoteClient(opts...),
 	}
 }
 
+// pathOrDefault returns path, or defaultPath if path is empty
+func pathOrDefault(path, defaultPath string) string {
+	if path == "" {
+		return defaultPath
+	}
+	return path
+}
+
 func (agent *RemoteAgent) GetName() string {
 	return agent.Name
 }
@@ -73,174 +129,194 @@ func (agent *RemoteAgent) Kind() agents.AgentKind {
 
 func (agent *RemoteAgent) AddSystemMessage(context string) error {
 	// Prepare request
-	reqBody := struct {
-		Context string `json:"context"`
-	}{
+	reqBody := chatserver.AddSystemMessageRequest{
 		Context: strings.TrimSpace(context),
 	}
 
-	// Convert to JSON
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("error creating JSON: %w", err)
-	}
+	_, err := agent.postJSON(agent.AddContextEndpoint, reqBody)
+	return err
+}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", agent.AddContextEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+func (agent *RemoteAgent) ReplaceMessagesWith(messages []*ai.Message) error {
+	// Replace the entire conversation history on the server side
+	if messages == nil {
+		return fmt.Errorf("messages cannot be nil")
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	// Execute the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error during HTTP call: %w", err)
+	reqBody := chatserver.ReplaceMessagesRequest{
+		Messages: messages,
 	}
-	defer resp.Body.Close()
+	_, err := agent.postIdempotentJSON(agent.ReplaceMessagesEndpoint, reqBody)
+	return err
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("HTTP error: status code %d, body: %s", resp.StatusCode, string(body))
+func (agent *RemoteAgent) ReplaceMessagesWithSystemMessages(systemMessages []string) error {
+	// Replace the entire conversation history with system messages on the server side
+	if systemMessages == nil {
+		return fmt.Errorf("systemMessages cannot be nil")
 	}
-
-	return nil
+	reqBody := chatserver.ReplaceWithSystemMessagesRequest{
+		SystemMessages: systemMessages,
+	}
+	_, err := agent.postIdempotentJSON(agent.ReplaceWithSystemMessagesEndpoint, reqBody)
+	return err
 }
 
-func (agent *RemoteAgent) ReplaceMessagesWith(messages []*ai.Message) error {
-	// Remote agents do not support replacing messages directly
-	// Message management must be done on the server side
-	return fmt.Errorf("ReplaceMessagesWith is not supported for remote agents: message history is managed by the remote server")
+// ClearMessages removes the entire conversation history on the server side
+func (agent *RemoteAgent) ClearMessages() error {
+	_, err := agent.postIdempotentJSON(agent.ClearMessagesEndpoint, struct{}{})
+	return err
 }
 
-func (agent *RemoteAgent) ReplaceMessagesWithSystemMessages(systemMessages []string) error {
-	// Remote agents do not support replacing messages directly
-	// Message management must be done on the server side
-	return fmt.Errorf("ReplaceMessagesWithSystemMessages is not supported for remote agents: message history is managed by the remote server")
+// TruncateMessages keeps only the last keepLast messages of the conversation history on the server side
+func (agent *RemoteAgent) TruncateMessages(keepLast int) error {
+	if keepLast < 0 {
+		return fmt.Errorf("keepLast cannot be negative")
+	}
+	reqBody := chatserver.TruncateMessagesRequest{
+		KeepLast: keepLast,
+	}
+	_, err := agent.postIdempotentJSON(agent.TruncateMessagesEndpoint, reqBody)
+	return err
 }
 
-func (agent *RemoteAgent) GetInfo() (agents.AgentInfo, error) {
-	// Create HTTP GET request to information endpoint
-	req, err := http.NewRequest("GET", agent.InformationEndpoint, nil)
+// GetSystemInstructions returns the system instructions of the remote agent
+// It returns an empty string if the server cannot be reached, use GetSystemInstructionsE to get the error
+func (agent *RemoteAgent) GetSystemInstructions() string {
+	systemInstructions, err := agent.GetSystemInstructionsE()
 	if err != nil {
-		return agents.AgentInfo{}, fmt.Errorf("error creating request: %w", err)
+		agent.log().Error("Error getting system instructions: %v", err)
+		return ""
 	}
+	return systemInstructions
+}
 
-	// Execute the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return agents.AgentInfo{}, fmt.Errorf("error during HTTP call: %w", err)
+// GetSystemInstructionsE returns the system instructions of the remote agent, or the error of the call
+func (agent *RemoteAgent) GetSystemInstructionsE() (string, error) {
+	var result chatserver.SystemInstructions
+	if err := agent.getJSON(agent.SystemInstructionsEndpoint, &result); err != nil {
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return agents.AgentInfo{}, fmt.Errorf("HTTP error: status code %d", resp.StatusCode)
-	}
+	return result.SystemInstructions, nil
+}
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return agents.AgentInfo{}, fmt.Errorf("error reading response body: %w", err)
+// SetSystemInstructions changes the system instructions of the remote agent
+func (agent *RemoteAgent) SetSystemInstructions(systemInstructions string) error {
+	reqBody := chatserver.SystemInstructions{
+		SystemInstructions: strings.TrimSpace(systemInstructions),
 	}
+	_, err := agent.postIdempotentJSON(agent.SystemInstructionsEndpoint, reqBody)
+	return err
+}
 
-	// Parse JSON response into AgentInfo struct
+func (agent *RemoteAgent) GetInfo() (agents.AgentInfo, error) {
 	var info agents.AgentInfo
-	if err := json.Unmarshal(body, &info); err != nil {
-		return agents.AgentInfo{}, fmt.Errorf("error parsing JSON response: %w", err)
+	if err := agent.getJSON(agent.InformationEndpoint, &info); err != nil {
+		return agents.AgentInfo{}, err
 	}
 
 	return info, nil
 }
 
+// GetMessages returns the conversation history of the remote agent
+// It returns nil if the server cannot be reached, use GetMessagesE to get the error
 func (agent *RemoteAgent) GetMessages() []*ai.Message {
-	// Create HTTP GET request to get messages endpoint
-	req, err := http.NewRequest("GET", agent.GetMessagesEndpoint, nil)
-	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		return nil
-	}
-
-	// Execute the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	messages, err := agent.GetMessagesE()
 	if err != nil {
-		fmt.Printf("Error during HTTP call: %v\n", err)
+		agent.log().Error("Error getting messages: %v", err)
 		return nil
 	}
-	defer resp.Body.Close()
+	return messages
+}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("HTTP error: status code %d\n", resp.StatusCode)
-		return nil
+// GetMessagesE returns the conversation history of the remote agent, or the error of the call
+func (agent *RemoteAgent) GetMessagesE() ([]*ai.Message, error) {
+	var messages []*ai.Message
+	if err := agent.getJSON(agent.GetMessagesEndpoint, &messages); err != nil {
+		return nil, err
 	}
+	return messages, nil
+}
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+// GetCurrentContextSize returns the size (in characters) of the conversation history of the remote agent
+// It returns 0 if the server cannot be reached, use GetCurrentContextSizeE to get the error
+func (agent *RemoteAgent) GetCurrentContextSize() int {
+	size, err := agent.GetCurrentContextSizeE()
 	if err != nil {
-		fmt.Printf("Error reading response body: %v\n", err)
-		return nil
+		agent.log().Error("Error getting context size: %v", err)
+		return 0
 	}
-
-	// Parse JSON response into []*ai.Message
-	var messages []*ai.Message
-	if err := json.Unmarshal(body, &messages); err != nil {
-		fmt.Printf("Error parsing JSON response: %v\n", err)
-		return nil
-	}
-
-	return messages
+	return size
 }
 
-func (agent *RemoteAgent) GetCurrentContextSize() int {
+// GetCurrentContextSizeE returns the size (in characters) of the conversation history of the remote agent,
+// or the error of the call
+func (agent *RemoteAgent) GetCurrentContextSizeE() (int, error) {
 	totalContextSize := 0
 
 	// Get messages from remote server
-	messages := agent.GetMessages()
+	messages, err := agent.GetMessagesE()
+	if err != nil {
+		return 0, err
+	}
 	for _, msg := range messages {
 		for _, content := range msg.Content {
 			totalContextSize += len(content.Text)
 		}
 	}
 
-	return totalContextSize
+	return totalContextSize, nil
 }
 
+// AskWithMemory sends the question to the chat flow with memory of the server
 func (agent *RemoteAgent) AskWithMemory(question string) (agents.ChatResponse, error) {
-	// Prepare request
-	reqBody := RemoteChatRequest{}
-	reqBody.Data.Message = strings.TrimSpace(question)
+	return agent.ask(agent.ChatEndPoint, question, false)
+}
 
-	// Convert to JSON
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return agents.ChatResponse{}, fmt.Errorf("error creating JSON: %w", err)
-	}
+// AskStreamWithMemory sends the question to the chat stream flow with memory of the server
+func (agent *RemoteAgent) AskStreamWithMemory(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	return agent.askStream(context.Background(), agent.ChatStreamEndpoint, question, callback)
+}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", agent.ChatEndPoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return agents.ChatResponse{}, fmt.Errorf("error creating request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
+// AskStreamWithMemoryContext is AskStreamWithMemory with a context
+// If ctx is cancelled (or the callback returns an error), the generation is cancelled on the server side
+func (agent *RemoteAgent) AskStreamWithMemoryContext(ctx context.Context, question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	return agent.askStream(ctx, agent.ChatStreamEndpoint, question, callback)
+}
 
-	// Execute the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// Ask sends the question to the stateless chat flow (without memory) of the server
+func (agent *RemoteAgent) Ask(question string) (agents.ChatResponse, error) {
+	return agent.ask(agent.AskEndpoint, question, true)
+}
+
+// AskStream sends the question to the stateless chat stream flow (without memory) of the server
+func (agent *RemoteAgent) AskStream(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	return agent.askStream(context.Background(), agent.AskStreamEndpoint, question, callback)
+}
+
+// AskStreamContext is AskStream with a context
+// If ctx is cancelled (or the callback returns an error), the generation is cancelled on the server side
+func (agent *RemoteAgent) AskStreamContext(ctx context.Context, question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	return agent.askStream(ctx, agent.AskStreamEndpoint, question, callback)
+}
+
+// ask sends the question to a flow endpoint, idempotent if the flow has no memory
+func (agent *RemoteAgent) ask(endpoint string, question string, idempotent bool) (agents.ChatResponse, error) {
+	// Prepare request
+	reqBody := chatserver.FlowRequest{}
+	reqBody.Data.UserMessage = strings.TrimSpace(question)
+
+	body, err := agent.post(endpoint, reqBody, idempotent)
 	if err != nil {
-		return agents.ChatResponse{}, fmt.Errorf("error during HTTP call: %w", err)
+		return agents.ChatResponse{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return agents.ChatResponse{}, fmt.Errorf("HTTP error: status code %d", resp.StatusCode)
+	// Genkit format: {"result": <ChatResponse>}, decoded with all its fields
+	var flowResponse struct {
+		Result *agents.ChatResponse `json:"result"`
 	}
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return agents.ChatResponse{}, fmt.Errorf("error reading response body: %w", err)
+	if err := json.Unmarshal(body, &flowResponse); err == nil && flowResponse.Result != nil && flowResponse.Result.Text != "" {
+		return *flowResponse.Result, nil
 	}
 
 	// Parse JSON response
@@ -288,147 +364,98 @@ func (agent *RemoteAgent) AskWithMemory(question string) (agents.ChatResponse, e
 	return agents.ChatResponse{}, fmt.Errorf("unable to extract message from response")
 }
 
-func (agent *RemoteAgent) AskStreamWithMemory(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+// askStream sends the question to a streaming endpoint
+// The chunks are sent to the callback, the final response is returned with all its fields
+// If the client stops the stream, the generation is cancelled on the server side
+func (agent *RemoteAgent) askStream(ctx context.Context, endpoint string, question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
 	// Prepare request
-	reqBody := RemoteChatRequest{}
-	reqBody.Data.Message = strings.TrimSpace(question)
+	reqBody := chatserver.FlowRequest{}
+	reqBody.Data.UserMessage = strings.TrimSpace(question)
 
-	// Convert to JSON
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		fmt.Printf("Error when creating JSON: %v\n", err)
-		return agents.ChatResponse{}, err
+	reader := &streamReader{callback: callback, started: agent.setCurrentStream, idempotent: endpoint == agent.AskStreamEndpoint}
+	response, interrupted, err := agent.stream(ctx, endpoint, reqBody, reader)
+	agent.endCurrentStream(reader.stream)
+	if interrupted {
+		agent.cancelRemoteStream(reader.stream)
 	}
-	// Create HTTP request
-	req, err := http.NewRequest("POST", agent.ChatStreamEndpoint, bytes.NewBuffer(jsonData))
+	return response, err
+}
+
+// CompressContext asks the server to compress the conversation history with its compressor agent
+// The server replaces its messages with a single system message containing the compressed context
+func (agent *RemoteAgent) CompressContext() (agents.ChatResponse, error) {
+	body, err := agent.postJSON(agent.CompressContextEndpoint, struct{}{})
 	if err != nil {
-		fmt.Printf("Error when creating the request: %v\n", err)
 		return agents.ChatResponse{}, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "text/event-stream")
 
-	// Execute the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error when HTTP call: %v\n", err)
-		return agents.ChatResponse{}, err
+	var response agents.ChatResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return agents.ChatResponse{}, fmt.Errorf("error parsing JSON response: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("HTTP error: status code %d\n", resp.StatusCode)
-		resp.Body.Close()
-		return agents.ChatResponse{}, fmt.Errorf("HTTP error: status code %d", resp.StatusCode)
-	}
-
-	// Read the stream
-	streamReader := bufio.NewReader(resp.Body)
-	fullResponse := ""
-	var callbackErr error
-	for {
-		line, err := streamReader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			callbackErr = err
-			fmt.Printf("\nError when stream reading: %v\n", err)
-			break
-		}
+	return response, nil
+}
 
-		// Read data lines starting with "data: "
-		line = strings.TrimSpace(line)
-		if after, ok := strings.CutPrefix(line, "data: "); ok {
-			data := after
-
-			// Check for end of stream
-			if data == "[DONE]" {
-				break
-			}
-
-			// Parse JSON to extract content
-			var chunk map[string]any
-			if err := json.Unmarshal([]byte(data), &chunk); err == nil {
-				var textContent string
-				var finishReason string
-
-				// Try to extract from nested message.response structure (Genkit streaming format)
-				if messageObj, ok := chunk["message"].(map[string]any); ok {
-					if response, ok := messageObj["response"].(string); ok {
-						textContent = response
-					}
-					if fr, ok := messageObj["finish_reason"].(string); ok {
-						finishReason = fr
-					}
-				}
-
-				// Try result.response for final chunk
-				if resultObj, ok := chunk["result"].(map[string]any); ok {
-					if response, ok := resultObj["response"].(string); ok {
-						textContent = response
-					}
-					if fr, ok := resultObj["finish_reason"].(string); ok {
-						finishReason = fr
-					}
-				}
-
-				// Fallback: try direct "message" or "text" fields (legacy support)
-				if textContent == "" {
-					if message, ok := chunk["message"].(string); ok {
-						textContent = message
-					} else if text, ok := chunk["text"].(string); ok {
-						textContent = text
-					}
-				}
-
-				// Send callback if we have content or finish reason
-				if textContent != "" || finishReason != "" {
-					fullResponse += textContent
-					if cbErr := callback(agents.ChatResponse{
-						Text:         textContent,
-						FinishReason: finishReason,
-					}); cbErr != nil {
-						callbackErr = cbErr
-					}
-				}
-			} else {
-				// If not JSON, print as is
-				fullResponse += data
-				if cbErr := callback(agents.ChatResponse{Text: data}); cbErr != nil {
-					callbackErr = cbErr
-				}
-			}
-		}
-	}
-	resp.Body.Close()
+// CompressContextStream asks the server to compress the conversation history with streaming
+// The callback function is called for each streamed chunk
+func (agent *RemoteAgent) CompressContextStream(callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
+	// Read the stream: each event is either a chunk ("message"), the final response ("result") or an error
+	response, _, err := agent.stream(context.Background(), agent.CompressContextStreamEndpoint, struct{}{}, &streamReader{callback: callback})
+	return response, err
+}
 
-	// Note: Genkit already sends a final chunk with finish_reason in the stream,
-	// so we don't need to send an additional one here (unlike local agents)
+// GetChatFlowWithMemory returns nil: the flows of a remote agent run on the server side
+func (agent *RemoteAgent) GetChatFlowWithMemory() *core.Flow[*agents.ChatRequest, *agents.ChatResponse, struct{}] {
+	return nil
+}
 
-	return agents.ChatResponse{Text: fullResponse}, callbackErr
+// GetChatStreamFlowWithMemory returns nil: the flows of a remote agent run on the server side
+func (agent *RemoteAgent) GetChatStreamFlowWithMemory() *core.Flow[*agents.ChatRequest, *agents.ChatResponse, agents.ChatResponse] {
+	return nil
 }
 
-// Ask is an alias for AskWithMemory for RemoteAgent
-// Remote agents delegate memory management to the server
-func (agent *RemoteAgent) Ask(question string) (agents.ChatResponse, error) {
-	return agent.AskWithMemory(question)
+// GetStreamCancel returns a function that cancels, on the server side, the last streaming completion started by the agent
+func (agent *RemoteAgent) GetStreamCancel() context.CancelFunc {
+	return func() {
+		agent.streamMu.Lock()
+		stream := agent.currentStream
+		agent.streamMu.Unlock()
+		if stream.id != "" {
+			agent.cancelRemoteStream(stream)
+		}
+	}
 }
 
-// AskStream is an alias for AskStreamWithMemory for RemoteAgent
-// Remote agents delegate memory management to the server
-func (agent *RemoteAgent) AskStream(question string, callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
-	return agent.AskStreamWithMemory(question, callback)
+// setCurrentStream records the last stream started by the agent
+func (agent *RemoteAgent) setCurrentStream(stream remoteStream) {
+	agent.streamMu.Lock()
+	defer agent.streamMu.Unlock()
+	agent.currentStream = stream
 }
 
-// CompressContext is not supported for remote agents
-// Context compression must be performed on the server side
-func (agent *RemoteAgent) CompressContext() (agents.ChatResponse, error) {
-	return agents.ChatResponse{}, fmt.Errorf("CompressContext is not supported for remote agents: compression must be performed on the server side")
+// endCurrentStream forgets the stream if it is the last one started by the agent
+func (agent *RemoteAgent) endCurrentStream(stream remoteStream) {
+	agent.streamMu.Lock()
+	defer agent.streamMu.Unlock()
+	if agent.currentStream == stream {
+		agent.currentStream = remoteStream{}
+	}
 }
 
-// CompressContextStream is not supported for remote agents
-// Context compression must be performed on the server side
-func (agent *RemoteAgent) CompressContextStream(callback func(agents.ChatResponse) error) (agents.ChatResponse, error) {
-	return agents.ChatResponse{}, fmt.Errorf("CompressContextStream is not supported for remote agents: compression must be performed on the server side")
+// cancelRemoteStream cancels a streaming completion on the server side (on its replica), by its stream id
+// Without stream id (the server sent none), nothing is cancelled: the server would cancel
+// its current completion, which may be the one of another client
+func (agent *RemoteAgent) cancelRemoteStream(stream remoteStream) {
+	if agent.CancelStreamEndpoint == "" {
+		return
+	}
+	if stream.id == "" {
+		agent.log().Warn("Cannot cancel the remote stream: the server did not send its id")
+		return
+	}
+	request := chatserver.CancelStreamRequest{StreamID: stream.id}
+	if _, err := agent.postIdempotentJSON(stream.endpoint(agent.CancelStreamEndpoint), request); err != nil {
+		agent.log().Warn("Error cancelling the remote stream %s: %v", stream.id, err)
+	}
 }
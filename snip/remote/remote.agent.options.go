@@ -0,0 +1,123 @@
+package remote
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// RemoteAgentOption defines a functional option for configuring the remote agents
+// (RemoteAgent, RemoteToolsAgent, RemoteRagAgent and RemoteStructuredAgent)
+type RemoteAgentOption func(*remoteClient)
+
+// WithHTTPClient sets the HTTP client used for every call to the server
+// By default, the remote agent uses a client shared by all its calls, with http.DefaultTransport
+func WithHTTPClient(client *http.Client) RemoteAgentOption {
+	return func(c *remoteClient) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithTransport sets the transport (e.g. a custom TLS configuration or a proxy) of the HTTP client
+func WithTransport(transport http.RoundTripper) RemoteAgentOption {
+	return func(c *remoteClient) {
+		client := *c.httpClient
+		client.Transport = transport
+		c.httpClient = &client
+	}
+}
+
+// WithBaseURL sets the base URL of the server, e.g. "https://agents.example.com/bob"
+// It takes precedence over the Address of the server configuration
+func WithBaseURL(baseURL string) RemoteAgentOption {
+	return func(c *remoteClient) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithHeader adds a header sent with every call to the server
+func WithHeader(key, value string) RemoteAgentOption {
+	return func(c *remoteClient) {
+		c.headers.Set(key, value)
+	}
+}
+
+// WithHeaders adds headers sent with every call to the server
+func WithHeaders(headers map[string]string) RemoteAgentOption {
+	return func(c *remoteClient) {
+		for key, value := range headers {
+			c.headers.Set(key, value)
+		}
+	}
+}
+
+// WithBearerToken sends "Authorization: Bearer <token>" with every call to the server
+func WithBearerToken(token string) RemoteAgentOption {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithTimeout sets the maximum duration of each non-streaming call to the server (0 means no timeout)
+// Streaming calls are only limited by the Timeout of the HTTP client, so that long answers are not cut
+func WithTimeout(timeout time.Duration) RemoteAgentOption {
+	return func(c *remoteClient) {
+		c.timeout = timeout
+	}
+}
+
+// DefaultRetryBackoff is the first delay between two attempts when WithRetries is given no backoff
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// WithRetries retries a call up to maxRetries times when the server cannot be reached
+// or answers 429, 502, 503 or 504, waiting backoff, then twice as long, and so on between attempts
+// A zero or negative backoff is replaced by DefaultRetryBackoff, so that the retries do not hammer a failing server
+// Only the idempotent calls are retried after the request was sent: the reads, Ask, AskStream, the RAG searches,
+// the structured generation and the calls replacing the history or the instructions
+// The other calls (e.g. AskWithMemory, AddSystemMessage or the RAG AddTextChunksToStore) would be replayed
+// by the server: they are only retried when the connection could not be opened, or on 429
+func WithRetries(maxRetries int, backoff time.Duration) RemoteAgentOption {
+	return func(c *remoteClient) {
+		if backoff <= 0 {
+			backoff = DefaultRetryBackoff
+		}
+		c.maxRetries = max(maxRetries, 0)
+		c.retryBackoff = backoff
+	}
+}
+
+// WithStreamResumes sets how many times a dropped stream is resumed with the Last-Event-ID header
+// (DefaultStreamResumes by default, 0 disables it)
+// The server must buffer the events of its streams, as ChatAgentServer does
+func WithStreamResumes(maxResumes int) RemoteAgentOption {
+	return func(c *remoteClient) {
+		maxResumes = max(maxResumes, 0)
+		c.maxStreamResumes = &maxResumes
+	}
+}
+
+// WithBalancing sets how the replicas are chosen by an agent created with NewRemoteAgentFromRegistry
+// (RoundRobin by default)
+func WithBalancing(balancing Balancing) RemoteAgentOption {
+	return func(c *remoteClient) {
+		c.balancing = balancing
+	}
+}
+
+// WithSessionStickiness keeps the calls of an agent created with NewRemoteAgentFromRegistry on the same replica
+// until it fails (enabled by default, as the conversation memory lives on the server)
+// Disable it for stateless calls (Ask, AskStream), to spread every call over the replicas
+func WithSessionStickiness(enabled bool) RemoteAgentOption {
+	return func(c *remoteClient) {
+		c.sticky = &enabled
+	}
+}
+
+// WithLogger sets a custom logger for the agent
+// It is used to report the errors of the methods that cannot return them (e.g. GetMessages)
+func WithLogger(log logger.Logger) RemoteAgentOption {
+	return func(c *remoteClient) {
+		c.logger = log
+	}
+}
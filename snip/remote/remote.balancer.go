@@ -0,0 +1,217 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+	"github.com/snipwise/snip-sdk/snip/registry"
+)
+
+// Balancing is the strategy used to choose a replica of a server registered in a registry
+type Balancing int
+
+const (
+	// RoundRobin uses the replicas in turn
+	RoundRobin Balancing = iota
+	// LeastActive uses the replica with the fewest calls in progress from this client
+	LeastActive
+)
+
+// DefaultReplicaDownDuration is how long a replica that failed its healthcheck is left out of the balancing
+const DefaultReplicaDownDuration = 10 * time.Second
+
+// replicaPool chooses the replica of each call among the live instances of a registry
+type replicaPool struct {
+	registry        registry.Registry
+	name            string
+	healthcheckPath string
+	balancing       Balancing
+	sticky          bool
+
+	mu     sync.Mutex
+	next   int
+	active map[string]int
+	// down holds the replicas that failed their healthcheck, until the given time
+	down map[string]time.Time
+	// session is the replica of the session when the pool is sticky
+	session string
+}
+
+// pick returns the address of the replica for the next call, skipping the excluded ones
+func (p *replicaPool) pick(exclude map[string]bool) (string, error) {
+	instances, err := p.registry.Lookup(p.name)
+	if err != nil {
+		return "", fmt.Errorf("error looking up %s in the registry: %w", p.name, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var candidates []string
+	for _, instance := range instances {
+		if exclude[instance.Address] {
+			continue
+		}
+		if until, ok := p.down[instance.Address]; ok {
+			if now.Before(until) {
+				continue
+			}
+			delete(p.down, instance.Address)
+		}
+		candidates = append(candidates, instance.Address)
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no healthy replica of %s in the registry", p.name)
+	}
+
+	if p.sticky && p.session != "" {
+		for _, address := range candidates {
+			if address == p.session {
+				return address, nil
+			}
+		}
+	}
+
+	var address string
+	switch p.balancing {
+	case LeastActive:
+		address = candidates[0]
+		for _, candidate := range candidates[1:] {
+			if p.active[candidate] < p.active[address] {
+				address = candidate
+			}
+		}
+	default:
+		address = candidates[p.next%len(candidates)]
+		p.next++
+	}
+
+	if p.sticky {
+		p.session = address
+	}
+	return address, nil
+}
+
+// acquire counts a call in progress on a replica, the returned function ends it
+func (p *replicaPool) acquire(address string) func() {
+	p.mu.Lock()
+	p.active[address]++
+	p.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			p.active[address]--
+			p.mu.Unlock()
+		})
+	}
+}
+
+// markDown leaves a replica out of the balancing for DefaultReplicaDownDuration
+func (p *replicaPool) markDown(address string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.down[address] = time.Now().Add(DefaultReplicaDownDuration)
+	if p.session == address {
+		p.session = ""
+	}
+}
+
+// NewRemoteAgentFromRegistry creates a remote agent for the replicas of the ChatAgentServer registered under name
+// (see chatserver.WithRegistry). Only the paths of config are used, the address comes from the registry
+// Each call goes to a live replica chosen with the WithBalancing strategy (round-robin by default)
+// By default the agent sticks to the replica of its first call, because the conversation memory lives on the server;
+// it only moves to another replica (with an empty memory) when its replica fails its healthcheck
+func NewRemoteAgentFromRegistry(name string, reg registry.Registry, config chatserver.ConfigHTTP, opts ...RemoteAgentOption) *RemoteAgent {
+	agent := newRemoteAgent(name, opts...)
+	agent.setEndpoints("", config)
+
+	agent.pool = &replicaPool{
+		registry:        reg,
+		name:            name,
+		healthcheckPath: pathOrDefault(config.HealthcheckPath, chatserver.DefaultHealthcheckPath),
+		balancing:       agent.balancing,
+		sticky:          agent.sticky == nil || *agent.sticky,
+		active:          map[string]int{},
+		down:            map[string]time.Time{},
+	}
+	return agent
+}
+
+// doBalanced sends a request to the endpoint path on a replica of the pool
+// If the replica cannot be reached or answers 502, 503 or 504, and fails its healthcheck, the next replica is tried
+// A request that is not idempotent only goes to the next replica if it could not reach this one,
+// since a failing replica may have processed it
+func (c *remoteClient) doBalanced(ctx context.Context, method, path string, body []byte, streaming, idempotent bool, header http.Header) (*http.Response, error) {
+	tried := map[string]bool{}
+	for {
+		address, err := c.pool.pick(tried)
+		if err != nil {
+			return nil, err
+		}
+		tried[address] = true
+
+		release := c.pool.acquire(address)
+		resp, err := c.do(ctx, method, address+path, body, streaming, idempotent, header)
+		failed := err != nil || resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout
+		// A request that is not idempotent is only sent to another replica if it did not reach this one
+		if !idempotent && (err == nil || !notSent(err)) {
+			failed = false
+		}
+
+		if !failed || ctx.Err() != nil || c.healthy(ctx, address) {
+			if err != nil {
+				release()
+				return nil, err
+			}
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		}
+
+		release()
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		c.log().Warn("Replica %s of %s failed its healthcheck, trying another replica", address, c.pool.name)
+		c.pool.markDown(address)
+	}
+}
+
+// healthy calls the healthcheck endpoint of a replica
+func (c *remoteClient) healthy(ctx context.Context, address string) bool {
+	resp, err := c.send(ctx, "GET", address+c.pool.healthcheckPath, nil, false, nil)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode == http.StatusOK
+}
+
+// isBalancedPath reports whether the endpoint is a path to resolve on a replica of the pool
+func (c *remoteClient) isBalancedPath(endpoint string) bool {
+	return c.pool != nil && strings.HasPrefix(endpoint, "/")
+}
+
+// releaseOnClose ends the count of a call in progress when its response body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
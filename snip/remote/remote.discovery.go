@@ -0,0 +1,53 @@
+package remote
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+)
+
+// NewRemoteAgentFromURL creates a remote agent from the base URL of a ChatAgentServer (e.g. "http://localhost:8080")
+// The endpoints are read from the discovery document of the server (chatserver.DefaultDiscoveryPath),
+// so the paths do not need to be mirrored on the client side
+// The endpoints not registered by the server are left empty and the related methods return an error
+func NewRemoteAgentFromURL(baseURL string, opts ...RemoteAgentOption) (*RemoteAgent, error) {
+	agent := newRemoteAgent("", opts...)
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	var document chatserver.DiscoveryDocument
+	if err := agent.getJSON(baseURL+chatserver.DefaultDiscoveryPath, &document); err != nil {
+		return nil, fmt.Errorf("error reading discovery document: %w", err)
+	}
+
+	agent.applyDiscovery(baseURL, document)
+	return agent, nil
+}
+
+// applyDiscovery maps the endpoints of the discovery document on the fields of the remote agent
+func (agent *RemoteAgent) applyDiscovery(baseURL string, document chatserver.DiscoveryDocument) {
+	endpoint := func(name string) string {
+		if e, ok := document.Endpoints[name]; ok {
+			return baseURL + e.Path
+		}
+		return ""
+	}
+
+	agent.ChatStreamEndpoint = endpoint(chatserver.EndpointChatStream)
+	agent.ChatEndPoint = endpoint(chatserver.EndpointChat)
+	agent.AskStreamEndpoint = endpoint(chatserver.EndpointAskStream)
+	agent.AskEndpoint = endpoint(chatserver.EndpointAsk)
+	agent.InformationEndpoint = endpoint(chatserver.EndpointInformation)
+	agent.AddContextEndpoint = endpoint(chatserver.EndpointAddSystemMessage)
+	agent.GetMessagesEndpoint = endpoint(chatserver.EndpointGetMessages)
+	agent.Name = document.Name
+
+	agent.ReplaceMessagesEndpoint = endpoint(chatserver.EndpointReplaceMessages)
+	agent.ReplaceWithSystemMessagesEndpoint = endpoint(chatserver.EndpointReplaceWithSystemMessages)
+	agent.ClearMessagesEndpoint = endpoint(chatserver.EndpointClearMessages)
+	agent.TruncateMessagesEndpoint = endpoint(chatserver.EndpointTruncateMessages)
+	agent.SystemInstructionsEndpoint = endpoint(chatserver.EndpointGetSystemInstructions)
+	agent.CompressContextEndpoint = endpoint(chatserver.EndpointCompressContext)
+	agent.CompressContextStreamEndpoint = endpoint(chatserver.EndpointCompressContextStream)
+	agent.CancelStreamEndpoint = endpoint(chatserver.EndpointCancelStream)
+}
@@ -0,0 +1,21 @@
+package remote
+
+import (
+	"fmt"
+
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+)
+
+// HTTPError is returned when the server answers with a status code other than 200
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP error: status code %d, body: %s", e.StatusCode, e.Body)
+}
+
+// StreamError is returned when the server sends an error event in a stream
+// (e.g. the generation failed after the first chunks were sent)
+type StreamError = chatserver.StreamError
@@ -0,0 +1,292 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/toolbox/logger"
+)
+
+// remoteClient is the HTTP client shared by the remote agents
+// It holds the settings of the RemoteAgentOption options
+type remoteClient struct {
+	httpClient   *http.Client
+	baseURL      string
+	headers      http.Header
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	// maxStreamResumes is the number of times a dropped stream is resumed (DefaultStreamResumes if nil)
+	maxStreamResumes *int
+	logger           logger.Logger
+
+	// balancing settings, and the replicas of the server when the agent uses a registry (see remote.balancer.go)
+	balancing Balancing
+	sticky    *bool
+	pool      *replicaPool
+}
+
+// newRemoteClient creates a client and applies the options
+func newRemoteClient(opts ...RemoteAgentOption) remoteClient {
+	c := remoteClient{
+		httpClient: &http.Client{},
+		headers:    http.Header{},
+		logger:     &logger.NoOpLogger{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// baseURLFromAddress returns the base URL of a server address
+// An address without scheme (e.g. "localhost:8080") is served over http
+func baseURLFromAddress(address string) string {
+	address = strings.TrimRight(address, "/")
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		return address
+	}
+	return "http://" + address
+}
+
+// client returns the HTTP client of the agent
+// A remote agent created as a struct literal uses http.DefaultClient
+func (c *remoteClient) client() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+	return c.httpClient
+}
+
+// log returns the logger of the agent (no-op if not set)
+func (c *remoteClient) log() logger.Logger {
+	if c.logger == nil {
+		return &logger.NoOpLogger{}
+	}
+	return c.logger
+}
+
+// isRetryableStatus reports whether a status code is worth a new attempt
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// notSent reports whether a request failed before reaching the server (the connection could not be opened)
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// shouldRetry reports whether a request is worth a new attempt after err or resp
+// A request that is not idempotent (e.g. AskWithMemory or AddSystemMessage) may have been processed by the server
+// before the error: it is only sent again if it did not reach the server, or was rejected with 429
+func shouldRetry(idempotent bool, resp *http.Response, err error) bool {
+	if idempotent {
+		return err != nil || isRetryableStatus(resp.StatusCode)
+	}
+	if err != nil {
+		return notSent(err)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests
+}
+
+// do sends a request to endpoint with the default headers of the agent and header, retrying if configured
+// body is sent as JSON when not nil
+// The GET requests are idempotent, the POST requests only if idempotent is true (see shouldRetry)
+// When streaming is false, the timeout of the agent applies to the call, including the read of the response body
+// The caller must close the body of the returned response
+func (c *remoteClient) do(ctx context.Context, method, endpoint string, body []byte, streaming, idempotent bool, header http.Header) (*http.Response, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint not available on the remote server")
+	}
+	idempotent = idempotent || method == "GET"
+	if c.isBalancedPath(endpoint) {
+		return c.doBalanced(ctx, method, endpoint, body, streaming, idempotent, header)
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, endpoint, body, streaming, header)
+
+		retryable := shouldRetry(idempotent, resp, err)
+		if !retryable || attempt >= c.maxRetries {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err != nil {
+			c.log().Warn("Call to %s failed (attempt %d/%d): %v", endpoint, attempt+1, c.maxRetries+1, err)
+		} else {
+			c.log().Warn("Call to %s failed (attempt %d/%d): status code %d", endpoint, attempt+1, c.maxRetries+1, resp.StatusCode)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error during HTTP call: %w", ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// send makes a single attempt of a request
+func (c *remoteClient) send(ctx context.Context, method, endpoint string, body []byte, streaming bool, header http.Header) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if !streaming && c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bodyReader)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	for key, values := range c.headers {
+		req.Header[key] = values
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if streaming {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("error during HTTP call: %w", err)
+	}
+
+	// Release the timeout context once the body is closed
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose cancels the context of a request when its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// getJSON sends a GET request to endpoint and decodes the JSON response into result
+// A status code other than 200 is returned as an *HTTPError
+func (c *remoteClient) getJSON(endpoint string, result any) error {
+	resp, err := c.do(context.Background(), "GET", endpoint, nil, false, true, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return nil
+}
+
+// postJSON sends reqBody as JSON to endpoint and returns the response body
+// The request changes the state of the server: it is not retried once sent (see shouldRetry)
+// A status code other than 200 is returned as an *HTTPError
+func (c *remoteClient) postJSON(endpoint string, reqBody any) ([]byte, error) {
+	return c.post(endpoint, reqBody, false)
+}
+
+// postIdempotentJSON is postJSON for the requests that can be sent again without side effect
+// (e.g. a question without memory or a search)
+func (c *remoteClient) postIdempotentJSON(endpoint string, reqBody any) ([]byte, error) {
+	return c.post(endpoint, reqBody, true)
+}
+
+func (c *remoteClient) post(endpoint string, reqBody any, idempotent bool) ([]byte, error) {
+	return c.postContext(context.Background(), endpoint, reqBody, idempotent)
+}
+
+// postContext is post, cancelled when ctx is done
+func (c *remoteClient) postContext(ctx context.Context, endpoint string, reqBody any, idempotent bool) ([]byte, error) {
+	// Convert to JSON
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating JSON: %w", err)
+	}
+
+	resp, err := c.do(ctx, "POST", endpoint, jsonData, false, idempotent, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return body, nil
+}
+
+// postStream sends reqBody as JSON to endpoint and returns the response of a server-sent events stream
+// If lastEventID is not empty, it is sent in the Last-Event-ID header to resume a stream (which is idempotent)
+// A status code other than 200 is returned as an *HTTPError
+// The caller must close the body of the returned response
+func (c *remoteClient) postStream(ctx context.Context, endpoint string, reqBody any, lastEventID string, idempotent bool) (*http.Response, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating JSON: %w", err)
+	}
+
+	var header http.Header
+	if lastEventID != "" {
+		header = http.Header{"Last-Event-ID": {lastEventID}}
+	}
+
+	resp, err := c.do(ctx, "POST", endpoint, jsonData, true, idempotent || lastEventID != "", header)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,103 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/text"
+)
+
+// RemoteRagAgent implements snip.AIRagAgent for a RAG agent served with chatserver.NewRagAgentHandler
+// The embeddings are computed and stored on the server side
+type RemoteRagAgent struct {
+	Name                string
+	InformationEndpoint string
+	AddChunksEndpoint   string
+	SearchEndpoint      string
+
+	remoteClient
+}
+
+// NewRemoteRagAgent creates a remote RAG agent for the server at baseURL (e.g. "http://embeddings.local:8080"),
+// using the default paths of chatserver.ConfigAgentHTTP
+func NewRemoteRagAgent(name, baseURL string, opts ...RemoteAgentOption) *RemoteRagAgent {
+	agent := &RemoteRagAgent{Name: name, remoteClient: newRemoteClient(opts...)}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	agent.InformationEndpoint = baseURL + chatserver.DefaultInformationPath
+	agent.AddChunksEndpoint = baseURL + chatserver.DefaultRagAddChunksPath
+	agent.SearchEndpoint = baseURL + chatserver.DefaultRagSearchPath
+	return agent
+}
+
+func (agent *RemoteRagAgent) GetName() string {
+	return agent.Name
+}
+
+func (agent *RemoteRagAgent) Kind() agents.AgentKind {
+	return agents.Remote
+}
+
+// GetInfo returns the information of the remote RAG agent
+func (agent *RemoteRagAgent) GetInfo() (agents.RagAgentInfo, error) {
+	var info agents.RagAgentInfo
+	if err := agent.getJSON(agent.InformationEndpoint, &info); err != nil {
+		return agents.RagAgentInfo{}, fmt.Errorf("error getting RAG agent information: %w", err)
+	}
+	return info, nil
+}
+
+// AddTextChunksToStore sends the chunks to the remote RAG agent and returns the number of chunks added
+func (agent *RemoteRagAgent) AddTextChunksToStore(chunks []text.TextChunk) (int, error) {
+	body, err := agent.postJSON(agent.AddChunksEndpoint, chatserver.RagAddChunksRequest{Chunks: chunks})
+	if err != nil {
+		return 0, fmt.Errorf("error adding text chunks: %w", err)
+	}
+
+	var response chatserver.RagAddChunksResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("error parsing add chunks response: %w", err)
+	}
+	return response.Count, nil
+}
+
+// SearchSimilarities returns the content of the chunks of the remote store similar to the query
+func (agent *RemoteRagAgent) SearchSimilarities(query string) ([]string, error) {
+	results, err := agent.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := []string{}
+	for _, result := range results {
+		contents = append(contents, result.Content)
+	}
+	return contents, nil
+}
+
+// Search returns the chunks of the remote store similar to the query, with their score and metadata
+func (agent *RemoteRagAgent) Search(query string, opts ...rag.SearchOption) ([]rag.SearchResult, error) {
+	return agent.SearchContext(context.Background(), query, opts...)
+}
+
+// SearchContext is Search, cancelled when ctx is done (see rag.ContextSearcher)
+func (agent *RemoteRagAgent) SearchContext(ctx context.Context, query string, opts ...rag.SearchOption) ([]rag.SearchResult, error) {
+	body, err := agent.postContext(ctx, agent.SearchEndpoint, chatserver.RagSearchRequest{
+		Query:   query,
+		Options: rag.NewSearchOptions(opts...),
+	}, true)
+	if err != nil {
+		return nil, fmt.Errorf("error searching similarities: %w", err)
+	}
+
+	var response chatserver.RagSearchResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing search response: %w", err)
+	}
+	return response.Matches, nil
+}
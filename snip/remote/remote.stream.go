@@ -0,0 +1,192 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+	"github.com/snipwise/snip-sdk/snip/toolbox/sse"
+)
+
+// DefaultStreamResumes is the default number of times a dropped stream is resumed
+const DefaultStreamResumes = 3
+
+// streamReader reads the server-sent events of a streaming endpoint, over one or several connections
+type streamReader struct {
+	callback func(agents.ChatResponse) error
+
+	// partial is the answer built from the chunks received so far
+	partial agents.ChatResponse
+	// lastEventID is the id of the last event received, used to resume the stream
+	lastEventID string
+	// stream identifies the stream on the server, to resume and cancel it
+	stream remoteStream
+	// started is called with the stream once its id is known (optional)
+	started func(stream remoteStream)
+	// idempotent is true if the request can be sent again (a question without memory), see shouldRetry
+	idempotent bool
+	// callbackErr is the error returned by the callback, which stops the stream
+	callbackErr error
+}
+
+// read reads the events of a connection
+// The callback is called with each chunk ("message" events), the final response ("result" event) is returned as is
+// An "error" event is returned as a *StreamError, with the partial answer
+// done is false if the connection ended before the end of the stream (the stream can be resumed);
+// then err is the read error, if any
+func (s *streamReader) read(body io.Reader) (response agents.ChatResponse, done bool, err error) {
+	decoder := sse.NewDecoder(body)
+
+	for {
+		event, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			return s.partial, false, nil
+		}
+		if err != nil {
+			return s.partial, false, err
+		}
+		if event.ID != "" {
+			s.lastEventID = event.ID
+			if streamID, _, found := strings.Cut(event.ID, ":"); found {
+				s.setStreamID(streamID)
+			}
+		}
+
+		if event.Data == "[DONE]" {
+			return s.partial, true, nil
+		}
+
+		streamEvent, err := decodeStreamEvent(event.Data)
+		if err != nil {
+			return s.partial, true, err
+		}
+
+		switch {
+		case streamEvent.Error != nil:
+			return s.partial, true, streamEvent.Error
+		case streamEvent.Result != nil:
+			return *streamEvent.Result, true, nil
+		case streamEvent.Message != nil:
+			chunk := *streamEvent.Message
+			s.partial.Text += chunk.Text
+			s.partial.ReasoningContent += chunk.ReasoningContent
+			if chunk.FinishReason != "" {
+				s.partial.FinishReason = chunk.FinishReason
+				s.partial.FinishMessage = chunk.FinishMessage
+			}
+			if err := s.callback(chunk); err != nil {
+				s.callbackErr = err
+				return s.partial, true, err
+			}
+		}
+	}
+}
+
+// remoteStream identifies a stream on the server
+type remoteStream struct {
+	// id is the StreamIDHeader of the response, or the prefix of the event ids
+	id string
+	// replica is the base URL of the replica serving the stream, when the agent balances its calls
+	replica string
+}
+
+// endpoint returns the URL of endpoint for the stream: on its replica, so that the balancer does not choose another one
+func (s remoteStream) endpoint(endpoint string) string {
+	if s.replica != "" && strings.HasPrefix(endpoint, "/") {
+		return s.replica + endpoint
+	}
+	return endpoint
+}
+
+// setStreamID records the id of the stream on the server
+func (s *streamReader) setStreamID(streamID string) {
+	if s.stream.id != "" || streamID == "" {
+		return
+	}
+	s.stream.id = streamID
+	if s.started != nil {
+		s.started(s.stream)
+	}
+}
+
+// stream posts reqBody to a streaming endpoint and reads the events with reader
+// If the connection drops and the server sent event ids, the stream is resumed with the Last-Event-ID header
+// interrupted is true if the stream was stopped by the client (callback error or cancelled context)
+// A stream ending before its result that cannot be resumed returns the partial answer and an error
+// wrapping io.ErrUnexpectedEOF (or the read error)
+func (c *remoteClient) stream(ctx context.Context, endpoint string, reqBody any, reader *streamReader) (response agents.ChatResponse, interrupted bool, err error) {
+	backoff := c.retryBackoff
+
+	for resumes := 0; ; resumes++ {
+		// A stream is resumed on its replica
+		resp, err := c.postStream(ctx, reader.stream.endpoint(endpoint), reqBody, reader.lastEventID, reader.idempotent)
+		if err != nil {
+			if ctx.Err() != nil {
+				return reader.partial, true, ctx.Err()
+			}
+			return reader.partial, false, err
+		}
+		if c.isBalancedPath(endpoint) && reader.stream.replica == "" {
+			reader.stream.replica = resp.Request.URL.Scheme + "://" + resp.Request.URL.Host
+		}
+		reader.setStreamID(resp.Header.Get(chatserver.StreamIDHeader))
+
+		response, done, err := reader.read(resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case ctx.Err() != nil:
+			return reader.partial, true, ctx.Err()
+		case reader.callbackErr != nil:
+			return reader.partial, true, reader.callbackErr
+		case done:
+			return response, false, err
+		case reader.lastEventID == "" || resumes >= c.streamResumes():
+			// The stream cannot be resumed: return what was received, with an error so that
+			// the partial answer is not taken for a complete one
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return reader.partial, false, fmt.Errorf("stream ended before its result: %w", err)
+		}
+
+		c.log().Warn("Stream from %s interrupted after event %s, resuming (%d/%d)", endpoint, reader.lastEventID, resumes+1, c.streamResumes())
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return reader.partial, true, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// streamResumes returns the maximum number of times a stream is resumed
+func (c *remoteClient) streamResumes() int {
+	if c.maxStreamResumes == nil {
+		return DefaultStreamResumes
+	}
+	return *c.maxStreamResumes
+}
+
+// decodeStreamEvent parses the data of a server-sent event
+func decodeStreamEvent(data string) (chatserver.StreamEvent, error) {
+	var streamEvent chatserver.StreamEvent
+	if err := json.Unmarshal([]byte(data), &streamEvent); err != nil {
+		// Genkit does not escape the details of the error events, so they may not be valid JSON
+		if strings.HasPrefix(strings.TrimSpace(data), `{"error"`) {
+			return chatserver.StreamEvent{Error: &StreamError{
+				Status:  "INTERNAL",
+				Message: "stream flow error",
+				Details: data,
+			}}, nil
+		}
+		return chatserver.StreamEvent{}, fmt.Errorf("error parsing stream event: %w", err)
+	}
+	return streamEvent, nil
+}
@@ -0,0 +1,53 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+)
+
+// RemoteStructuredAgent implements snip.StructuredAgentInterface[O] for a structured agent
+// served with chatserver.NewStructuredAgentHandler
+// O must be decoded from the same JSON as the output type of the server
+type RemoteStructuredAgent[O any] struct {
+	Name             string
+	GenerateEndpoint string
+
+	remoteClient
+}
+
+// NewRemoteStructuredAgent creates a remote structured agent for the server at baseURL,
+// using the default paths of chatserver.ConfigAgentHTTP
+func NewRemoteStructuredAgent[O any](name, baseURL string, opts ...RemoteAgentOption) *RemoteStructuredAgent[O] {
+	agent := &RemoteStructuredAgent[O]{Name: name, remoteClient: newRemoteClient(opts...)}
+	agent.GenerateEndpoint = strings.TrimRight(baseURL, "/") + chatserver.DefaultStructuredGeneratePath
+	return agent
+}
+
+func (agent *RemoteStructuredAgent[O]) GetName() string {
+	return agent.Name
+}
+
+func (agent *RemoteStructuredAgent[O]) Kind() agents.AgentKind {
+	return agents.Remote
+}
+
+// GenerateStructuredData sends the text to the remote structured agent and decodes the generated data
+func (agent *RemoteStructuredAgent[O]) GenerateStructuredData(text string) (*O, error) {
+	body, err := agent.postIdempotentJSON(agent.GenerateEndpoint, chatserver.StructuredGenerateRequest{Text: text})
+	if err != nil {
+		return nil, fmt.Errorf("error generating structured data: %w", err)
+	}
+
+	var response chatserver.StructuredGenerateResponse[O]
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing structured data: %w", err)
+	}
+	if response.Result == nil {
+		return nil, fmt.Errorf("no structured data in the response")
+	}
+	return response.Result, nil
+}
@@ -0,0 +1,63 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+	"github.com/snipwise/snip-sdk/snip/tools"
+)
+
+// RemoteToolsAgent implements snip.AIToolsAgent for a tools agent served with chatserver.NewToolsAgentHandler
+// The tools run on the server side
+type RemoteToolsAgent struct {
+	Name                string
+	InformationEndpoint string
+	ToolCallsEndpoint   string
+
+	remoteClient
+}
+
+// NewRemoteToolsAgent creates a remote tools agent for the server at baseURL (e.g. "http://tools.local:8080"),
+// using the default paths of chatserver.ConfigAgentHTTP
+func NewRemoteToolsAgent(name, baseURL string, opts ...RemoteAgentOption) *RemoteToolsAgent {
+	agent := &RemoteToolsAgent{Name: name, remoteClient: newRemoteClient(opts...)}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	agent.InformationEndpoint = baseURL + chatserver.DefaultInformationPath
+	agent.ToolCallsEndpoint = baseURL + chatserver.DefaultToolCallsPath
+	return agent
+}
+
+func (agent *RemoteToolsAgent) GetName() string {
+	return agent.Name
+}
+
+func (agent *RemoteToolsAgent) Kind() agents.AgentKind {
+	return agents.Remote
+}
+
+// GetInfo returns the information of the remote tools agent
+func (agent *RemoteToolsAgent) GetInfo() (agents.ToolsAgentInfo, error) {
+	var info agents.ToolsAgentInfo
+	if err := agent.getJSON(agent.InformationEndpoint, &info); err != nil {
+		return agents.ToolsAgentInfo{}, fmt.Errorf("error getting tools agent information: %w", err)
+	}
+	return info, nil
+}
+
+// RunToolCalls sends the prompt to the remote tools agent, which detects and runs the tool calls
+func (agent *RemoteToolsAgent) RunToolCalls(prompt string) (tools.ToolCallsResult, error) {
+	body, err := agent.postJSON(agent.ToolCallsEndpoint, tools.ToolCallsRequest{Prompt: prompt})
+	if err != nil {
+		return tools.ToolCallsResult{}, fmt.Errorf("error running tool calls: %w", err)
+	}
+
+	var result tools.ToolCallsResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return tools.ToolCallsResult{}, fmt.Errorf("error parsing tool calls result: %w", err)
+	}
+	return result, nil
+}
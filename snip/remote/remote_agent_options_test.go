@@ -0,0 +1,212 @@
+package remote
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+)
+
+// ============================================================================
+// Tests for RemoteAgent options
+// ============================================================================
+
+func TestNewRemoteAgentBaseURL(t *testing.T) {
+	t.Run("https address", func(t *testing.T) {
+		agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{Address: "https://agents.example.com/", ChatFlowPath: "/api/chat"})
+		if agent.ChatEndPoint != "https://agents.example.com/api/chat" {
+			t.Errorf("ChatEndPoint = %q, want %q", agent.ChatEndPoint, "https://agents.example.com/api/chat")
+		}
+	})
+
+	t.Run("WithBaseURL takes precedence over the address", func(t *testing.T) {
+		agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{Address: "localhost:8080", ChatFlowPath: "/api/chat"},
+			WithBaseURL("https://agents.example.com/bob"))
+		if agent.ChatEndPoint != "https://agents.example.com/bob/api/chat" {
+			t.Errorf("ChatEndPoint = %q, want %q", agent.ChatEndPoint, "https://agents.example.com/bob/api/chat")
+		}
+	})
+}
+
+func TestRemoteAgentHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+		}
+		if got := r.Header.Get("X-Tenant"); got != "acme" {
+			t.Errorf("X-Tenant = %q, want %q", got, "acme")
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{},
+		WithBaseURL(server.URL),
+		WithBearerToken("secret"),
+		WithHeaders(map[string]string{"X-Tenant": "acme"}),
+	)
+
+	if _, err := agent.GetMessagesE(); err != nil {
+		t.Errorf("GetMessagesE() unexpected error: %v", err)
+	}
+}
+
+func TestRemoteAgentTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL(server.URL), WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	if err := agent.ClearMessages(); err == nil {
+		t.Error("ClearMessages() expected timeout error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("ClearMessages() took %v, want about 50ms", elapsed)
+	}
+}
+
+func TestRemoteAgentRetries(t *testing.T) {
+	t.Run("retries until success", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"status":"success"}`))
+		}))
+		defer server.Close()
+
+		agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL(server.URL), WithRetries(3, time.Millisecond))
+		if err := agent.ClearMessages(); err != nil {
+			t.Errorf("ClearMessages() unexpected error: %v", err)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("calls = %d, want 3", calls.Load())
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL(server.URL), WithRetries(3, time.Millisecond))
+		if err := agent.ClearMessages(); err == nil {
+			t.Error("ClearMessages() expected error, got nil")
+		}
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+	})
+
+	t.Run("does not replay requests with side effects", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"status":"success"}`))
+		}))
+		defer server.Close()
+
+		agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL(server.URL), WithRetries(3, time.Millisecond))
+		if err := agent.AddSystemMessage("You are Bob"); err == nil {
+			t.Error("AddSystemMessage() expected error, got nil")
+		}
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+	})
+
+	t.Run("default backoff", func(t *testing.T) {
+		var c remoteClient
+		WithRetries(2, 0)(&c)
+		if c.retryBackoff != DefaultRetryBackoff {
+			t.Errorf("retryBackoff = %v, want %v", c.retryBackoff, DefaultRetryBackoff)
+		}
+	})
+
+	t.Run("retries requests not sent", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := listener.Addr().String()
+		listener.Close()
+
+		// The server starts after the first attempt
+		var calls atomic.Int32
+		started := make(chan *httptest.Server, 1)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Write([]byte(`{"status":"success"}`))
+			}))
+			server.Listener.Close()
+			server.Listener, _ = net.Listen("tcp", address)
+			server.Start()
+			started <- server
+		}()
+
+		agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL("http://"+address), WithRetries(5, 20*time.Millisecond))
+		if err := agent.AddSystemMessage("You are Bob"); err != nil {
+			t.Errorf("AddSystemMessage() unexpected error: %v", err)
+		}
+		(<-started).Close()
+		if calls.Load() != 1 {
+			t.Errorf("calls = %d, want 1", calls.Load())
+		}
+	})
+}
+
+func TestRemoteAgentWithHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"role":"user","content":[{"text":"Hello"}]}]`))
+	}))
+	defer server.Close()
+
+	agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	size, err := agent.GetCurrentContextSizeE()
+	if err != nil {
+		t.Fatalf("GetCurrentContextSizeE() unexpected error: %v", err)
+	}
+	if size != 5 {
+		t.Errorf("GetCurrentContextSizeE() = %d, want 5", size)
+	}
+}
+
+func TestRemoteAgentGetMessagesE(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL(server.URL))
+
+	if _, err := agent.GetMessagesE(); err == nil {
+		t.Error("GetMessagesE() expected error, got nil")
+	}
+	if _, err := agent.GetCurrentContextSizeE(); err == nil {
+		t.Error("GetCurrentContextSizeE() expected error, got nil")
+	}
+	if messages := agent.GetMessages(); messages != nil {
+		t.Errorf("GetMessages() = %v, want nil", messages)
+	}
+}
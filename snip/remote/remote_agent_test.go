@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip"
 	"github.com/snipwise/snip-sdk/snip/agents"
 	"github.com/snipwise/snip-sdk/snip/chatserver"
 	"github.com/snipwise/snip-sdk/snip/models"
@@ -86,6 +87,16 @@ func TestNewRemoteAgent(t *testing.T) {
 		if agent.GetMessagesEndpoint != expectedMessagesEndpoint {
 			t.Errorf("GetMessagesEndpoint = %q, want %q", agent.GetMessagesEndpoint, expectedMessagesEndpoint)
 		}
+
+		expectedAskEndpoint := "http://localhost:9000" + chatserver.DefaultAskFlowPath
+		if agent.AskEndpoint != expectedAskEndpoint {
+			t.Errorf("AskEndpoint = %q, want %q", agent.AskEndpoint, expectedAskEndpoint)
+		}
+
+		expectedAskStreamEndpoint := "http://localhost:9000" + chatserver.DefaultAskStreamFlowPath
+		if agent.AskStreamEndpoint != expectedAskStreamEndpoint {
+			t.Errorf("AskStreamEndpoint = %q, want %q", agent.AskStreamEndpoint, expectedAskStreamEndpoint)
+		}
 	})
 }
 
@@ -93,6 +104,55 @@ func TestNewRemoteAgent(t *testing.T) {
 // Tests for RemoteAgent.GetName
 // ============================================================================
 
+func TestNewRemoteAgentFromURL(t *testing.T) {
+	t.Run("reads the discovery document", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != chatserver.DefaultDiscoveryPath {
+				t.Errorf("Expected path %s, got %s", chatserver.DefaultDiscoveryPath, r.URL.Path)
+			}
+			json.NewEncoder(w).Encode(chatserver.DiscoveryDocument{
+				Name: "remote-bob",
+				Kind: agents.ChatServer,
+				Endpoints: map[string]chatserver.Endpoint{
+					chatserver.EndpointChat:          {Name: chatserver.EndpointChat, Method: "POST", Path: "/custom/chat"},
+					chatserver.EndpointClearMessages: {Name: chatserver.EndpointClearMessages, Method: "POST", Path: "/custom/clear"},
+				},
+			})
+		}))
+		defer server.Close()
+
+		agent, err := NewRemoteAgentFromURL(server.URL + "/")
+		if err != nil {
+			t.Fatalf("NewRemoteAgentFromURL() unexpected error: %v", err)
+		}
+
+		if agent.Name != "remote-bob" {
+			t.Errorf("Name = %q, want %q", agent.Name, "remote-bob")
+		}
+		if agent.ChatEndPoint != server.URL+"/custom/chat" {
+			t.Errorf("ChatEndPoint = %q, want %q", agent.ChatEndPoint, server.URL+"/custom/chat")
+		}
+		if agent.ClearMessagesEndpoint != server.URL+"/custom/clear" {
+			t.Errorf("ClearMessagesEndpoint = %q, want %q", agent.ClearMessagesEndpoint, server.URL+"/custom/clear")
+		}
+		if agent.TruncateMessagesEndpoint != "" {
+			t.Errorf("TruncateMessagesEndpoint = %q, want empty", agent.TruncateMessagesEndpoint)
+		}
+		if err := agent.TruncateMessages(1); err == nil {
+			t.Error("TruncateMessages() expected error for an endpoint not registered, got nil")
+		}
+	})
+
+	t.Run("server without discovery document", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		if _, err := NewRemoteAgentFromURL(server.URL); err == nil {
+			t.Error("NewRemoteAgentFromURL() expected error, got nil")
+		}
+	})
+}
+
 func TestRemoteAgentGetName(t *testing.T) {
 	agent := &RemoteAgent{
 		Name: "remote-test-agent",
@@ -325,9 +385,30 @@ func TestRemoteAgentGetCurrentContextSize(t *testing.T) {
 // ============================================================================
 
 func TestRemoteAgentReplaceMessages(t *testing.T) {
-	t.Run("returns error - not supported", func(t *testing.T) {
+	t.Run("successful request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				t.Errorf("Request method = %q, want POST", r.Method)
+			}
+
+			var reqBody struct {
+				Messages []*ai.Message `json:"messages"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+				t.Errorf("Failed to decode request body: %v", err)
+			}
+
+			if len(reqBody.Messages) != 2 {
+				t.Errorf("Messages length = %d, want 2", len(reqBody.Messages))
+			}
+
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"status":"success"}`))
+		}))
+		defer server.Close()
+
 		agent := &RemoteAgent{
-			Name: "test-agent",
+			ReplaceMessagesEndpoint: server.URL,
 		}
 
 		newMessages := []*ai.Message{
@@ -336,13 +417,8 @@ func TestRemoteAgentReplaceMessages(t *testing.T) {
 		}
 
 		err := agent.ReplaceMessagesWith(newMessages)
-		if err == nil {
-			t.Error("ReplaceMessagesWith() expected error, got nil")
-		}
-
-		expectedMsg := "ReplaceMessagesWith is not supported for remote agents"
-		if !strings.Contains(err.Error(), expectedMsg) {
-			t.Errorf("ReplaceMessagesWith() error = %q, want error containing %q", err.Error(), expectedMsg)
+		if err != nil {
+			t.Errorf("ReplaceMessagesWith() unexpected error: %v", err)
 		}
 	})
 
@@ -355,28 +431,288 @@ func TestRemoteAgentReplaceMessages(t *testing.T) {
 		if err == nil {
 			t.Error("ReplaceMessagesWith(nil) expected error, got nil")
 		}
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","message":"invalid request body"}`))
+		}))
+		defer server.Close()
+
+		agent := &RemoteAgent{
+			ReplaceMessagesEndpoint: server.URL,
+		}
+
+		err := agent.ReplaceMessagesWith([]*ai.Message{})
+		if err == nil {
+			t.Error("ReplaceMessagesWith() expected error for HTTP 400, got nil")
+		}
+	})
+}
+
+// ============================================================================
+// Tests for RemoteAgent.ReplaceMessagesWithSystemMessages
+// ============================================================================
+
+func TestRemoteAgentReplaceMessagesWithSystemMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			SystemMessages []string `json:"system_messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		if len(reqBody.SystemMessages) != 1 || reqBody.SystemMessages[0] != "You are a helpful assistant" {
+			t.Errorf("SystemMessages = %v, want [You are a helpful assistant]", reqBody.SystemMessages)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	agent := &RemoteAgent{
+		ReplaceWithSystemMessagesEndpoint: server.URL,
+	}
+
+	err := agent.ReplaceMessagesWithSystemMessages([]string{"You are a helpful assistant"})
+	if err != nil {
+		t.Errorf("ReplaceMessagesWithSystemMessages() unexpected error: %v", err)
+	}
+
+	err = agent.ReplaceMessagesWithSystemMessages(nil)
+	if err == nil {
+		t.Error("ReplaceMessagesWithSystemMessages(nil) expected error, got nil")
+	}
+}
+
+// ============================================================================
+// Tests for RemoteAgent.ClearMessages and RemoteAgent.TruncateMessages
+// ============================================================================
+
+func TestRemoteAgentClearMessages(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	agent := &RemoteAgent{
+		ClearMessagesEndpoint: server.URL,
+	}
+
+	if err := agent.ClearMessages(); err != nil {
+		t.Errorf("ClearMessages() unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("ClearMessages() did not call the server")
+	}
+}
+
+func TestRemoteAgentTruncateMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			KeepLast int `json:"keep_last"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		if reqBody.KeepLast != 4 {
+			t.Errorf("KeepLast = %d, want 4", reqBody.KeepLast)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	agent := &RemoteAgent{
+		TruncateMessagesEndpoint: server.URL,
+	}
+
+	if err := agent.TruncateMessages(4); err != nil {
+		t.Errorf("TruncateMessages() unexpected error: %v", err)
+	}
+
+	if err := agent.TruncateMessages(-1); err == nil {
+		t.Error("TruncateMessages(-1) expected error, got nil")
+	}
+}
+
+// ============================================================================
+// Tests for RemoteAgent system instructions
+// ============================================================================
+
+func TestRemoteAgentSystemInstructions(t *testing.T) {
+	systemInstructions := "You are a helpful assistant"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SystemInstructions string `json:"system_instructions"`
+		}
+		switch r.Method {
+		case "GET":
+			body.SystemInstructions = systemInstructions
+			json.NewEncoder(w).Encode(body)
+		case "POST":
+			json.NewDecoder(r.Body).Decode(&body)
+			systemInstructions = body.SystemInstructions
+			w.Write([]byte(`{"status":"success"}`))
+		}
+	}))
+	defer server.Close()
+
+	agent := &RemoteAgent{
+		SystemInstructionsEndpoint: server.URL,
+	}
+
+	if err := agent.SetSystemInstructions("  You are a Go expert  "); err != nil {
+		t.Errorf("SetSystemInstructions() unexpected error: %v", err)
+	}
 
-		expectedMsg := "ReplaceMessagesWith is not supported for remote agents"
-		if !strings.Contains(err.Error(), expectedMsg) {
-			t.Errorf("ReplaceMessagesWith(nil) error = %q, want error containing %q", err.Error(), expectedMsg)
+	got, err := agent.GetSystemInstructionsE()
+	if err != nil {
+		t.Errorf("GetSystemInstructionsE() unexpected error: %v", err)
+	}
+	if got != "You are a Go expert" {
+		t.Errorf("GetSystemInstructionsE() = %q, want %q", got, "You are a Go expert")
+	}
+	if got := agent.GetSystemInstructions(); got != "You are a Go expert" {
+		t.Errorf("GetSystemInstructions() = %q, want %q", got, "You are a Go expert")
+	}
+
+	server.Close()
+	if got := agent.GetSystemInstructions(); got != "" {
+		t.Errorf("GetSystemInstructions() = %q, want an empty string when the server is down", got)
+	}
+	if _, err := agent.GetSystemInstructionsE(); err == nil {
+		t.Error("GetSystemInstructionsE() should fail when the server is down")
+	}
+}
+
+// ============================================================================
+// Tests for RemoteAgent.CompressContext and RemoteAgent.CompressContextStream
+// ============================================================================
+
+func TestRemoteAgentCompressContext(t *testing.T) {
+	t.Run("successful request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(agents.ChatResponse{Text: "Compressed", FinishReason: "stop"})
+		}))
+		defer server.Close()
+
+		agent := &RemoteAgent{
+			CompressContextEndpoint: server.URL,
+		}
+
+		response, err := agent.CompressContext()
+		if err != nil {
+			t.Errorf("CompressContext() unexpected error: %v", err)
+		}
+		if response.Text != "Compressed" {
+			t.Errorf("CompressContext() = %q, want %q", response.Text, "Compressed")
+		}
+		if !response.IsFinishReasonStop() {
+			t.Errorf("FinishReason = %q, want stop", response.FinishReason)
 		}
 	})
 
-	t.Run("returns error with empty slice", func(t *testing.T) {
+	t.Run("no compressor on the server", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
 		agent := &RemoteAgent{
-			Name: "test-agent",
+			CompressContextEndpoint: server.URL,
 		}
 
-		err := agent.ReplaceMessagesWith([]*ai.Message{})
+		_, err := agent.CompressContext()
 		if err == nil {
-			t.Error("ReplaceMessagesWith([]) expected error, got nil")
+			t.Error("CompressContext() expected error for HTTP 404, got nil")
 		}
+	})
+}
 
-		expectedMsg := "ReplaceMessagesWith is not supported for remote agents"
-		if !strings.Contains(err.Error(), expectedMsg) {
-			t.Errorf("ReplaceMessagesWith([]) error = %q, want error containing %q", err.Error(), expectedMsg)
+func TestRemoteAgentCompressContextStream(t *testing.T) {
+	t.Run("successful stream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"message\":{\"response\":\"Com\"}}\n\n"))
+			w.Write([]byte("data: {\"message\":{\"response\":\"pressed\"}}\n\n"))
+			w.Write([]byte("data: {\"result\":{\"response\":\"Compressed\",\"finish_reason\":\"stop\"}}\n\n"))
+		}))
+		defer server.Close()
+
+		agent := &RemoteAgent{
+			CompressContextStreamEndpoint: server.URL,
+		}
+
+		chunks := []string{}
+		response, err := agent.CompressContextStream(func(chunk agents.ChatResponse) error {
+			chunks = append(chunks, chunk.Text)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("CompressContextStream() unexpected error: %v", err)
+		}
+		if len(chunks) != 2 {
+			t.Errorf("Callback called %d times, want 2", len(chunks))
+		}
+		if response.Text != "Compressed" {
+			t.Errorf("CompressContextStream() = %q, want %q", response.Text, "Compressed")
 		}
 	})
+
+	t.Run("error event", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"error\":{\"status\":\"INTERNAL\",\"message\":\"failed to compress context\",\"details\":\"boom\"}}\n\n"))
+		}))
+		defer server.Close()
+
+		agent := &RemoteAgent{
+			CompressContextStreamEndpoint: server.URL,
+		}
+
+		_, err := agent.CompressContextStream(func(chunk agents.ChatResponse) error {
+			return nil
+		})
+		if err == nil {
+			t.Fatal("CompressContextStream() expected error, got nil")
+		}
+		if !strings.Contains(err.Error(), "boom") {
+			t.Errorf("CompressContextStream() error = %q, want error containing 'boom'", err.Error())
+		}
+	})
+}
+
+// ============================================================================
+// Tests for RemoteAgent.GetStreamCancel
+// ============================================================================
+
+func TestRemoteAgentGetStreamCancel(t *testing.T) {
+	cancelled := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatserver.CancelStreamRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		cancelled = req.StreamID
+		w.Write([]byte(`{"status":"stream cancelled"}`))
+	}))
+	defer server.Close()
+
+	remoteAgent := &RemoteAgent{
+		CancelStreamEndpoint: server.URL,
+	}
+	remoteAgent.setCurrentStream(remoteStream{id: "stream-1"})
+
+	var agent snip.AIChatAgent = remoteAgent
+	agent.GetStreamCancel()()
+	if cancelled != "stream-1" {
+		t.Errorf("GetStreamCancel() cancelled %q, want the current stream of the agent", cancelled)
+	}
 }
 
 // ============================================================================
@@ -604,6 +940,70 @@ func TestRemoteAgentAsk(t *testing.T) {
 	})
 }
 
+// ============================================================================
+// Tests for RemoteAgent.Ask and RemoteAgent.AskStream (without memory)
+// ============================================================================
+
+func TestRemoteAgentAskWithoutMemory(t *testing.T) {
+	t.Run("uses the stateless endpoint", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /api/chat", func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Ask() should not call the chat endpoint with memory")
+		})
+		mux.HandleFunc("POST /api/ask", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{
+				"result": map[string]any{"response": "Stateless answer"},
+			})
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		agent := &RemoteAgent{
+			ChatEndPoint: server.URL + "/api/chat",
+			AskEndpoint:  server.URL + "/api/ask",
+		}
+
+		answer, err := agent.Ask("Test question")
+		if err != nil {
+			t.Errorf("Ask() unexpected error: %v", err)
+		}
+
+		if answer.Text != "Stateless answer" {
+			t.Errorf("Ask() = %q, want %q", answer.Text, "Stateless answer")
+		}
+	})
+
+	t.Run("stream uses the stateless endpoint", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /api/chat-stream", func(w http.ResponseWriter, r *http.Request) {
+			t.Error("AskStream() should not call the chat stream endpoint with memory")
+		})
+		mux.HandleFunc("POST /api/ask-stream", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"message\":{\"response\":\"Hello\"}}\n\n"))
+			w.Write([]byte("data: {\"result\":{\"response\":\"Hello\"}}\n\n"))
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		agent := &RemoteAgent{
+			ChatStreamEndpoint: server.URL + "/api/chat-stream",
+			AskStreamEndpoint:  server.URL + "/api/ask-stream",
+		}
+
+		answer, err := agent.AskStream("Test question", func(chunk agents.ChatResponse) error {
+			return nil
+		})
+		if err != nil {
+			t.Errorf("AskStream() unexpected error: %v", err)
+		}
+
+		if answer.Text != "Hello" {
+			t.Errorf("AskStream() = %q, want %q", answer.Text, "Hello")
+		}
+	})
+}
+
 // ============================================================================
 // Tests for RemoteAgent.GetMessages
 // ============================================================================
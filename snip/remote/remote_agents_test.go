@@ -0,0 +1,168 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip"
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+	"github.com/snipwise/snip-sdk/snip/rag"
+	"github.com/snipwise/snip-sdk/snip/text"
+	"github.com/snipwise/snip-sdk/snip/tools"
+)
+
+type fakeToolsAgent struct{}
+
+func (fakeToolsAgent) GetName() string { return "tools" }
+func (fakeToolsAgent) GetInfo() (agents.ToolsAgentInfo, error) {
+	return agents.ToolsAgentInfo{Name: "tools", ModelID: "tool-model"}, nil
+}
+func (fakeToolsAgent) Kind() agents.AgentKind { return agents.Tool }
+func (fakeToolsAgent) RunToolCalls(prompt string) (tools.ToolCallsResult, error) {
+	if prompt == "fail" {
+		return tools.ToolCallsResult{}, errors.New("tool failed")
+	}
+	return tools.ToolCallsResult{Text: "ran: " + prompt, List: []map[string]any{{"add": float64(3)}}}, nil
+}
+
+type fakeRagAgent struct {
+	chunks  []string
+	options rag.SearchOptions
+}
+
+func (a *fakeRagAgent) GetName() string { return "rag" }
+func (a *fakeRagAgent) GetInfo() (agents.RagAgentInfo, error) {
+	return agents.RagAgentInfo{Name: "rag", NumberOfDocuments: len(a.chunks)}, nil
+}
+func (a *fakeRagAgent) Kind() agents.AgentKind { return agents.Rag }
+func (a *fakeRagAgent) AddTextChunksToStore(chunks []text.TextChunk) (int, error) {
+	for _, chunk := range chunks {
+		a.chunks = append(a.chunks, chunk.Content)
+	}
+	return len(chunks), nil
+}
+func (a *fakeRagAgent) SearchSimilarities(query string) ([]string, error) {
+	return a.chunks, nil
+}
+func (a *fakeRagAgent) Search(query string, opts ...rag.SearchOption) ([]rag.SearchResult, error) {
+	a.options = rag.NewSearchOptions(opts...)
+	var results []rag.SearchResult
+	for i, chunk := range a.chunks {
+		results = append(results, rag.SearchResult{
+			ID:       chunk,
+			Content:  chunk,
+			Metadata: map[string]any{"rank": float64(i)},
+			Score:    1 / float64(i+1),
+		})
+	}
+	return results, nil
+}
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type fakeStructuredAgent struct{}
+
+func (fakeStructuredAgent) GenerateStructuredData(text string) (*person, error) {
+	return &person{Name: text, Age: 42}, nil
+}
+func (fakeStructuredAgent) Kind() agents.AgentKind { return agents.Structured }
+
+func TestRemoteToolsAgent(t *testing.T) {
+	server := httptest.NewServer(chatserver.NewToolsAgentHandler(fakeToolsAgent{}, chatserver.ConfigAgentHTTP{}))
+	defer server.Close()
+
+	var agent snip.AIToolsAgent = NewRemoteToolsAgent("tools", server.URL)
+
+	info, err := agent.GetInfo()
+	if err != nil || info.ModelID != "tool-model" {
+		t.Errorf("GetInfo() = %+v, %v, want the server information", info, err)
+	}
+
+	result, err := agent.RunToolCalls("add 1 and 2")
+	if err != nil {
+		t.Fatalf("RunToolCalls() unexpected error: %v", err)
+	}
+	want := tools.ToolCallsResult{Text: "ran: add 1 and 2", List: []map[string]any{{"add": float64(3)}}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("RunToolCalls() = %+v, want %+v", result, want)
+	}
+
+	_, err = agent.RunToolCalls("fail")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("RunToolCalls() error = %v, want an *HTTPError with status 500", err)
+	}
+}
+
+func TestRemoteRagAgent(t *testing.T) {
+	local := &fakeRagAgent{}
+	server := httptest.NewServer(chatserver.NewRagAgentHandler(local, chatserver.ConfigAgentHTTP{}))
+	defer server.Close()
+
+	var agent snip.AIRagAgent = NewRemoteRagAgent("rag", server.URL)
+
+	count, err := agent.AddTextChunksToStore([]text.TextChunk{{Content: "one"}, {Content: "two"}})
+	if err != nil || count != 2 {
+		t.Fatalf("AddTextChunksToStore() = %d, %v, want 2", count, err)
+	}
+
+	results, err := agent.SearchSimilarities("anything")
+	if err != nil || !reflect.DeepEqual(results, []string{"one", "two"}) {
+		t.Errorf("SearchSimilarities() = %v, %v, want [one two]", results, err)
+	}
+
+	matches, err := agent.Search("anything", rag.WithTopK(5), rag.WithMinScore(0.2), rag.WithMetadataFilter(map[string]any{"lang": "en"}))
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	want := []rag.SearchResult{
+		{ID: "one", Content: "one", Metadata: map[string]any{"rank": float64(0)}, Score: 1},
+		{ID: "two", Content: "two", Metadata: map[string]any{"rank": float64(1)}, Score: 0.5},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("Search() = %+v, want %+v", matches, want)
+	}
+	wantOptions := rag.NewSearchOptions(rag.WithTopK(5), rag.WithMinScore(0.2), rag.WithMetadataFilter(map[string]any{"lang": "en"}))
+	if !reflect.DeepEqual(local.options, wantOptions) {
+		t.Errorf("options on the server = %+v, want %+v", local.options, wantOptions)
+	}
+
+	info, err := agent.GetInfo()
+	if err != nil || info.NumberOfDocuments != 2 {
+		t.Errorf("GetInfo() = %+v, %v, want 2 documents", info, err)
+	}
+
+	// The search is cancelled with its context, e.g. when a MultiRetriever source times out
+	searcher, ok := agent.(rag.ContextSearcher)
+	if !ok {
+		t.Fatal("RemoteRagAgent is not a rag.ContextSearcher")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := searcher.SearchContext(ctx, "anything"); !errors.Is(err, context.Canceled) {
+		t.Errorf("SearchContext() with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestRemoteStructuredAgent(t *testing.T) {
+	server := httptest.NewServer(chatserver.NewStructuredAgentHandler[person]("people", fakeStructuredAgent{}, chatserver.ConfigAgentHTTP{}))
+	defer server.Close()
+
+	var agent snip.StructuredAgentInterface[person] = NewRemoteStructuredAgent[person]("people", server.URL)
+
+	result, err := agent.GenerateStructuredData("Bob")
+	if err != nil {
+		t.Fatalf("GenerateStructuredData() unexpected error: %v", err)
+	}
+	if *result != (person{Name: "Bob", Age: 42}) {
+		t.Errorf("GenerateStructuredData() = %+v, want Bob, 42", *result)
+	}
+}
@@ -0,0 +1,158 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+	"github.com/snipwise/snip-sdk/snip/registry"
+	"github.com/snipwise/snip-sdk/snip/toolbox/sse"
+)
+
+// replica is a test server answering the ask flow with its name
+type replica struct {
+	name    string
+	server  *httptest.Server
+	calls   atomic.Int32
+	healthy atomic.Bool
+}
+
+func newReplica(t *testing.T, reg registry.Registry, name string) *replica {
+	t.Helper()
+	r := &replica{name: name}
+	r.healthy.Store(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+chatserver.DefaultHealthcheckPath, func(w http.ResponseWriter, req *http.Request) {
+		if !r.healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"healthy"}`))
+	})
+	mux.HandleFunc("POST "+chatserver.DefaultAskFlowPath, func(w http.ResponseWriter, req *http.Request) {
+		if !r.healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		r.calls.Add(1)
+		w.Write([]byte(`{"result":{"response":"` + r.name + `"}}`))
+	})
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+
+	reg.Register(registry.Instance{ID: name, Name: "bob", Address: r.server.URL})
+	return r
+}
+
+func askReplicas(t *testing.T, agent *RemoteAgent, count int) []string {
+	t.Helper()
+	var answers []string
+	for i := 0; i < count; i++ {
+		answer, err := agent.Ask("Hi")
+		if err != nil {
+			t.Fatalf("Ask() unexpected error: %v", err)
+		}
+		answers = append(answers, answer.Text)
+	}
+	return answers
+}
+
+func TestRemoteAgentFromRegistry(t *testing.T) {
+	reg := registry.NewMemoryRegistry(0)
+	first := newReplica(t, reg, "r1")
+	newReplica(t, reg, "r2")
+
+	t.Run("round robin", func(t *testing.T) {
+		agent := NewRemoteAgentFromRegistry("bob", reg, chatserver.ConfigHTTP{}, WithSessionStickiness(false))
+		answers := askReplicas(t, agent, 4)
+		want := []string{"r1", "r2", "r1", "r2"}
+		for i := range want {
+			if answers[i] != want[i] {
+				t.Fatalf("answers = %v, want %v", answers, want)
+			}
+		}
+	})
+
+	t.Run("session stickiness", func(t *testing.T) {
+		agent := NewRemoteAgentFromRegistry("bob", reg, chatserver.ConfigHTTP{})
+		answers := askReplicas(t, agent, 3)
+		if answers[0] != answers[1] || answers[1] != answers[2] {
+			t.Errorf("answers = %v, want the same replica for the session", answers)
+		}
+	})
+
+	t.Run("least active", func(t *testing.T) {
+		agent := NewRemoteAgentFromRegistry("bob", reg, chatserver.ConfigHTTP{}, WithBalancing(LeastActive), WithSessionStickiness(false))
+		release := agent.pool.acquire(first.server.URL)
+		defer release()
+
+		answers := askReplicas(t, agent, 2)
+		if answers[0] != "r2" || answers[1] != "r2" {
+			t.Errorf("answers = %v, want the replica without call in progress", answers)
+		}
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		agent := NewRemoteAgentFromRegistry("bob", reg, chatserver.ConfigHTTP{})
+		if answers := askReplicas(t, agent, 1); answers[0] != "r1" {
+			t.Fatalf("answers = %v, want the session on r1", answers)
+		}
+
+		first.healthy.Store(false)
+		defer first.healthy.Store(true)
+
+		answers := askReplicas(t, agent, 2)
+		if answers[0] != "r2" || answers[1] != "r2" {
+			t.Errorf("answers = %v, want the healthy replica", answers)
+		}
+	})
+
+	t.Run("no replica", func(t *testing.T) {
+		agent := NewRemoteAgentFromRegistry("alice", reg, chatserver.ConfigHTTP{})
+		if _, err := agent.Ask("Hi"); err == nil {
+			t.Error("Ask() should fail without replica")
+		}
+	})
+}
+
+func TestRemoteAgentFromRegistryStreamResume(t *testing.T) {
+	reg := registry.NewMemoryRegistry(0)
+	var starts, resumes atomic.Int32
+	for _, name := range []string{"r1", "r2"} {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST "+chatserver.DefaultAskStreamFlowPath, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			if r.Header.Get("Last-Event-ID") == "" {
+				// The connection drops after the first chunk
+				starts.Add(1)
+				sse.Write(w, sse.Event{ID: name + ":0", Data: `{"message":{"response":"Hello "}}`})
+				return
+			}
+			if got := r.Header.Get("Last-Event-ID"); got != name+":0" {
+				t.Errorf("%s resumed the stream %q, want the stream on its replica", name, got)
+			}
+			resumes.Add(1)
+			sse.Write(w, sse.Event{ID: name + ":1", Data: `{"result":{"response":"Hello ` + name + `","finish_reason":"stop"}}`})
+		})
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		reg.Register(registry.Instance{ID: name, Name: "bob", Address: server.URL})
+	}
+
+	agent := NewRemoteAgentFromRegistry("bob", reg, chatserver.ConfigHTTP{}, WithSessionStickiness(false))
+	answer, err := agent.AskStream("Hi", func(chunk agents.ChatResponse) error { return nil })
+	if err != nil {
+		t.Fatalf("AskStream() unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(answer.Text, "Hello r") {
+		t.Errorf("answer = %q, want the answer of a replica", answer.Text)
+	}
+	if starts.Load() != 1 || resumes.Load() != 1 {
+		t.Errorf("starts = %d, resumes = %d, want 1 and 1", starts.Load(), resumes.Load())
+	}
+}
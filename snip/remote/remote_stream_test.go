@@ -0,0 +1,303 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/snipwise/snip-sdk/snip/agents"
+	"github.com/snipwise/snip-sdk/snip/chatserver"
+	"github.com/snipwise/snip-sdk/snip/toolbox/sse"
+)
+
+// streamServer serves the events as a server-sent events stream
+func streamServer(t *testing.T, events ...chatserver.StreamEvent) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				t.Fatalf("json.Marshal() unexpected error: %v", err)
+			}
+			sse.Write(w, sse.Event{Data: string(data)})
+		}
+	}))
+}
+
+func TestRemoteAgentAskStreamRoundTrip(t *testing.T) {
+	chunks := []agents.ChatResponse{
+		{Text: "Hel", Role: ai.RoleModel, Content: []*ai.Part{ai.NewTextPart("Hel")}},
+		{Text: "lo\nworld", ReasoningContent: "thinking", Content: []*ai.Part{ai.NewReasoningPart("thinking", nil)}},
+		{FinishReason: "stop", FinishMessage: "done"},
+	}
+	final := agents.ChatResponse{
+		Text:             "Hello\nworld",
+		FinishReason:     "stop",
+		FinishMessage:    "done",
+		ReasoningContent: "thinking",
+	}
+
+	var events []chatserver.StreamEvent
+	for i := range chunks {
+		events = append(events, chatserver.StreamEvent{Message: &chunks[i]})
+	}
+	events = append(events, chatserver.StreamEvent{Result: &final})
+
+	server := streamServer(t, events...)
+	defer server.Close()
+
+	agent := &RemoteAgent{ChatStreamEndpoint: server.URL}
+
+	var received []agents.ChatResponse
+	answer, err := agent.AskStreamWithMemory("Hi", func(chunk agents.ChatResponse) error {
+		received = append(received, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("AskStreamWithMemory() unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(answer, final) {
+		t.Errorf("AskStreamWithMemory() = %+v, want %+v", answer, final)
+	}
+	if len(received) != len(chunks) {
+		t.Fatalf("received %d chunks, want %d", len(received), len(chunks))
+	}
+	for i := range chunks {
+		got, _ := json.Marshal(received[i])
+		want, _ := json.Marshal(chunks[i])
+		if string(got) != string(want) {
+			t.Errorf("chunk %d = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestRemoteAgentAskStreamErrors(t *testing.T) {
+	t.Run("error event", func(t *testing.T) {
+		server := streamServer(t,
+			chatserver.StreamEvent{Message: &agents.ChatResponse{Text: "Partial"}},
+			chatserver.StreamEvent{Error: &chatserver.StreamError{Status: "INTERNAL", Message: "stream flow error", Details: "model unavailable"}},
+		)
+		defer server.Close()
+
+		agent := &RemoteAgent{AskStreamEndpoint: server.URL}
+		answer, err := agent.AskStream("Hi", func(chunk agents.ChatResponse) error { return nil })
+
+		var streamErr *StreamError
+		if !errors.As(err, &streamErr) {
+			t.Fatalf("AskStream() error = %v, want a *StreamError", err)
+		}
+		if streamErr.Details != "model unavailable" {
+			t.Errorf("Details = %q, want %q", streamErr.Details, "model unavailable")
+		}
+		if answer.Text != "Partial" {
+			t.Errorf("AskStream() = %q, want the partial answer", answer.Text)
+		}
+	})
+
+	t.Run("unescaped Genkit error event", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`data: {"error": {"status": "INTERNAL", "message": "stream flow error", "details": "bad "quote""}}` + "\n\n"))
+		}))
+		defer server.Close()
+
+		agent := &RemoteAgent{AskStreamEndpoint: server.URL}
+		_, err := agent.AskStream("Hi", func(chunk agents.ChatResponse) error { return nil })
+
+		var streamErr *StreamError
+		if !errors.As(err, &streamErr) || !strings.Contains(streamErr.Details, "bad") {
+			t.Errorf("AskStream() error = %v, want a *StreamError with the raw details", err)
+		}
+	})
+
+	t.Run("HTTP error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "flow not found", http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		agent := &RemoteAgent{AskStreamEndpoint: server.URL}
+		_, err := agent.AskStream("Hi", func(chunk agents.ChatResponse) error { return nil })
+
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+			t.Errorf("AskStream() error = %v, want an *HTTPError with status 404", err)
+		}
+	})
+
+	t.Run("callback error stops the stream", func(t *testing.T) {
+		server := streamServer(t,
+			chatserver.StreamEvent{Message: &agents.ChatResponse{Text: "one"}},
+			chatserver.StreamEvent{Message: &agents.ChatResponse{Text: "two"}},
+		)
+		defer server.Close()
+
+		stop := errors.New("stop")
+		calls := 0
+		agent := &RemoteAgent{AskStreamEndpoint: server.URL}
+		_, err := agent.AskStream("Hi", func(chunk agents.ChatResponse) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("AskStream() error = %v after %d calls, want the callback error after 1 call", err, calls)
+		}
+	})
+}
+
+func TestRemoteAgentAskStreamResume(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch calls.Add(1) {
+		case 1:
+			// The connection drops after 2 chunks
+			sse.Write(w, sse.Event{ID: "s1:0", Data: `{"message":{"response":"Hello "}}`})
+			sse.Write(w, sse.Event{ID: "s1:1", Data: `{"message":{"response":"from "}}`})
+		case 2:
+			if got := r.Header.Get("Last-Event-ID"); got != "s1:1" {
+				t.Errorf("Last-Event-ID = %q, want %q", got, "s1:1")
+			}
+			sse.Write(w, sse.Event{ID: "s1:2", Data: `{"message":{"response":"Bob"}}`})
+			sse.Write(w, sse.Event{ID: "s1:3", Data: `{"result":{"response":"Hello from Bob","finish_reason":"stop"}}`})
+		}
+	}))
+	defer server.Close()
+
+	agent := &RemoteAgent{ChatStreamEndpoint: server.URL}
+
+	streamed := ""
+	answer, err := agent.AskStreamWithMemory("Hi", func(chunk agents.ChatResponse) error {
+		streamed += chunk.Text
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("AskStreamWithMemory() unexpected error: %v", err)
+	}
+	if answer.Text != "Hello from Bob" || streamed != "Hello from Bob" {
+		t.Errorf("answer = %q, streamed = %q, want %q", answer.Text, streamed, "Hello from Bob")
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		calls.Store(0)
+		agent := NewRemoteAgent("bob", chatserver.ConfigHTTP{}, WithBaseURL(server.URL), WithStreamResumes(0))
+		agent.ChatStreamEndpoint = server.URL
+
+		answer, err := agent.AskStreamWithMemory("Hi", func(chunk agents.ChatResponse) error { return nil })
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("AskStreamWithMemory() error = %v, want io.ErrUnexpectedEOF for a stream cut before its result", err)
+		}
+		if answer.Text != "Hello from " || calls.Load() != 1 {
+			t.Errorf("answer = %q after %d calls, want the partial answer after 1 call", answer.Text, calls.Load())
+		}
+	})
+}
+
+func TestRemoteAgentAskStreamCancel(t *testing.T) {
+	var cancelled atomic.Bool
+	var sendStreamID atomic.Bool
+	sendStreamID.Store(true)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /cancel", func(w http.ResponseWriter, r *http.Request) {
+		var req chatserver.CancelStreamRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.StreamID != "stream-1" {
+			t.Errorf("cancelled stream = %q, want the stream of the client", req.StreamID)
+		}
+		cancelled.Store(true)
+		w.Write([]byte(`{"status":"success"}`))
+	})
+	mux.HandleFunc("POST /stream", func(w http.ResponseWriter, r *http.Request) {
+		if sendStreamID.Load() {
+			w.Header().Set(chatserver.StreamIDHeader, "stream-1")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if err := sse.Write(w, sse.Event{Data: `{"message":{"response":"word "}}`}); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	agent := &RemoteAgent{
+		AskStreamEndpoint:    server.URL + "/stream",
+		CancelStreamEndpoint: server.URL + "/cancel",
+	}
+
+	t.Run("callback error", func(t *testing.T) {
+		cancelled.Store(false)
+		stop := errors.New("stop")
+		_, err := agent.AskStream("Hi", func(chunk agents.ChatResponse) error { return stop })
+		if !errors.Is(err, stop) {
+			t.Errorf("AskStream() error = %v, want the callback error", err)
+		}
+		if !cancelled.Load() {
+			t.Error("AskStream() did not cancel the remote stream")
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		cancelled.Store(false)
+		ctx, cancel := context.WithCancel(context.Background())
+		answer, err := agent.AskStreamContext(ctx, "Hi", func(chunk agents.ChatResponse) error {
+			cancel()
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("AskStreamContext() error = %v, want context.Canceled", err)
+		}
+		if answer.Text == "" {
+			t.Error("AskStreamContext() should return the partial answer")
+		}
+		if !cancelled.Load() {
+			t.Error("AskStreamContext() did not cancel the remote stream")
+		}
+	})
+
+	t.Run("stream cancel", func(t *testing.T) {
+		cancelled.Store(false)
+		_, err := agent.AskStream("Hi", func(chunk agents.ChatResponse) error {
+			agent.GetStreamCancel()()
+			return errors.New("stop")
+		})
+		if err == nil || !cancelled.Load() {
+			t.Errorf("GetStreamCancel() = %v, cancelled %v, want the current stream cancelled", err, cancelled.Load())
+		}
+		cancelled.Store(false)
+		agent.GetStreamCancel()()
+		if cancelled.Load() {
+			t.Error("GetStreamCancel() without stream should not cancel")
+		}
+	})
+
+	t.Run("without stream id", func(t *testing.T) {
+		cancelled.Store(false)
+		sendStreamID.Store(false)
+		defer sendStreamID.Store(true)
+		agent.AskStream("Hi", func(chunk agents.ChatResponse) error { return errors.New("stop") })
+		if cancelled.Load() {
+			t.Error("AskStream() without stream id should not cancel the current completion of the server")
+		}
+	})
+}
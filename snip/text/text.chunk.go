@@ -1,6 +1,8 @@
 package text
 
 type TextChunk struct {
+	// ID identifies the chunk in the RAG stores (the hash of its content and metadata if empty)
+	ID       string
 	Content  string
 	Metadata map[string]any
-}
\ No newline at end of file
+}
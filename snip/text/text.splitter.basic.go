@@ -0,0 +1,76 @@
+package text
+
+import "regexp"
+
+var (
+	// wordPattern matches a word with its trailing spaces
+	wordPattern = regexp.MustCompile(`\S+\s*`)
+	// sentenceEndPattern matches the end of a sentence: its punctuation and closing quotes or brackets
+	// followed by spaces, or a blank line
+	sentenceEndPattern = regexp.MustCompile(`[.!?…]+["'”’)\]]*\s+|\n\s*\n\s*`)
+)
+
+// DefaultSeparators are the separators of the RecursiveSplitter: paragraphs, lines, sentences, words, characters
+var DefaultSeparators = []string{"\n\n", "\n", ". ", " ", ""}
+
+// FixedSizeSplitter splits a text in chunks of a fixed size, cutting between words
+// (a word longer than the size is cut between characters)
+type FixedSizeSplitter struct {
+	config splitterConfig
+}
+
+// NewFixedSizeSplitter creates a fixed size splitter
+// e.g. NewFixedSizeSplitter(WithChunkSize(500), WithChunkOverlap(50))
+func NewFixedSizeSplitter(opts ...SplitterOption) *FixedSizeSplitter {
+	return &FixedSizeSplitter{config: newSplitterConfig(nil, opts)}
+}
+
+func (s *FixedSizeSplitter) Split(text string, metadata map[string]any) []TextChunk {
+	pieces := []piece{}
+	for _, word := range wordPattern.FindAllStringIndex(text, -1) {
+		pieces = append(pieces, s.config.splitRecursive(text, word[0], word[1], nil)...)
+	}
+	return newChunks(text, s.config.merge(pieces, nil), metadata)
+}
+
+// RecursiveSplitter splits a text with the first separator found, then splits the parts still too long
+// with the next separators, and merges the consecutive parts up to the size of a chunk
+// It keeps the paragraphs, then the lines, then the sentences together as much as possible
+type RecursiveSplitter struct {
+	config splitterConfig
+}
+
+// NewRecursiveSplitter creates a recursive splitter with the DefaultSeparators
+// (see WithSeparators to change them)
+func NewRecursiveSplitter(opts ...SplitterOption) *RecursiveSplitter {
+	return &RecursiveSplitter{config: newSplitterConfig(DefaultSeparators, opts)}
+}
+
+func (s *RecursiveSplitter) Split(text string, metadata map[string]any) []TextChunk {
+	pieces := s.config.splitRecursive(text, 0, len(text), s.config.separators)
+	return newChunks(text, s.config.merge(pieces, nil), metadata)
+}
+
+// SentenceSplitter splits a text in sentences, and merges the consecutive sentences up to the size of a chunk
+// (a sentence longer than the size is cut between words); the overlap is made of whole sentences
+type SentenceSplitter struct {
+	config splitterConfig
+}
+
+// NewSentenceSplitter creates a sentence splitter
+func NewSentenceSplitter(opts ...SplitterOption) *SentenceSplitter {
+	return &SentenceSplitter{config: newSplitterConfig([]string{" ", ""}, opts)}
+}
+
+func (s *SentenceSplitter) Split(text string, metadata map[string]any) []TextChunk {
+	pieces := []piece{}
+	start := 0
+	for _, end := range sentenceEndPattern.FindAllStringIndex(text, -1) {
+		pieces = append(pieces, s.config.splitRecursive(text, start, end[1], s.config.separators)...)
+		start = end[1]
+	}
+	if start < len(text) {
+		pieces = append(pieces, s.config.splitRecursive(text, start, len(text), s.config.separators)...)
+	}
+	return newChunks(text, s.config.merge(pieces, nil), metadata)
+}
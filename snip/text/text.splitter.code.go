@@ -0,0 +1,162 @@
+package text
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// Declaration metadata set by the CodeSplitter
+const (
+	// MetadataLanguage is the language of the code
+	MetadataLanguage = "language"
+	// MetadataKind is the kind of the Go declaration of the chunk: package, import, func, method, type, const or var
+	MetadataKind = "kind"
+	// MetadataName is the name of the Go declaration of the chunk, e.g. "Split" or "(*CodeSplitter).Split"
+	MetadataName = "name"
+)
+
+// CodeSeparators are the separators of the CodeSplitter by language,
+// starting with the declarations so that the chunks keep them whole
+var CodeSeparators = map[string][]string{
+	"go":         {"\nfunc ", "\ntype ", "\nvar ", "\nconst ", "\n\n", "\n", " ", ""},
+	"python":     {"\nclass ", "\ndef ", "\n\tdef ", "\n    def ", "\n\n", "\n", " ", ""},
+	"javascript": {"\nfunction ", "\nclass ", "\nexport ", "\nconst ", "\nlet ", "\n\n", "\n", " ", ""},
+	"typescript": {"\nfunction ", "\nclass ", "\ninterface ", "\ntype ", "\nexport ", "\nconst ", "\nlet ", "\n\n", "\n", " ", ""},
+	"rust":       {"\nfn ", "\npub fn ", "\nstruct ", "\npub struct ", "\nenum ", "\nimpl ", "\ntrait ", "\nmod ", "\n\n", "\n", " ", ""},
+	"java":       {"\nclass ", "\ninterface ", "\npublic ", "\nprotected ", "\nprivate ", "\nstatic ", "\n\n", "\n", " ", ""},
+}
+
+// CodeSplitter splits source code by declarations
+// Go code is parsed: each top-level declaration, with its doc comment, is a chunk with its kind and name
+// (the package clause and the imports are the first chunk); the declarations longer than the size,
+// and the code of the other languages (or Go code that does not parse), are split with the CodeSeparators
+type CodeSplitter struct {
+	language string
+	config   splitterConfig
+}
+
+// NewCodeSplitter creates a splitter for the code of a language of CodeSeparators
+func NewCodeSplitter(language string, opts ...SplitterOption) (*CodeSplitter, error) {
+	language = strings.ToLower(language)
+	separators, ok := CodeSeparators[language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q", language)
+	}
+	return &CodeSplitter{language: language, config: newSplitterConfig(separators, opts)}, nil
+}
+
+func (s *CodeSplitter) Split(text string, metadata map[string]any) []TextChunk {
+	var spans []chunkSpan
+	if s.language == "go" {
+		spans = s.splitGo(text)
+	}
+	if spans == nil {
+		pieces := s.config.splitRecursive(text, 0, len(text), s.config.separators)
+		spans = s.config.merge(pieces, nil)
+	}
+
+	chunks := newChunks(text, spans, metadata)
+	for _, chunk := range chunks {
+		chunk.Metadata[MetadataLanguage] = s.language
+	}
+	return chunks
+}
+
+// goDeclaration is a top-level declaration of a Go file, from its doc comment to its end
+type goDeclaration struct {
+	start, end int
+	kind, name string
+}
+
+// splitGo splits Go code by declarations, nil if the code does not parse
+// The declarations are contiguous: the comments between two declarations go with the next one,
+// and the end of the file with the last one
+func (s *CodeSplitter) splitGo(text string) []chunkSpan {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", text, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+
+	header := goDeclaration{start: 0, end: offset(file.Name.End()), kind: "package", name: file.Name.Name}
+	declarations := []goDeclaration{}
+	for _, decl := range file.Decls {
+		declaration := goDeclaration{start: offset(decl.Pos()), end: offset(decl.End())}
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Doc != nil {
+				declaration.start = offset(decl.Doc.Pos())
+			}
+			declaration.kind, declaration.name = "func", decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				declaration.kind = "method"
+				declaration.name = fmt.Sprintf("(%s).%s", receiverType(text[offset(decl.Recv.List[0].Type.Pos()):offset(decl.Recv.List[0].Type.End())]), decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			if decl.Doc != nil {
+				declaration.start = offset(decl.Doc.Pos())
+			}
+			declaration.kind = decl.Tok.String()
+			if decl.Tok == token.IMPORT {
+				// The imports go with the package clause
+				header.end = declaration.end
+				continue
+			}
+			declaration.name = strings.Join(specNames(decl.Specs), ", ")
+		default:
+			continue
+		}
+		declarations = append(declarations, declaration)
+	}
+
+	// Make the declarations contiguous
+	previousEnd := header.end
+	for i := range declarations {
+		declarations[i].start = previousEnd
+		previousEnd = declarations[i].end
+	}
+	if len(declarations) > 0 {
+		declarations[len(declarations)-1].end = len(text)
+	} else {
+		header.end = len(text)
+	}
+
+	spans := []chunkSpan{}
+	for _, declaration := range slices.Insert(declarations, 0, header) {
+		declarationMetadata := map[string]any{MetadataKind: declaration.kind, MetadataName: declaration.name}
+		pieces := s.config.splitRecursive(text, declaration.start, declaration.end, s.config.separators)
+		spans = append(spans, s.config.merge(pieces, declarationMetadata)...)
+	}
+	return spans
+}
+
+// receiverType returns the type of a method receiver without its type parameters, e.g. *CodeSplitter
+func receiverType(receiver string) string {
+	if idx := strings.IndexByte(receiver, '['); idx >= 0 {
+		return receiver[:idx]
+	}
+	return receiver
+}
+
+// specNames returns the names declared by the specs of a declaration
+func specNames(specs []ast.Spec) []string {
+	names := []string{}
+	for _, spec := range specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, spec.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range spec.Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,283 @@
+package text
+
+import (
+	"maps"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultChunkSize is the default maximum length of a chunk
+	DefaultChunkSize = 1000
+	// DefaultChunkOverlap is the default length shared by two consecutive chunks
+	DefaultChunkOverlap = 0
+)
+
+// Position metadata set by the splitters on each chunk
+const (
+	// MetadataChunkIndex is the index of the chunk in the chunks of the text
+	MetadataChunkIndex = "chunk_index"
+	// MetadataStartOffset and MetadataEndOffset are the byte offsets of the chunk in the text
+	MetadataStartOffset = "start_offset"
+	MetadataEndOffset   = "end_offset"
+	// MetadataStartLine and MetadataEndLine are the first and last lines of the chunk (from 1)
+	MetadataStartLine = "start_line"
+	MetadataEndLine   = "end_line"
+)
+
+// Splitter splits a text into chunks
+type Splitter interface {
+	// Split returns the chunks of the text, with a copy of metadata and their position
+	Split(text string, metadata map[string]any) []TextChunk
+}
+
+// LengthFunc measures the size of a text, in characters (CharacterLength), tokens (EstimateTokens)
+// or with the tokenizer of a model
+type LengthFunc func(text string) int
+
+// CharacterLength returns the number of characters (runes) of a text
+func CharacterLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// EstimateTokens approximates the number of tokens of a text, counting about 4 characters per token in each word
+func EstimateTokens(text string) int {
+	tokens := 0
+	for _, word := range strings.Fields(text) {
+		tokens += (utf8.RuneCountInString(word) + 3) / 4
+	}
+	return tokens
+}
+
+// splitterConfig is the configuration shared by the splitters
+type splitterConfig struct {
+	size       int
+	overlap    int
+	length     LengthFunc
+	separators []string
+}
+
+// SplitterOption defines a functional option for configuring a splitter
+type SplitterOption func(*splitterConfig)
+
+// WithChunkSize sets the maximum length of a chunk (DefaultChunkSize by default)
+// A chunk can only be longer when a single character is longer
+func WithChunkSize(size int) SplitterOption {
+	return func(c *splitterConfig) {
+		c.size = size
+	}
+}
+
+// WithChunkOverlap sets the length shared by two consecutive chunks (DefaultChunkOverlap by default)
+// The overlap is made of whole parts of the text (words, sentences...), so it can be shorter
+func WithChunkOverlap(overlap int) SplitterOption {
+	return func(c *splitterConfig) {
+		c.overlap = overlap
+	}
+}
+
+// WithLengthFunction sets how the length of the chunks is measured (CharacterLength by default)
+// e.g. WithLengthFunction(text.EstimateTokens) for sizes in tokens
+func WithLengthFunction(length LengthFunc) SplitterOption {
+	return func(c *splitterConfig) {
+		c.length = length
+	}
+}
+
+// WithSeparators sets the separators tried in order by the recursive splitters
+func WithSeparators(separators ...string) SplitterOption {
+	return func(c *splitterConfig) {
+		c.separators = separators
+	}
+}
+
+// newSplitterConfig applies the options to the default configuration
+func newSplitterConfig(defaultSeparators []string, opts []SplitterOption) splitterConfig {
+	config := splitterConfig{
+		size:       DefaultChunkSize,
+		overlap:    DefaultChunkOverlap,
+		length:     CharacterLength,
+		separators: defaultSeparators,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.size <= 0 {
+		config.size = DefaultChunkSize
+	}
+	if config.overlap < 0 || config.overlap >= config.size {
+		config.overlap = 0
+	}
+	if config.length == nil {
+		config.length = CharacterLength
+	}
+	return config
+}
+
+// piece is an unbreakable part of a text, between two byte offsets
+type piece struct {
+	start, end int
+	length     int
+}
+
+// chunkSpan is a chunk of a text, between two byte offsets, with the metadata of its splitter
+type chunkSpan struct {
+	start, end int
+	metadata   map[string]any
+}
+
+// newPiece measures the part of the text between two offsets
+func (c splitterConfig) newPiece(text string, start, end int) piece {
+	return piece{start: start, end: end, length: c.length(text[start:end])}
+}
+
+// merge groups the consecutive pieces in chunks of at most size, the next chunk starting
+// with the last pieces of the previous one that fit in overlap
+func (c splitterConfig) merge(pieces []piece, metadata map[string]any) []chunkSpan {
+	spans := []chunkSpan{}
+	first, length := 0, 0
+	for i, p := range pieces {
+		if i > first && length+p.length > c.size {
+			spans = append(spans, chunkSpan{start: pieces[first].start, end: pieces[i-1].end, metadata: metadata})
+			// The next chunk starts after the first piece of the previous one, so that the splitting advances
+			next, kept := i, 0
+			for next > first+1 && kept+pieces[next-1].length <= c.overlap && kept+pieces[next-1].length+p.length <= c.size {
+				next--
+				kept += pieces[next].length
+			}
+			first, length = next, kept
+		}
+		length += p.length
+	}
+	if first < len(pieces) {
+		spans = append(spans, chunkSpan{start: pieces[first].start, end: pieces[len(pieces)-1].end, metadata: metadata})
+	}
+	return spans
+}
+
+// splitRecursive splits the part of the text between two offsets in pieces of at most size,
+// with the first separator found, then the next separators for the pieces still too long
+// The empty separator splits by characters
+func (c splitterConfig) splitRecursive(text string, start, end int, separators []string) []piece {
+	if p := c.newPiece(text, start, end); p.length <= c.size {
+		return []piece{p}
+	}
+	for i, separator := range separators {
+		if separator == "" {
+			break
+		}
+		if !strings.Contains(text[start:end], separator) {
+			continue
+		}
+		pieces := []piece{}
+		for pos := start; pos < end; {
+			next := end
+			if idx := strings.Index(text[pos:end], separator); idx >= 0 {
+				next = pos + idx + separatorCut(separator)
+			}
+			pieces = append(pieces, c.splitRecursive(text, pos, next, separators[i+1:])...)
+			pos = next
+		}
+		return pieces
+	}
+	return c.splitCharacters(text, start, end)
+}
+
+// separatorCut returns where a text is cut in a separator: after it, or after its leading new lines
+// for a separator starting a declaration (e.g. "\nfunc "), so that the keyword starts the next piece
+func separatorCut(separator string) int {
+	trimmed := strings.TrimLeft(separator, "\n")
+	if trimmed != "" && len(trimmed) < len(separator) {
+		return len(separator) - len(trimmed)
+	}
+	return len(separator)
+}
+
+// splitCharacters splits the part of the text between two offsets in pieces of at most size,
+// by characters (a piece has at least one character)
+func (c splitterConfig) splitCharacters(text string, start, end int) []piece {
+	// boundaries are the offsets of the ends of the characters
+	boundaries := []int{}
+	for pos := start; pos < end; {
+		_, width := utf8.DecodeRuneInString(text[pos:end])
+		pos += width
+		boundaries = append(boundaries, pos)
+	}
+	fits := func(from, to int) bool {
+		return c.length(text[from:boundaries[to]]) <= c.size
+	}
+
+	pieces := []piece{}
+	for first := 0; first < len(boundaries); {
+		pieceStart := start
+		if first > 0 {
+			pieceStart = boundaries[first-1]
+		}
+		// Exponential then binary search of the last character of the piece,
+		// so that the measured texts stay close to the size of a piece
+		last, step := first, 1
+		for last+step < len(boundaries) && fits(pieceStart, last+step) {
+			last += step
+			step *= 2
+		}
+		for step > 1 {
+			step /= 2
+			if last+step < len(boundaries) && fits(pieceStart, last+step) {
+				last += step
+			}
+		}
+		pieces = append(pieces, c.newPiece(text, pieceStart, boundaries[last]))
+		first = last + 1
+	}
+	return pieces
+}
+
+// newChunks returns the chunks of the spans of the text, without their leading and trailing spaces,
+// with a copy of metadata, the metadata of their span and their position
+func newChunks(text string, spans []chunkSpan, metadata map[string]any) []TextChunk {
+	// lines are the offsets of the new lines, to compute the line numbers
+	lines := []int{}
+	for i := range len(text) {
+		if text[i] == '\n' {
+			lines = append(lines, i)
+		}
+	}
+	lineOf := func(offset int) int {
+		return sort.SearchInts(lines, offset) + 1
+	}
+
+	chunks := []TextChunk{}
+	for _, span := range spans {
+		start, end := span.start, span.end
+		for start < end {
+			r, width := utf8.DecodeRuneInString(text[start:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			start += width
+		}
+		for end > start {
+			r, width := utf8.DecodeLastRuneInString(text[start:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end -= width
+		}
+		if start == end {
+			continue
+		}
+
+		chunkMetadata := make(map[string]any, len(metadata)+len(span.metadata)+5)
+		maps.Copy(chunkMetadata, metadata)
+		maps.Copy(chunkMetadata, span.metadata)
+		chunkMetadata[MetadataChunkIndex] = len(chunks)
+		chunkMetadata[MetadataStartOffset] = start
+		chunkMetadata[MetadataEndOffset] = end
+		chunkMetadata[MetadataStartLine] = lineOf(start)
+		chunkMetadata[MetadataEndLine] = lineOf(end - 1)
+		chunks = append(chunks, TextChunk{Content: text[start:end], Metadata: chunkMetadata})
+	}
+	return chunks
+}
@@ -0,0 +1,98 @@
+package text
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Heading metadata set by the MarkdownSplitter
+const (
+	// MetadataHeading is the title of the section of the chunk
+	MetadataHeading = "heading"
+	// MetadataHeadingPath is the titles of the section of the chunk and of its parents, joined by " > "
+	MetadataHeadingPath = "heading_path"
+)
+
+var (
+	// headingPattern matches an ATX heading: its level and its title
+	headingPattern = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	// fencePattern matches the opening or closing line of a fenced code block
+	fencePattern = regexp.MustCompile("^[ \t]*(```|~~~)")
+)
+
+// MarkdownSeparators are the separators of the sections of the MarkdownSplitter
+var MarkdownSeparators = []string{"\n```", "\n\n", "\n", ". ", " ", ""}
+
+// MarkdownSplitter splits a Markdown document by sections (the headings outside the code blocks),
+// then splits the sections longer than the size with the MarkdownSeparators
+// The chunks have the heading of their section (MetadataHeading) and the path of the headings
+// from the top of the document (MetadataHeadingPath), e.g. "Install > Linux"
+type MarkdownSplitter struct {
+	config splitterConfig
+}
+
+// NewMarkdownSplitter creates a Markdown splitter
+func NewMarkdownSplitter(opts ...SplitterOption) *MarkdownSplitter {
+	return &MarkdownSplitter{config: newSplitterConfig(MarkdownSeparators, opts)}
+}
+
+// markdownSection is a section of a document, from its heading to the next heading
+type markdownSection struct {
+	start    int
+	headings []string
+}
+
+func (s *MarkdownSplitter) Split(text string, metadata map[string]any) []TextChunk {
+	sections := []markdownSection{{start: 0}}
+	// headings are the titles of the current section and of its parents, by level
+	headings := [6]string{}
+	inFence := ""
+
+	for offset := 0; offset < len(text); {
+		line, next := text[offset:], len(text)
+		if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+			line, next = line[:idx], offset+idx+1
+		}
+		line = strings.TrimRight(line, "\r")
+
+		if fence := fencePattern.FindStringSubmatch(line); fence != nil {
+			switch inFence {
+			case "":
+				inFence = fence[1]
+			case fence[1]:
+				inFence = ""
+			}
+		} else if match := headingPattern.FindStringSubmatch(line); match != nil && inFence == "" {
+			level := len(match[1])
+			headings[level-1] = match[2]
+			clear(headings[level:])
+
+			path := []string{}
+			for _, heading := range headings {
+				if heading != "" {
+					path = append(path, heading)
+				}
+			}
+			sections = append(sections, markdownSection{start: offset, headings: path})
+		}
+		offset = next
+	}
+
+	spans := []chunkSpan{}
+	for i, section := range sections {
+		end := len(text)
+		if i+1 < len(sections) {
+			end = sections[i+1].start
+		}
+		var sectionMetadata map[string]any
+		if len(section.headings) > 0 {
+			sectionMetadata = map[string]any{
+				MetadataHeading:     section.headings[len(section.headings)-1],
+				MetadataHeadingPath: strings.Join(section.headings, " > "),
+			}
+		}
+		pieces := s.config.splitRecursive(text, section.start, end, s.config.separators)
+		spans = append(spans, s.config.merge(pieces, sectionMetadata)...)
+	}
+	return newChunks(text, spans, metadata)
+}
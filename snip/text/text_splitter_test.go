@@ -0,0 +1,201 @@
+package text
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// contents returns the contents of the chunks
+func contents(chunks []TextChunk) []string {
+	result := []string{}
+	for _, chunk := range chunks {
+		result = append(result, chunk.Content)
+	}
+	return result
+}
+
+// checkPositions checks that the offsets of the chunks give back their content
+func checkPositions(t *testing.T, text string, chunks []TextChunk) {
+	t.Helper()
+	for idx, chunk := range chunks {
+		start, end := chunk.Metadata[MetadataStartOffset].(int), chunk.Metadata[MetadataEndOffset].(int)
+		if text[start:end] != chunk.Content || chunk.Metadata[MetadataChunkIndex] != idx {
+			t.Errorf("chunk %d %q has the position %d-%d (%q) and the index %v", idx, chunk.Content, start, end, text[start:end], chunk.Metadata[MetadataChunkIndex])
+		}
+	}
+}
+
+func TestFixedSizeSplitter(t *testing.T) {
+	text := "one two three four five six seven"
+	// The lengths count the spaces after the words: "three " fits in the overlap
+	splitter := NewFixedSizeSplitter(WithChunkSize(14), WithChunkOverlap(6))
+	chunks := splitter.Split(text, map[string]any{"source": "numbers.txt"})
+
+	want := []string{"one two three", "three four", "four five six", "six seven"}
+	if got := contents(chunks); !slices.Equal(got, want) {
+		t.Errorf("Split() = %q, want %q", got, want)
+	}
+	checkPositions(t, text, chunks)
+	if chunks[1].Metadata["source"] != "numbers.txt" {
+		t.Errorf("chunk metadata = %v, want the metadata of the text", chunks[1].Metadata)
+	}
+
+	t.Run("long word", func(t *testing.T) {
+		chunks := NewFixedSizeSplitter(WithChunkSize(4)).Split("abcdéfghij", nil)
+		if got := contents(chunks); !slices.Equal(got, []string{"abcd", "éfgh", "ij"}) {
+			t.Errorf("Split() = %q, want the word cut between characters", got)
+		}
+	})
+}
+
+func TestRecursiveSplitter(t *testing.T) {
+	text := "First paragraph, first line.\nSecond line.\n\nSecond paragraph is a bit longer than the others."
+	chunks := NewRecursiveSplitter(WithChunkSize(45)).Split(text, nil)
+
+	want := []string{"First paragraph, first line.\nSecond line.", "Second paragraph is a bit longer than the", "others."}
+	if got := contents(chunks); !slices.Equal(got, want) {
+		t.Errorf("Split() = %q, want %q", got, want)
+	}
+	checkPositions(t, text, chunks)
+	if chunks[1].Metadata[MetadataStartLine] != 4 || chunks[0].Metadata[MetadataEndLine] != 2 {
+		t.Errorf("lines = %v and %v, want the chunks from line 1 to 2 and from line 4", chunks[0].Metadata, chunks[1].Metadata)
+	}
+}
+
+func TestSentenceSplitter(t *testing.T) {
+	text := "Dolphins swim. Eagles fly! Do frogs jump? Yes.\n\nA new paragraph"
+	chunks := NewSentenceSplitter(WithChunkSize(30), WithChunkOverlap(15)).Split(text, nil)
+
+	want := []string{"Dolphins swim. Eagles fly!", "Eagles fly! Do frogs jump?", "Do frogs jump? Yes.", "Yes.\n\nA new paragraph"}
+	if got := contents(chunks); !slices.Equal(got, want) {
+		t.Errorf("Split() = %q, want %q", got, want)
+	}
+	checkPositions(t, text, chunks)
+}
+
+func TestSplitterTokenLength(t *testing.T) {
+	if n := EstimateTokens("a tokenizer estimation"); n != 7 {
+		t.Errorf("EstimateTokens() = %d, want 1 + 3 + 3", n)
+	}
+	text := strings.Repeat("word ", 20)
+	chunks := NewFixedSizeSplitter(WithChunkSize(5), WithLengthFunction(EstimateTokens)).Split(text, nil)
+	if len(chunks) != 4 || chunks[0].Content != "word word word word word" {
+		t.Errorf("Split() = %q, want 4 chunks of 5 tokens", contents(chunks))
+	}
+}
+
+func TestMarkdownSplitter(t *testing.T) {
+	text := strings.Join([]string{
+		"Introduction without heading.",
+		"# Install",
+		"Download the binary.",
+		"## Linux",
+		"Run the script:",
+		"```bash",
+		"# not a heading",
+		"./install.sh",
+		"```",
+		"## macOS",
+		"Use brew.",
+		"# Usage",
+		"Run snip.",
+	}, "\n")
+	chunks := NewMarkdownSplitter().Split(text, nil)
+
+	want := []struct{ path, heading string }{
+		{"", ""},
+		{"Install", "Install"},
+		{"Install > Linux", "Linux"},
+		{"Install > macOS", "macOS"},
+		{"Usage", "Usage"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("Split() = %q, want %d sections", contents(chunks), len(want))
+	}
+	for idx, section := range want {
+		path, _ := chunks[idx].Metadata[MetadataHeadingPath].(string)
+		heading, _ := chunks[idx].Metadata[MetadataHeading].(string)
+		if path != section.path || heading != section.heading {
+			t.Errorf("chunk %d %q has the path %q and heading %q, want %q and %q", idx, chunks[idx].Content, path, heading, section.path, section.heading)
+		}
+	}
+	if !strings.Contains(chunks[2].Content, "# not a heading") {
+		t.Errorf("chunk %q, want the code block in the Linux section", chunks[2].Content)
+	}
+	checkPositions(t, text, chunks)
+
+	t.Run("long section", func(t *testing.T) {
+		chunks := NewMarkdownSplitter(WithChunkSize(30)).Split("# Title\nA first paragraph.\n\nA second paragraph.", nil)
+		if len(chunks) != 2 || chunks[1].Content != "A second paragraph." || chunks[1].Metadata[MetadataHeading] != "Title" {
+			t.Errorf("Split() = %v, want the section split in two with its heading", chunks)
+		}
+	})
+}
+
+func TestCodeSplitter(t *testing.T) {
+	code := `// Package zoo is a test
+package zoo
+
+import "fmt"
+
+// Animal is an animal
+type Animal struct{ Name string }
+
+// Speak prints the name
+func (a *Animal) Speak() {
+	fmt.Println(a.Name)
+}
+
+const (
+	Dolphin = "dolphin"
+	Eagle   = "eagle"
+)
+
+func New(name string) *Animal { return &Animal{Name: name} }
+`
+	splitter, err := NewCodeSplitter("Go")
+	if err != nil {
+		t.Fatalf("NewCodeSplitter() unexpected error: %v", err)
+	}
+	chunks := splitter.Split(code, map[string]any{"source": "zoo.go"})
+
+	want := []struct{ kind, name, start string }{
+		{"package", "zoo", "// Package zoo"},
+		{"type", "Animal", "// Animal is"},
+		{"method", "(*Animal).Speak", "// Speak prints"},
+		{"const", "Dolphin, Eagle", "const ("},
+		{"func", "New", "func New"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("Split() = %q, want %d declarations", contents(chunks), len(want))
+	}
+	for idx, declaration := range want {
+		metadata := chunks[idx].Metadata
+		if metadata[MetadataKind] != declaration.kind || metadata[MetadataName] != declaration.name || !strings.HasPrefix(chunks[idx].Content, declaration.start) {
+			t.Errorf("chunk %d = %q %v, want the %s %s", idx, chunks[idx].Content, metadata, declaration.kind, declaration.name)
+		}
+		if metadata[MetadataLanguage] != "go" || metadata["source"] != "zoo.go" {
+			t.Errorf("chunk %d metadata = %v, want the language and the metadata of the file", idx, metadata)
+		}
+	}
+	if chunks[2].Metadata[MetadataStartLine] != 9 || chunks[2].Metadata[MetadataEndLine] != 12 {
+		t.Errorf("method lines = %v-%v, want 9-12", chunks[2].Metadata[MetadataStartLine], chunks[2].Metadata[MetadataEndLine])
+	}
+	checkPositions(t, code, chunks)
+
+	t.Run("other languages and invalid Go", func(t *testing.T) {
+		python, _ := NewCodeSplitter("python", WithChunkSize(30))
+		chunks := python.Split("def one():\n    return 1\n\ndef two():\n    return 2\n", nil)
+		if got := contents(chunks); !slices.Equal(got, []string{"def one():\n    return 1", "def two():\n    return 2"}) {
+			t.Errorf("Split() = %q, want one chunk per function", got)
+		}
+		golang, _ := NewCodeSplitter("go", WithChunkSize(20))
+		if chunks := golang.Split("func broken( {\n}\n\nfunc other() {}", nil); len(chunks) != 2 {
+			t.Errorf("Split() of invalid Go = %q, want the separators fallback", contents(chunks))
+		}
+		if _, err := NewCodeSplitter("cobol"); err == nil {
+			t.Error("NewCodeSplitter() of an unknown language: expected an error")
+		}
+	})
+}
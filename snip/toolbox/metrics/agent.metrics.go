@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultSession is the session label used by agents holding a single conversation history
+const DefaultSession = "default"
+
+// AgentMetrics groups the metrics recorded by the agents and the agent servers
+// All the methods are safe to call on a nil *AgentMetrics (nothing is recorded)
+type AgentMetrics struct {
+	// Registry is the registry holding the metrics (use Registry.Handler() to expose them)
+	Registry *Registry
+
+	// Requests counts the HTTP requests by agent, endpoint and status code
+	Requests *CounterVec
+	// RequestDuration measures the HTTP request latency by agent and endpoint
+	RequestDuration *HistogramVec
+	// TimeToFirstToken measures the delay before the first streamed chunk by agent and flow
+	TimeToFirstToken *HistogramVec
+	// GeneratedTokens counts the generated tokens by agent and flow
+	GeneratedTokens *CounterVec
+	// ActiveStreams is the number of streaming completions in progress by agent
+	ActiveStreams *GaugeVec
+	// StreamCancellations counts the cancelled streaming completions by agent
+	StreamCancellations *CounterVec
+	// HistoryMessages is the number of messages in the conversation history by agent and session
+	HistoryMessages *GaugeVec
+	// EngineErrors counts the errors returned by the inference engine by agent and flow
+	EngineErrors *CounterVec
+}
+
+// NewAgentMetrics creates the agent metrics and registers them in registry
+func NewAgentMetrics(registry *Registry) *AgentMetrics {
+	return &AgentMetrics{
+		Registry: registry,
+		Requests: registry.NewCounterVec("snip_http_requests_total",
+			"Total number of HTTP requests handled by the agent server.", "agent", "endpoint", "code"),
+		RequestDuration: registry.NewHistogramVec("snip_http_request_duration_seconds",
+			"HTTP request latency in seconds.", nil, "agent", "endpoint"),
+		TimeToFirstToken: registry.NewHistogramVec("snip_time_to_first_token_seconds",
+			"Delay in seconds between the start of a streaming completion and its first chunk.", nil, "agent", "flow"),
+		GeneratedTokens: registry.NewCounterVec("snip_generated_tokens_total",
+			"Total number of tokens generated by the model.", "agent", "flow"),
+		ActiveStreams: registry.NewGaugeVec("snip_active_streams",
+			"Number of streaming completions in progress.", "agent"),
+		StreamCancellations: registry.NewCounterVec("snip_stream_cancellations_total",
+			"Total number of cancelled streaming completions.", "agent"),
+		HistoryMessages: registry.NewGaugeVec("snip_history_messages",
+			"Number of messages in the conversation history.", "agent", "session"),
+		EngineErrors: registry.NewCounterVec("snip_engine_errors_total",
+			"Total number of errors returned by the inference engine.", "agent", "flow"),
+	}
+}
+
+var (
+	defaultAgentMetrics     *AgentMetrics
+	defaultAgentMetricsOnce sync.Once
+)
+
+// DefaultAgentMetrics returns the agent metrics shared by all the agents by default
+// They are registered in their own registry, available with DefaultAgentMetrics().Registry
+func DefaultAgentMetrics() *AgentMetrics {
+	defaultAgentMetricsOnce.Do(func() {
+		defaultAgentMetrics = NewAgentMetrics(NewRegistry())
+	})
+	return defaultAgentMetrics
+}
+
+// ObserveRequest records an HTTP request and its latency
+func (m *AgentMetrics) ObserveRequest(agent, endpoint string, code int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.Requests.WithLabelValues(agent, endpoint, strconv.Itoa(code)).Inc()
+	m.RequestDuration.WithLabelValues(agent, endpoint).Observe(duration.Seconds())
+}
+
+// ObserveTimeToFirstToken records the delay before the first streamed chunk
+func (m *AgentMetrics) ObserveTimeToFirstToken(agent, flow string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.TimeToFirstToken.WithLabelValues(agent, flow).Observe(duration.Seconds())
+}
+
+// AddGeneratedTokens records the number of generated tokens
+func (m *AgentMetrics) AddGeneratedTokens(agent, flow string, tokens int) {
+	if m == nil || tokens <= 0 {
+		return
+	}
+	m.GeneratedTokens.WithLabelValues(agent, flow).Add(float64(tokens))
+}
+
+// StreamStarted increments the number of active streams
+func (m *AgentMetrics) StreamStarted(agent string) {
+	if m == nil {
+		return
+	}
+	m.ActiveStreams.WithLabelValues(agent).Inc()
+}
+
+// StreamEnded decrements the number of active streams
+func (m *AgentMetrics) StreamEnded(agent string) {
+	if m == nil {
+		return
+	}
+	m.ActiveStreams.WithLabelValues(agent).Dec()
+}
+
+// StreamCancelled records a cancelled streaming completion
+func (m *AgentMetrics) StreamCancelled(agent string) {
+	if m == nil {
+		return
+	}
+	m.StreamCancellations.WithLabelValues(agent).Inc()
+}
+
+// SetHistorySize records the number of messages in a conversation history
+func (m *AgentMetrics) SetHistorySize(agent, session string, messages int) {
+	if m == nil {
+		return
+	}
+	m.HistoryMessages.WithLabelValues(agent, session).Set(float64(messages))
+}
+
+// EngineError records an error returned by the inference engine
+func (m *AgentMetrics) EngineError(agent, flow string) {
+	if m == nil {
+		return
+	}
+	m.EngineErrors.WithLabelValues(agent, flow).Inc()
+}
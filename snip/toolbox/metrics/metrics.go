@@ -0,0 +1,415 @@
+package metrics
+
+/*
+Minimal metrics primitives (counters, gauges and histograms with labels)
+exposed in the Prometheus text exposition format, without external dependencies.
+
+	registry := metrics.NewRegistry()
+	requests := registry.NewCounterVec("app_requests_total", "Number of requests", "endpoint")
+	requests.WithLabelValues("/api/chat").Inc()
+
+	http.Handle("/metrics", registry.Handler())
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the default histogram buckets (in seconds)
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// collector is implemented by every metric family of a registry
+type collector interface {
+	metricName() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds a set of metric families and writes them in the Prometheus text format
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		names: map[string]bool{},
+	}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[c.metricName()] {
+		panic(fmt.Sprintf("metrics: %s is already registered", c.metricName()))
+	}
+	r.names[c.metricName()] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// NewCounterVec creates and registers a counter family with the given label names
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	counterVec := &CounterVec{family: newFamily[Counter](name, help, labelNames)}
+	r.register(counterVec)
+	return counterVec
+}
+
+// NewGaugeVec creates and registers a gauge family with the given label names
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	gaugeVec := &GaugeVec{family: newFamily[Gauge](name, help, labelNames)}
+	r.register(gaugeVec)
+	return gaugeVec
+}
+
+// NewHistogramVec creates and registers a histogram family with the given buckets and label names
+// If buckets is nil, DefaultBuckets is used
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	sortedBuckets := append([]float64(nil), buckets...)
+	sort.Float64s(sortedBuckets)
+	histogramVec := &HistogramVec{family: newFamily[Histogram](name, help, labelNames), buckets: sortedBuckets}
+	r.register(histogramVec)
+	return histogramVec
+}
+
+// WriteTo writes all the metric families in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	buffer := bufio.NewWriter(counter)
+
+	r.mu.RLock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	for _, c := range collectors {
+		c.write(buffer)
+	}
+	err := buffer.Flush()
+	return counter.n, err
+}
+
+// Handler returns an HTTP handler serving the metrics in the Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// family holds the children of a metric family, indexed by their label values
+type family[T any] struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu       sync.RWMutex
+	children map[string]*child[T]
+}
+
+type child[T any] struct {
+	labelValues []string
+	metric      *T
+}
+
+func newFamily[T any](name, help string, labelNames []string) family[T] {
+	return family[T]{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		children:   map[string]*child[T]{},
+	}
+}
+
+func (f *family[T]) metricName() string {
+	return f.name
+}
+
+// get returns the metric for the given label values, creating it with create if needed
+func (f *family[T]) get(labelValues []string, create func() *T) *T {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.RLock()
+	c, ok := f.children[key]
+	f.mu.RUnlock()
+	if ok {
+		return c.metric
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.children[key]; ok {
+		return c.metric
+	}
+	c = &child[T]{labelValues: append([]string(nil), labelValues...), metric: create()}
+	f.children[key] = c
+	return c.metric
+}
+
+// sortedChildren returns the children sorted by label values, for a stable output
+func (f *family[T]) sortedChildren() []*child[T] {
+	f.mu.RLock()
+	children := make([]*child[T], 0, len(f.children))
+	for _, c := range f.children {
+		children = append(children, c)
+	}
+	f.mu.RUnlock()
+
+	sort.Slice(children, func(i, j int) bool {
+		return strings.Join(children[i].labelValues, "\xff") < strings.Join(children[j].labelValues, "\xff")
+	})
+	return children
+}
+
+func (f *family[T]) writeHeader(w *bufio.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, metricType)
+}
+
+// +++ Counter +++
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by value (negative values are ignored)
+func (c *Counter) Add(value float64) {
+	if value < 0 {
+		return
+	}
+	addFloat(&c.bits, value)
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// CounterVec is a family of counters partitioned by label values
+type CounterVec struct {
+	family[Counter]
+}
+
+// WithLabelValues returns the counter for the given label values
+func (v *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return v.get(labelValues, func() *Counter { return &Counter{} })
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.writeHeader(w, "counter")
+	for _, c := range v.sortedChildren() {
+		writeSample(w, v.name, v.labelNames, c.labelValues, "", "", c.metric.Value())
+	}
+}
+
+// +++ Gauge +++
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set sets the gauge to value
+func (g *Gauge) Set(value float64) {
+	g.bits.Store(math.Float64bits(value))
+}
+
+// Inc increments the gauge by 1
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec decrements the gauge by 1
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add adds value to the gauge
+func (g *Gauge) Add(value float64) {
+	addFloat(&g.bits, value)
+}
+
+// Value returns the current value of the gauge
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// GaugeVec is a family of gauges partitioned by label values
+type GaugeVec struct {
+	family[Gauge]
+}
+
+// WithLabelValues returns the gauge for the given label values
+func (v *GaugeVec) WithLabelValues(labelValues ...string) *Gauge {
+	return v.get(labelValues, func() *Gauge { return &Gauge{} })
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.writeHeader(w, "gauge")
+	for _, c := range v.sortedChildren() {
+		writeSample(w, v.name, v.labelNames, c.labelValues, "", "", c.metric.Value())
+	}
+}
+
+// +++ Histogram +++
+
+// Histogram counts observations in configurable buckets
+type Histogram struct {
+	mu           sync.Mutex
+	upperBounds  []float64
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+// Observe adds a single observation to the histogram
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upperBound := range h.upperBounds {
+		if value <= upperBound {
+			h.bucketCounts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Sum returns the sum of all the observations
+func (h *Histogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+// HistogramVec is a family of histograms partitioned by label values
+type HistogramVec struct {
+	family[Histogram]
+	buckets []float64
+}
+
+// WithLabelValues returns the histogram for the given label values
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return v.get(labelValues, func() *Histogram {
+		return &Histogram{
+			upperBounds:  v.buckets,
+			bucketCounts: make([]uint64, len(v.buckets)),
+		}
+	})
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.writeHeader(w, "histogram")
+	for _, c := range v.sortedChildren() {
+		h := c.metric
+		h.mu.Lock()
+		cumulativeCount := uint64(0)
+		for i, upperBound := range h.upperBounds {
+			cumulativeCount += h.bucketCounts[i]
+			writeSample(w, v.name+"_bucket", v.labelNames, c.labelValues, "le", formatFloat(upperBound), float64(cumulativeCount))
+		}
+		writeSample(w, v.name+"_bucket", v.labelNames, c.labelValues, "le", "+Inf", float64(h.count))
+		writeSample(w, v.name+"_sum", v.labelNames, c.labelValues, "", "", h.sum)
+		writeSample(w, v.name+"_count", v.labelNames, c.labelValues, "", "", float64(h.count))
+		h.mu.Unlock()
+	}
+}
+
+// +++ Helpers +++
+
+func addFloat(bits *atomic.Uint64, value float64) {
+	for {
+		oldBits := bits.Load()
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + value)
+		if bits.CompareAndSwap(oldBits, newBits) {
+			return
+		}
+	}
+}
+
+// writeSample writes one sample line, with an optional extra label (used for the "le" label of histograms)
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", labelName, escapeLabelValue(labelValues[i]))
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraName, escapeLabelValue(extraValue))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
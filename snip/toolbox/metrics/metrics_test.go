@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCounterVec(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_requests_total", "Number of requests.", "endpoint")
+
+	counter.WithLabelValues("/api/chat").Inc()
+	counter.WithLabelValues("/api/chat").Add(2)
+	counter.WithLabelValues("/api/ask").Inc()
+	counter.WithLabelValues("/api/ask").Add(-5) // ignored
+
+	if got := counter.WithLabelValues("/api/chat").Value(); got != 3 {
+		t.Errorf("Value() = %v, want 3", got)
+	}
+	if got := counter.WithLabelValues("/api/ask").Value(); got != 1 {
+		t.Errorf("Value() = %v, want 1", got)
+	}
+}
+
+func TestGaugeVec(t *testing.T) {
+	registry := NewRegistry()
+	gauge := registry.NewGaugeVec("test_active", "Active things.", "agent")
+
+	gauge.WithLabelValues("bob").Inc()
+	gauge.WithLabelValues("bob").Inc()
+	gauge.WithLabelValues("bob").Dec()
+	if got := gauge.WithLabelValues("bob").Value(); got != 1 {
+		t.Errorf("Value() = %v, want 1", got)
+	}
+
+	gauge.WithLabelValues("bob").Set(42)
+	if got := gauge.WithLabelValues("bob").Value(); got != 42 {
+		t.Errorf("Value() = %v, want 42", got)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.NewHistogramVec("test_duration_seconds", "Durations.", []float64{1, 0.1}, "endpoint")
+
+	histogram.WithLabelValues("/api/chat").Observe(0.05)
+	histogram.WithLabelValues("/api/chat").Observe(0.5)
+	histogram.WithLabelValues("/api/chat").Observe(5)
+
+	h := histogram.WithLabelValues("/api/chat")
+	if h.Count() != 3 {
+		t.Errorf("Count() = %d, want 3", h.Count())
+	}
+	if h.Sum() != 5.55 {
+		t.Errorf("Sum() = %v, want 5.55", h.Sum())
+	}
+
+	var output strings.Builder
+	registry.WriteTo(&output)
+
+	expectedLines := []string{
+		`# TYPE test_duration_seconds histogram`,
+		`test_duration_seconds_bucket{endpoint="/api/chat",le="0.1"} 1`,
+		`test_duration_seconds_bucket{endpoint="/api/chat",le="1"} 2`,
+		`test_duration_seconds_bucket{endpoint="/api/chat",le="+Inf"} 3`,
+		`test_duration_seconds_sum{endpoint="/api/chat"} 5.55`,
+		`test_duration_seconds_count{endpoint="/api/chat"} 3`,
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(output.String(), line+"\n") {
+			t.Errorf("output does not contain %q:\n%s", line, output.String())
+		}
+	}
+}
+
+func TestRegistryWriteTo(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "Help with \\ and\nnew line.", "label")
+	counter.WithLabelValues("b").Inc()
+	counter.WithLabelValues(`a "quoted"` + "\n").Inc()
+	registry.NewGaugeVec("test_no_label", "Gauge without labels.").WithLabelValues().Set(1.5)
+
+	var output strings.Builder
+	n, err := registry.WriteTo(&output)
+	if err != nil {
+		t.Fatalf("WriteTo() unexpected error: %v", err)
+	}
+	if int(n) != output.Len() {
+		t.Errorf("WriteTo() = %d, want %d", n, output.Len())
+	}
+
+	expected := `# HELP test_total Help with \\ and\nnew line.
+# TYPE test_total counter
+test_total{label="a \"quoted\"\n"} 1
+test_total{label="b"} 1
+# HELP test_no_label Gauge without labels.
+# TYPE test_no_label gauge
+test_no_label 1.5
+`
+	if output.String() != expected {
+		t.Errorf("WriteTo() output =\n%s\nwant\n%s", output.String(), expected)
+	}
+}
+
+func TestRegistryDuplicateName(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("test_total", "help")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering the same name twice should panic")
+		}
+	}()
+	registry.NewGaugeVec("test_total", "help")
+}
+
+func TestRegistryHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("test_total", "help").WithLabelValues().Inc()
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want text/plain; version=0.0.4", contentType)
+	}
+	if !strings.Contains(recorder.Body.String(), "test_total 1\n") {
+		t.Errorf("body does not contain the counter:\n%s", recorder.Body.String())
+	}
+}
+
+func TestCounterConcurrency(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "help", "worker")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				counter.WithLabelValues("w").Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := counter.WithLabelValues("w").Value(); got != 5000 {
+		t.Errorf("Value() = %v, want 5000", got)
+	}
+}
+
+func TestAgentMetrics(t *testing.T) {
+	t.Run("records values", func(t *testing.T) {
+		agentMetrics := NewAgentMetrics(NewRegistry())
+
+		agentMetrics.ObserveRequest("bob", "POST /api/chat", 200, 150*time.Millisecond)
+		agentMetrics.AddGeneratedTokens("bob", "chat", 12)
+		agentMetrics.StreamStarted("bob")
+		agentMetrics.StreamCancelled("bob")
+		agentMetrics.SetHistorySize("bob", DefaultSession, 4)
+		agentMetrics.EngineError("bob", "chat")
+
+		if got := agentMetrics.Requests.WithLabelValues("bob", "POST /api/chat", "200").Value(); got != 1 {
+			t.Errorf("Requests = %v, want 1", got)
+		}
+		if got := agentMetrics.GeneratedTokens.WithLabelValues("bob", "chat").Value(); got != 12 {
+			t.Errorf("GeneratedTokens = %v, want 12", got)
+		}
+		if got := agentMetrics.ActiveStreams.WithLabelValues("bob").Value(); got != 1 {
+			t.Errorf("ActiveStreams = %v, want 1", got)
+		}
+		if got := agentMetrics.HistoryMessages.WithLabelValues("bob", DefaultSession).Value(); got != 4 {
+			t.Errorf("HistoryMessages = %v, want 4", got)
+		}
+
+		var output strings.Builder
+		agentMetrics.Registry.WriteTo(&output)
+		if !strings.Contains(output.String(), `snip_engine_errors_total{agent="bob",flow="chat"} 1`) {
+			t.Errorf("output does not contain the engine errors:\n%s", output.String())
+		}
+	})
+
+	t.Run("nil metrics are a no-op", func(t *testing.T) {
+		var agentMetrics *AgentMetrics
+		agentMetrics.ObserveRequest("bob", "POST /api/chat", 200, time.Second)
+		agentMetrics.StreamStarted("bob")
+		agentMetrics.StreamEnded("bob")
+		agentMetrics.SetHistorySize("bob", DefaultSession, 1)
+	})
+
+	t.Run("default metrics are shared", func(t *testing.T) {
+		if DefaultAgentMetrics() != DefaultAgentMetrics() {
+			t.Error("DefaultAgentMetrics() should always return the same instance")
+		}
+	})
+}
@@ -0,0 +1,144 @@
+// Package sse reads and writes server-sent events streams
+// (https://html.spec.whatwg.org/multipage/server-sent-events.html)
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Event is a server-sent event
+type Event struct {
+	// ID is the value of the last "id:" field (it is kept from one event to the next, as in a browser)
+	ID string
+	// Event is the value of the "event:" field, empty for the default "message" type
+	Event string
+	// Data is the value of the "data:" fields, joined with "\n"
+	Data string
+	// Retry is the value of the "retry:" field in milliseconds, 0 if not set
+	Retry int
+}
+
+// Decoder reads the events of a server-sent events stream
+type Decoder struct {
+	scanner *bufio.Scanner
+	lastID  string
+}
+
+// maxLineSize is the maximum size of a line of the stream (a chunk of a completion)
+const maxLineSize = 1024 * 1024
+
+// NewDecoder returns a decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	scanner.Split(scanLines)
+	return &Decoder{scanner: scanner}
+}
+
+// Next returns the next event of the stream
+// It returns io.EOF at the end of the stream; an event not terminated by a blank line is discarded
+func (d *Decoder) Next() (Event, error) {
+	var data strings.Builder
+	event := Event{}
+	hasData := false
+
+	for d.scanner.Scan() {
+		line := d.scanner.Text()
+
+		// A blank line dispatches the event
+		if line == "" {
+			if !hasData {
+				// Nothing to dispatch (e.g. only comments, or only an id)
+				event = Event{}
+				continue
+			}
+			event.ID = d.lastID
+			event.Data = data.String()
+			return event, nil
+		}
+
+		// Comment
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "event":
+			event.Event = value
+		case "id":
+			// An id containing NULL is ignored
+			if !strings.ContainsRune(value, 0) {
+				d.lastID = value
+			}
+		case "retry":
+			if retry, err := strconv.Atoi(value); err == nil && retry >= 0 {
+				event.Retry = retry
+			}
+		}
+	}
+
+	if err := d.scanner.Err(); err != nil {
+		return Event{}, fmt.Errorf("error reading stream: %w", err)
+	}
+	return Event{}, io.EOF
+}
+
+// scanLines splits the stream on "\r\n", "\n" or "\r"
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' {
+			// Wait for the next byte to know if "\r" is followed by "\n"
+			if i+1 == len(data) && !atEOF {
+				return 0, nil, nil
+			}
+			if i+1 < len(data) && data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Write writes event to w
+// Multi-line data is written as several "data:" fields, so that it is read back unchanged
+func Write(w io.Writer, event Event) error {
+	var buf bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&buf, "id: %s\n", event.ID)
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event.Event)
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %d\n", event.Retry)
+	}
+	data := strings.ReplaceAll(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\r", "\n")
+	for line := range strings.SplitSeq(data, "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
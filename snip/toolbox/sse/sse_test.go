@@ -0,0 +1,90 @@
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, stream string) []Event {
+	t.Helper()
+	decoder := NewDecoder(strings.NewReader(stream))
+	var events []Event
+	for {
+		event, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %v", err)
+		}
+		events = append(events, event)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	t.Run("fields", func(t *testing.T) {
+		events := readAll(t, ": comment\nid: 1\nevent: chunk\nretry: 3000\ndata: hello\n\n")
+		if len(events) != 1 {
+			t.Fatalf("events = %d, want 1", len(events))
+		}
+		want := Event{ID: "1", Event: "chunk", Data: "hello", Retry: 3000}
+		if events[0] != want {
+			t.Errorf("event = %+v, want %+v", events[0], want)
+		}
+	})
+
+	t.Run("multi-line data", func(t *testing.T) {
+		events := readAll(t, "data: first\ndata:second\ndata\n\n")
+		if len(events) != 1 || events[0].Data != "first\nsecond\n" {
+			t.Errorf("events = %+v, want one event with 3 lines", events)
+		}
+	})
+
+	t.Run("line endings", func(t *testing.T) {
+		events := readAll(t, "data: a\r\n\r\ndata: b\r\rdata: c\n\n")
+		if len(events) != 3 || events[0].Data != "a" || events[1].Data != "b" || events[2].Data != "c" {
+			t.Errorf("events = %+v, want a, b, c", events)
+		}
+	})
+
+	t.Run("id is kept and event type is reset", func(t *testing.T) {
+		events := readAll(t, "id: 7\nevent: result\ndata: a\n\ndata: b\n\n")
+		if len(events) != 2 {
+			t.Fatalf("events = %d, want 2", len(events))
+		}
+		if events[1].ID != "7" || events[1].Event != "" {
+			t.Errorf("second event = %+v, want id 7 and default type", events[1])
+		}
+	})
+
+	t.Run("event without data is not dispatched", func(t *testing.T) {
+		events := readAll(t, "event: ping\n\ndata: a\n\n")
+		if len(events) != 1 || events[0].Event != "" {
+			t.Errorf("events = %+v, want only the data event", events)
+		}
+	})
+
+	t.Run("incomplete event is discarded", func(t *testing.T) {
+		events := readAll(t, "data: a\n\ndata: b")
+		if len(events) != 1 || events[0].Data != "a" {
+			t.Errorf("events = %+v, want only the first event", events)
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	want := Event{ID: "42", Event: "message", Data: "{\"a\":1}\nsecond line", Retry: 1000}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, want); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+
+	events := readAll(t, buf.String())
+	if len(events) != 1 || events[0] != want {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}